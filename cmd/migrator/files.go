@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// migrationFile is one numbered migration in the migrations directory.
+type migrationFile struct {
+	Version uint
+	Up      string
+	Down    string
+	// Digits is the width of the version prefix in the file names.
+	Digits int
+}
+
+// listMigrations returns the migrations in fsys ordered by version. Files not
+// named like migrations are ignored.
+func listMigrations(fsys fs.FS) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migrationFile)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			continue
+		}
+
+		f := byVersion[m.Version]
+		if f == nil {
+			f = &migrationFile{
+				Version: m.Version,
+				Digits:  strings.IndexByte(e.Name(), '_'),
+			}
+			byVersion[m.Version] = f
+		}
+
+		name := &f.Up
+		if m.Direction == source.Down {
+			name = &f.Down
+		}
+		if *name != "" {
+			return nil, fmt.Errorf("duplicate %s migration for version %d: %s and %s", m.Direction, m.Version, *name, e.Name())
+		}
+		*name = e.Name()
+	}
+
+	files := make([]migrationFile, 0, len(byVersion))
+	for _, f := range byVersion {
+		if f.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", f.Version)
+		}
+		files = append(files, *f)
+	}
+	slices.SortFunc(files, func(a, b migrationFile) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return files, nil
+}
+
+// createMigration writes empty up and down files for the version after the
+// latest one in dir, keeping the width of the existing version prefixes, and
+// returns their paths.
+func createMigration(dir, name string) (string, string, error) {
+	name = migrationName(name)
+	if name == "" {
+		return "", "", errors.New("name must contain letters or digits")
+	}
+
+	files, err := listMigrations(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	var version uint
+	digits := 3
+	if len(files) > 0 {
+		last := files[len(files)-1]
+		version = last.Version + 1
+		digits = last.Digits
+	}
+
+	base := fmt.Sprintf("%0*d_%s", digits, version, name)
+	up := filepath.Join(dir, base+".up.sql")
+	down := filepath.Join(dir, base+".down.sql")
+	if err := createFile(up); err != nil {
+		return "", "", err
+	}
+	if err := createFile(down); err != nil {
+		_ = os.Remove(up)
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// migrationName turns name into a lower snake case file name part.
+func migrationName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func createFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMigrations(t *testing.T) {
+	files, err := listMigrations(fstest.MapFS{
+		"010_add_index.up.sql":        {},
+		"002_create_wallets.up.sql":   {},
+		"002_create_wallets.down.sql": {},
+		"010_add_index.down.sql":      {},
+		"README.md":                   {},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []migrationFile{
+		{Version: 2, Up: "002_create_wallets.up.sql", Down: "002_create_wallets.down.sql", Digits: 3},
+		{Version: 10, Up: "010_add_index.up.sql", Down: "010_add_index.down.sql", Digits: 3},
+	}, files)
+}
+
+func TestListMigrationsWithoutUp(t *testing.T) {
+	_, err := listMigrations(fstest.MapFS{"003_orphan.down.sql": {}})
+	assert.ErrorContains(t, err, "no up file")
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0007_create_holds.up.sql", "0007_create_holds.down.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	up, down, err := createMigration(dir, "Add wallet-status column")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_wallet_status_column.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "0008_add_wallet_status_column.down.sql"), down)
+	assert.FileExists(t, up)
+	assert.FileExists(t, down)
+}
+
+func TestCreateMigrationInEmptyDir(t *testing.T) {
+	up, _, err := createMigration(t.TempDir(), "init")
+	require.NoError(t, err)
+	assert.Equal(t, "000_init.up.sql", filepath.Base(up))
+}
+
+func TestCreateMigrationRejectsEmptyName(t *testing.T) {
+	_, _, err := createMigration(t.TempDir(), "--")
+	assert.Error(t, err)
+}
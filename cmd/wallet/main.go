@@ -2,19 +2,30 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"ITK/internal/api"
 	"ITK/internal/api/handlers"
 	"ITK/internal/config"
+	"ITK/internal/jobs"
+	"ITK/internal/metrics"
 	"ITK/internal/repository"
 	"ITK/internal/service"
+	"ITK/internal/tracing"
+	"ITK/migrations"
 	"ITK/pkg/postgres"
+	"ITK/pkg/retry"
+	pkgsync "ITK/pkg/sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // @title Wallet Service API
@@ -28,21 +39,67 @@ func main() {
 	logger := setupLogger(cfg.Env)
 	logger.Info("starting wallet service", slog.String("env", cfg.Env))
 
-	pool, err := postgres.NewPool(context.Background(), cfg.DB, logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	if err = metrics.Register(prometheus.DefaultRegisterer); err != nil {
+		logger.Error("failed to register metrics", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	store, closeStore, err := newStorage(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("failed to set up storage", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer closeStore()
+
+	walletLocker, closeLocker, err := newWalletLocker(context.Background(), cfg, logger)
 	if err != nil {
-		logger.Error("failed to connect to database", slog.String("error", err.Error()))
+		logger.Error("failed to set up wallet locks", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
-	defer pool.Close()
+	defer closeLocker()
 
-	walletRepo := repository.New(pool, logger, repository.Config{
-		MaxRetries:  cfg.Retry.MaxAttempts,
-		BaseDelayMS: cfg.Retry.BaseDelayMS,
+	walletService := service.New(store.wallets, logger, service.Config{
+		DefaultCurrency: cfg.Wallets.DefaultCurrency,
+		HoldTTL:         cfg.Holds.TTL,
+		HoldMaxTTL:      cfg.Holds.MaxTTL,
+		LockTimeout:     cfg.Wallets.LockTimeout,
+		Locker:          walletLocker,
+		GroupCommit:     cfg.Wallets.GroupCommit,
+		MaxBatch:        cfg.Wallets.MaxBatch,
 	})
-	walletService := service.New(walletRepo, logger)
 	walletHandler := handlers.New(walletService, logger)
 
-	router := api.NewRouter(logger, walletHandler)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go jobs.NewIdempotencyCleanup(store.idempotency, logger, cfg.Idempotency.CleanupInterval).Run(jobsCtx)
+	go jobs.NewHoldSweeper(store.wallets, logger, cfg.Holds.SweepInterval, cfg.Holds.SweepBatchSize).Run(jobsCtx)
+
+	var webhookHandler *handlers.WebhookHandler
+	if store.webhooks != nil {
+		webhookHandler = handlers.NewWebhooks(service.NewWebhooks(store.webhooks, logger), logger)
+		go jobs.NewWebhookDispatcher(store.webhooks, logger, jobs.WebhookDispatcherConfig{
+			Interval:    cfg.Webhooks.DispatchInterval,
+			BatchSize:   cfg.Webhooks.BatchSize,
+			Timeout:     cfg.Webhooks.Timeout,
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			BackoffBase: cfg.Webhooks.BackoffBase,
+			BackoffMax:  cfg.Webhooks.BackoffMax,
+		}).Run(jobsCtx)
+	}
+
+	router := api.NewRouter(logger, walletHandler, webhookHandler, store.idempotency, cfg.Idempotency.TTL)
 
 	server := &http.Server{
 		Addr:         cfg.HTTPServer.Address,
@@ -68,6 +125,8 @@ func main() {
 
 	logger.Info("shutting down server...")
 
+	stopJobs()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -75,9 +134,123 @@ func main() {
 		logger.Error("server forced to shutdown", slog.String("error", err.Error()))
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
+
 	logger.Info("server exited gracefully")
 }
 
+// storage holds the repositories selected by STORAGE.
+type storage struct {
+	wallets     repository.Repository
+	idempotency repository.IdempotencyRepository
+	// webhooks is nil for the in-memory storage, which has no outbox to
+	// deliver from.
+	webhooks repository.WebhookRepository
+}
+
+// newStorage builds the repositories selected by STORAGE and a func
+// releasing their resources.
+func newStorage(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*storage, func(), error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		logger.Warn("using in-memory storage, data is lost on exit")
+		return &storage{
+			wallets:     repository.NewMemory(logger),
+			idempotency: repository.NewMemoryIdempotency(),
+		}, func() {}, nil
+	case config.StoragePostgres:
+		retryPolicy, err := newRetryPolicy(cfg.Retry)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pool, err := postgres.NewPool(ctx, cfg.DB, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		if err = migrateSchema(ctx, pool, cfg.DB, logger); err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		prometheus.MustRegister(metrics.NewPoolCollector(pool))
+
+		return &storage{
+			wallets:     repository.New(pool, logger, repository.Config{Retry: retryPolicy}),
+			idempotency: repository.NewIdempotency(pool, logger),
+			webhooks:    repository.NewWebhooks(pool, logger),
+		}, pool.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown STORAGE %q", cfg.Storage)
+}
+
+// migrateSchema applies the embedded migrations when DB_AUTO_MIGRATE is set and
+// otherwise only checks that the schema is not ahead of this binary.
+func migrateSchema(ctx context.Context, pool *pgxpool.Pool, cfg postgres.DBConfig, logger *slog.Logger) error {
+	if cfg.AutoMigrate {
+		return postgres.Migrate(ctx, pool, cfg, migrations.FS, logger)
+	}
+
+	pending, err := postgres.CheckSchema(cfg, migrations.FS)
+	if err != nil {
+		return err
+	}
+	if pending {
+		logger.Warn("database schema has pending migrations, run the migrator or set DB_AUTO_MIGRATE")
+	}
+	return nil
+}
+
+// newRetryPolicy builds the policy the repository reruns failed
+// transactions with.
+func newRetryPolicy(cfg config.RetryConfig) (retry.Policy, error) {
+	switch cfg.Jitter {
+	case retry.JitterNone, retry.JitterFull, retry.JitterDecorrelated:
+	default:
+		return retry.Policy{}, fmt.Errorf("unknown RETRY_JITTER %q", cfg.Jitter)
+	}
+
+	policy := retry.Policy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.BaseDelayMS) * time.Millisecond,
+		MaxDelay:    cfg.MaxDelay,
+		Jitter:      cfg.Jitter,
+		Retryable:   postgres.Retryable(cfg.SQLStates),
+	}
+	if cfg.BudgetTokens > 0 {
+		policy.Budget = retry.NewBudget(cfg.BudgetTokens, cfg.BudgetRatio)
+	}
+	return policy, nil
+}
+
+// newWalletLocker builds the per-wallet lock selected by LOCK_MODE and a func
+// releasing its resources.
+func newWalletLocker(ctx context.Context, cfg *config.Config, logger *slog.Logger) (pkgsync.Locker, func(), error) {
+	switch cfg.Lock.Mode {
+	case config.LockModeMemory:
+		return pkgsync.NewKeyedMutex(), func() {}, nil
+	case config.LockModePostgres:
+		// A separate pool, so that held locks cannot starve the queries
+		// running under them. PGLocker needs a connection per session.
+		dbCfg := cfg.DB
+		dbCfg.MaxOpenConns = max(cfg.Lock.PGSessions, 1)
+		dbCfg.MaxIdleConns = dbCfg.MaxOpenConns
+		pool, err := postgres.NewPool(ctx, dbCfg, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pkgsync.NewPGLocker(pool, pkgsync.DefaultLockClass, cfg.Lock.PGSessions), pool.Close, nil
+	case config.LockModeRing:
+		if !slices.Contains(cfg.Lock.RingNodes, cfg.Lock.RingSelf) {
+			return nil, nil, fmt.Errorf("LOCK_RING_SELF %q is not in LOCK_RING_NODES", cfg.Lock.RingSelf)
+		}
+		ring := pkgsync.NewRing(cfg.Lock.RingNodes, pkgsync.DefaultVirtualNodes)
+		return pkgsync.NewRingLocker(ring, cfg.Lock.RingSelf, pkgsync.NewKeyedMutex()), func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown LOCK_MODE %q", cfg.Lock.Mode)
+}
+
 func setupLogger(env string) *slog.Logger {
 	var handler slog.Handler
 
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type PlaceHoldRequest struct {
+	Amount     float64 `json:"amount" example:"150.00"`
+	TTLSeconds int     `json:"ttlSeconds,omitempty" example:"3600"`
+}
+
+type CaptureHoldRequest struct {
+	Amount float64 `json:"amount,omitempty" example:"120.00"`
+}
+
+type HoldResponse struct {
+	ID             string  `json:"id" example:"850e8400-e29b-41d4-a716-446655440000"`
+	WalletID       string  `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Amount         float64 `json:"amount" example:"150.00"`
+	CapturedAmount float64 `json:"capturedAmount" example:"0"`
+	Status         string  `json:"status" example:"ACTIVE" enums:"ACTIVE,CAPTURED,RELEASED,EXPIRED"`
+	ExpiresAt      string  `json:"expiresAt" example:"2025-01-31T23:59:59Z"`
+	CreatedAt      string  `json:"createdAt" example:"2025-01-30T23:59:59Z"`
+}
+
+// PlaceHold godoc
+// @Summary Place a hold on wallet funds
+// @Description Reserves part of the wallet balance. Held funds stay in the balance but are not available for withdrawal until the hold is captured, released or expires.
+// @Tags Holds
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param request body PlaceHoldRequest true "Hold details"
+// @Success 201 {object} HoldResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 409 {object} response.Response "Insufficient available funds, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v1/wallets/{id}/holds [post]
+func (h *Handler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	var req PlaceHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	hold, ok := h.placeHold(w, r, walletID, decimal.NewFromFloat(req.Amount), req.TTLSeconds)
+	if !ok {
+		return
+	}
+
+	writeHold(w, http.StatusCreated, hold)
+}
+
+// placeHold validates and places a hold. On failure it writes the error
+// response and returns false.
+func (h *Handler) placeHold(w http.ResponseWriter, r *http.Request, walletID uuid.UUID, amount decimal.Decimal, ttlSeconds int) (*service.Hold, bool) {
+	if !amount.IsPositive() {
+		response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+		return nil, false
+	}
+	if ttlSeconds < 0 {
+		response.WriteError(w, http.StatusBadRequest, "ttlSeconds must not be negative")
+		return nil, false
+	}
+
+	ttl := time.Duration(ttlSeconds) * time.Second
+
+	hold, err := h.service.PlaceHold(r.Context(), walletID, amount, ttl)
+	if err != nil {
+		if writeCurrencyError(w, err) || writeLockError(w, err) || writeCommitError(w, err) || writeStatusError(w, err) {
+			return nil, false
+		}
+		switch {
+		case errors.Is(err, service.ErrWalletNotFound):
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+		case errors.Is(err, service.ErrInsufficientFunds):
+			response.WriteError(w, http.StatusConflict, "insufficient funds")
+		case errors.Is(err, service.ErrInvalidAmount):
+			response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+		case errors.Is(err, service.ErrInvalidHoldTTL):
+			response.WriteError(w, http.StatusBadRequest, "ttlSeconds is out of range")
+		default:
+			h.log.Error("failed to place hold",
+				slog.String("error", err.Error()),
+				slog.String("wallet_id", walletID.String()),
+			)
+			response.WriteError(w, http.StatusInternalServerError, "failed to place hold")
+		}
+		return nil, false
+	}
+
+	return hold, true
+}
+
+// GetHold godoc
+// @Summary Get hold
+// @Description Returns a hold by its ID
+// @Tags Holds
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Success 200 {object} HoldResponse
+// @Failure 400 {object} response.Response "Invalid hold ID"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v1/holds/{holdId} [get]
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	if hold, ok := h.holdAction(w, r, h.service.GetHold, "failed to get hold"); ok {
+		writeHold(w, http.StatusOK, hold)
+	}
+}
+
+// CaptureHold godoc
+// @Summary Capture a hold
+// @Description Withdraws the given amount (or the whole hold if omitted) from the held funds and releases the rest
+// @Tags Holds
+// @Accept json
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Param request body CaptureHoldRequest false "Capture details"
+// @Success 200 {object} HoldResponse
+// @Failure 400 {object} response.Response "Invalid request or amount exceeds hold"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 409 {object} response.Response "Hold is not active, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v1/holds/{holdId}/capture [post]
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	holdID, err := uuid.Parse(chi.URLParam(r, "holdId"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid hold ID format")
+		return
+	}
+
+	var req CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Amount < 0 {
+		response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	// Zero means "capture the whole hold".
+	amount := decimal.Zero
+	if req.Amount > 0 {
+		amount = decimal.NewFromFloat(req.Amount)
+	}
+
+	hold, err := h.service.CaptureHold(r.Context(), holdID, amount)
+	if err != nil {
+		h.writeHoldError(w, err, holdID, "failed to capture hold")
+		return
+	}
+
+	writeHold(w, http.StatusOK, hold)
+}
+
+// ReleaseHold godoc
+// @Summary Release a hold
+// @Description Cancels an active hold and returns the reserved funds to the available balance
+// @Tags Holds
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Success 200 {object} HoldResponse
+// @Failure 400 {object} response.Response "Invalid hold ID"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 409 {object} response.Response "Hold is not active"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v1/holds/{holdId}/release [post]
+func (h *Handler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	if hold, ok := h.holdAction(w, r, h.service.ReleaseHold, "failed to release hold"); ok {
+		writeHold(w, http.StatusOK, hold)
+	}
+}
+
+// holdAction runs action on the hold named in the URL. On failure it writes
+// the error response and returns false.
+func (h *Handler) holdAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, holdID uuid.UUID) (*service.Hold, error),
+	msg string,
+) (*service.Hold, bool) {
+	holdID, err := uuid.Parse(chi.URLParam(r, "holdId"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid hold ID format")
+		return nil, false
+	}
+
+	hold, err := action(r.Context(), holdID)
+	if err != nil {
+		h.writeHoldError(w, err, holdID, msg)
+		return nil, false
+	}
+
+	return hold, true
+}
+
+func (h *Handler) writeHoldError(w http.ResponseWriter, err error, holdID uuid.UUID, msg string) {
+	if writeCurrencyError(w, err) || writeLockError(w, err) || writeCommitError(w, err) || writeStatusError(w, err) ||
+		writeLimitError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrHoldNotFound):
+		response.WriteError(w, http.StatusNotFound, "hold not found")
+	case errors.Is(err, service.ErrHoldNotActive):
+		response.WriteError(w, http.StatusConflict, "hold is not active")
+	case errors.Is(err, service.ErrCaptureExceedsHold):
+		response.WriteError(w, http.StatusBadRequest, "capture amount exceeds hold amount")
+	case errors.Is(err, service.ErrInvalidAmount):
+		response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+	default:
+		h.log.Error(msg,
+			slog.String("error", err.Error()),
+			slog.String("hold_id", holdID.String()),
+		)
+		response.WriteError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func writeHold(w http.ResponseWriter, status int, hold *service.Hold) {
+	amount, _ := hold.Amount.Float64()
+	captured, _ := hold.CapturedAmount.Float64()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(HoldResponse{
+		ID:             hold.ID.String(),
+		WalletID:       hold.WalletID.String(),
+		Amount:         amount,
+		CapturedAmount: captured,
+		Status:         hold.Status,
+		ExpiresAt:      hold.ExpiresAt.UTC().Format(time.RFC3339),
+		CreatedAt:      hold.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
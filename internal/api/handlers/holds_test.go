@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func withURLParam(req *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func (s *WalletHandlersSuite) TestPlaceHold_Success() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(150.5)
+	hold := &service.Hold{
+		ID:        uuid.New(),
+		WalletID:  walletID,
+		Amount:    amount,
+		Status:    "ACTIVE",
+		ExpiresAt: time.Date(2025, 1, 31, 13, 0, 0, 0, time.UTC),
+		CreatedAt: time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+
+	body, _ := json.Marshal(PlaceHoldRequest{Amount: 150.5, TTLSeconds: 3600})
+
+	s.walletService.EXPECT().
+		PlaceHold(gomock.Any(), walletID, amount, time.Hour).
+		Return(hold, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/"+walletID.String()+"/holds", bytes.NewReader(body))
+	req = withURLParam(req, "id", walletID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.PlaceHold(w, req)
+
+	s.Equal(http.StatusCreated, w.Code)
+
+	var response HoldResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	s.NoError(err)
+	s.Equal(hold.ID.String(), response.ID)
+	s.Equal(150.5, response.Amount)
+	s.Equal("ACTIVE", response.Status)
+	s.Equal("2025-01-31T13:00:00Z", response.ExpiresAt)
+}
+
+func (s *WalletHandlersSuite) TestPlaceHold_InsufficientFunds() {
+	walletID := uuid.New()
+
+	body, _ := json.Marshal(PlaceHoldRequest{Amount: 150})
+
+	s.walletService.EXPECT().
+		PlaceHold(gomock.Any(), walletID, decimal.NewFromFloat(150), time.Duration(0)).
+		Return(nil, service.ErrInsufficientFunds)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/"+walletID.String()+"/holds", bytes.NewReader(body))
+	req = withURLParam(req, "id", walletID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.PlaceHold(w, req)
+
+	s.Equal(http.StatusConflict, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestPlaceHold_InvalidAmount() {
+	walletID := uuid.New()
+
+	body, _ := json.Marshal(PlaceHoldRequest{Amount: -1})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/"+walletID.String()+"/holds", bytes.NewReader(body))
+	req = withURLParam(req, "id", walletID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.PlaceHold(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestCaptureHold_EmptyBodyCapturesAll() {
+	holdID := uuid.New()
+	hold := &service.Hold{
+		ID:             holdID,
+		WalletID:       uuid.New(),
+		Amount:         decimal.NewFromFloat(150),
+		CapturedAmount: decimal.NewFromFloat(150),
+		Status:         "CAPTURED",
+	}
+
+	s.walletService.EXPECT().
+		CaptureHold(gomock.Any(), holdID, decimal.Zero).
+		Return(hold, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+holdID.String()+"/capture", nil)
+	req = withURLParam(req, "holdId", holdID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.CaptureHold(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response HoldResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	s.NoError(err)
+	s.Equal("CAPTURED", response.Status)
+	s.Equal(150.0, response.CapturedAmount)
+}
+
+func (s *WalletHandlersSuite) TestCaptureHold_NotActive() {
+	holdID := uuid.New()
+
+	body, _ := json.Marshal(CaptureHoldRequest{Amount: 10})
+
+	s.walletService.EXPECT().
+		CaptureHold(gomock.Any(), holdID, decimal.NewFromFloat(10)).
+		Return(nil, service.ErrHoldNotActive)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+holdID.String()+"/capture", bytes.NewReader(body))
+	req = withURLParam(req, "holdId", holdID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.CaptureHold(w, req)
+
+	s.Equal(http.StatusConflict, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestReleaseHold_NotFound() {
+	holdID := uuid.New()
+
+	s.walletService.EXPECT().
+		ReleaseHold(gomock.Any(), holdID).
+		Return(nil, service.ErrHoldNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+holdID.String()+"/release", nil)
+	req = withURLParam(req, "holdId", holdID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.ReleaseHold(w, req)
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
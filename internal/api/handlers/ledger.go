@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"ITK/pkg/api/response"
+)
+
+type TrialBalanceResponse struct {
+	// Balanced is false when a currency does not sum to zero or a cached
+	// wallet balance differs from the ledger.
+	Balanced   bool                       `json:"balanced" example:"true"`
+	Lines      []TrialBalanceLineResponse `json:"lines"`
+	Totals     map[string]string          `json:"totals" example:"RUB:0"`
+	Mismatches []BalanceMismatchResponse  `json:"mismatches"`
+}
+
+type TrialBalanceLineResponse struct {
+	Currency string `json:"currency" example:"RUB"`
+	Account  string `json:"account" example:"CASH_IN" enums:"CASH_IN,CASH_OUT,OPENING_BALANCE,WALLETS"`
+	Accounts int    `json:"accounts" example:"1"`
+	Balance  string `json:"balance" example:"-1000.50"`
+}
+
+type BalanceMismatchResponse struct {
+	WalletID      string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	CachedBalance string `json:"cachedBalance" example:"100"`
+	LedgerBalance string `json:"ledgerBalance" example:"90"`
+}
+
+// TrialBalance godoc
+// @Summary Ledger trial balance
+// @Description Sums the double-entry ledger by currency and account. Wallet accounts of a currency are summed up in the WALLETS line. Deposits are booked against CASH_IN, withdrawals and captured holds against CASH_OUT, so the lines of every currency sum to zero. Also lists up to 100 wallets whose cached balance differs from their postings.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} TrialBalanceResponse
+// @Failure 500 {object} response.Response
+// @Router /admin/ledger/trial-balance [get]
+func (h *Handler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	tb, err := h.service.TrialBalance(r.Context())
+	if err != nil {
+		h.log.Error("failed to build trial balance", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusInternalServerError, "failed to build trial balance")
+		return
+	}
+
+	resp := TrialBalanceResponse{
+		Balanced:   tb.Balanced(),
+		Lines:      make([]TrialBalanceLineResponse, 0, len(tb.Lines)),
+		Totals:     make(map[string]string),
+		Mismatches: make([]BalanceMismatchResponse, 0, len(tb.Mismatches)),
+	}
+	for _, line := range tb.Lines {
+		resp.Lines = append(resp.Lines, TrialBalanceLineResponse{
+			Currency: line.Currency,
+			Account:  line.Account,
+			Accounts: line.Accounts,
+			Balance:  line.Balance.String(),
+		})
+	}
+	for currency, total := range tb.Totals() {
+		resp.Totals[currency] = total.String()
+	}
+	for _, m := range tb.Mismatches {
+		resp.Mismatches = append(resp.Mismatches, BalanceMismatchResponse{
+			WalletID:      m.WalletID.String(),
+			CachedBalance: m.Cached.String(),
+			LedgerBalance: m.Ledger.String(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
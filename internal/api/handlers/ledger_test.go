@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestTrialBalance() {
+	walletID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	s.walletService.EXPECT().
+		TrialBalance(gomock.Any()).
+		Return(&service.TrialBalance{
+			Lines: []service.TrialBalanceLine{
+				{Currency: "RUB", Account: "CASH_IN", Accounts: 1, Balance: decimal.RequireFromString("-150.5")},
+				{Currency: "RUB", Account: "CASH_OUT", Accounts: 1, Balance: decimal.RequireFromString("50")},
+				{Currency: "RUB", Account: "WALLETS", Accounts: 2, Balance: decimal.RequireFromString("100.5")},
+			},
+			Mismatches: []service.BalanceMismatch{
+				{WalletID: walletID, Cached: decimal.RequireFromString("10"), Ledger: decimal.RequireFromString("9")},
+			},
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ledger/trial-balance", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.TrialBalance(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{
+		"balanced": false,
+		"lines": [
+			{"currency": "RUB", "account": "CASH_IN", "accounts": 1, "balance": "-150.5"},
+			{"currency": "RUB", "account": "CASH_OUT", "accounts": 1, "balance": "50"},
+			{"currency": "RUB", "account": "WALLETS", "accounts": 2, "balance": "100.5"}
+		],
+		"totals": {"RUB": "0"},
+		"mismatches": [
+			{"walletId": "550e8400-e29b-41d4-a716-446655440000", "cachedBalance": "10", "ledgerBalance": "9"}
+		]
+	}`, w.Body.String())
+}
+
+func (s *WalletHandlersSuite) TestTrialBalance_Error() {
+	s.walletService.EXPECT().TrialBalance(gomock.Any()).Return(nil, errors.New("connection refused"))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/ledger/trial-balance", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.TrialBalance(w, req)
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
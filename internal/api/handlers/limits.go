@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type LimitPolicyRequest struct {
+	Tier              string              `json:"tier,omitempty" example:"unverified"`
+	Currency          string              `json:"currency,omitempty" example:"RUB"`
+	MaxWithdrawal     decimal.NullDecimal `json:"maxWithdrawal" swaggertype:"string" example:"15000"`
+	DailyWithdrawal   decimal.NullDecimal `json:"dailyWithdrawal" swaggertype:"string" example:"15000"`
+	MonthlyWithdrawal decimal.NullDecimal `json:"monthlyWithdrawal" swaggertype:"string" example:"40000"`
+	MaxBalance        decimal.NullDecimal `json:"maxBalance" swaggertype:"string" example:"15000"`
+}
+
+type LimitPolicyResponse struct {
+	Name              string              `json:"name" example:"unverified"`
+	Tier              string              `json:"tier,omitempty" example:"unverified"`
+	Currency          string              `json:"currency" example:"RUB"`
+	MaxWithdrawal     decimal.NullDecimal `json:"maxWithdrawal" swaggertype:"string" example:"15000"`
+	DailyWithdrawal   decimal.NullDecimal `json:"dailyWithdrawal" swaggertype:"string" example:"15000"`
+	MonthlyWithdrawal decimal.NullDecimal `json:"monthlyWithdrawal" swaggertype:"string" example:"40000"`
+	MaxBalance        decimal.NullDecimal `json:"maxBalance" swaggertype:"string" example:"15000"`
+	CreatedAt         string              `json:"createdAt" example:"2025-01-31T23:59:59Z"`
+	UpdatedAt         string              `json:"updatedAt" example:"2025-01-31T23:59:59Z"`
+}
+
+type WalletLimitsRequest struct {
+	Tier   string `json:"tier,omitempty" example:"unverified"`
+	Policy string `json:"policy,omitempty" example:"vip"`
+}
+
+type WalletLimitsResponse struct {
+	WalletID string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Tier     string `json:"tier,omitempty" example:"unverified"`
+	Policy   string `json:"policy,omitempty" example:"vip"`
+}
+
+// LimitExceededResponse is returned with 422 when an operation would break a
+// limit of the wallet's limit policy.
+type LimitExceededResponse struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"limit exceeded"`
+	Limit  string `json:"limit" example:"daily_withdrawal" enums:"max_withdrawal,daily_withdrawal,monthly_withdrawal,max_balance"`
+	Policy string `json:"policy" example:"unverified"`
+	Value  string `json:"value" example:"15000"`
+}
+
+// PutLimitPolicy godoc
+// @Summary Create or replace a limit policy
+// @Description Saves the limit policy with the given name. Limits are amounts in the policy currency, the default wallet currency if omitted; omitted limits do not apply. A policy only governs wallets of its currency. A policy with a tier applies to every wallet of that tier and currency that has no policy of its own; a tier can have one policy per currency. Withdrawal totals cover withdrawals, captured holds and transfers out since the start of the current day or month, less their reversals.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Policy name"
+// @Param request body LimitPolicyRequest true "Limits"
+// @Success 200 {object} LimitPolicyResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 409 {object} response.Response "Tier already has a limit policy in this currency, or the policy is assigned to wallets of another currency"
+// @Failure 500 {object} response.Response
+// @Router /admin/limit-policies/{name} [put]
+func (h *Handler) PutLimitPolicy(w http.ResponseWriter, r *http.Request) {
+	var req LimitPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	policy, err := h.service.PutLimitPolicy(r.Context(), service.LimitPolicy{
+		Name:              chi.URLParam(r, "name"),
+		Tier:              req.Tier,
+		Currency:          strings.TrimSpace(req.Currency),
+		MaxWithdrawal:     req.MaxWithdrawal,
+		DailyWithdrawal:   req.DailyWithdrawal,
+		MonthlyWithdrawal: req.MonthlyWithdrawal,
+		MaxBalance:        req.MaxBalance,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLimitPolicy):
+			response.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrLimitTierTaken):
+			response.WriteError(w, http.StatusConflict, "tier already has a limit policy in this currency")
+		case errors.Is(err, service.ErrLimitCurrencyMismatch):
+			response.WriteError(w, http.StatusConflict, "limit policy is assigned to wallets of another currency")
+		default:
+			h.log.Error("failed to save limit policy", slog.String("error", err.Error()))
+			response.WriteError(w, http.StatusInternalServerError, "failed to save limit policy")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toLimitPolicyResponse(*policy))
+}
+
+// ListLimitPolicies godoc
+// @Summary List limit policies
+// @Description Returns all limit policies ordered by name
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} LimitPolicyResponse
+// @Failure 500 {object} response.Response
+// @Router /admin/limit-policies [get]
+func (h *Handler) ListLimitPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.service.ListLimitPolicies(r.Context())
+	if err != nil {
+		h.log.Error("failed to list limit policies", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusInternalServerError, "failed to list limit policies")
+		return
+	}
+
+	resp := make([]LimitPolicyResponse, 0, len(policies))
+	for _, policy := range policies {
+		resp = append(resp, toLimitPolicyResponse(policy))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetWalletLimits godoc
+// @Summary Assign wallet limits
+// @Description Sets the tier and the limit policy of a wallet. The policy must be in the wallet currency. The wallet's own policy takes precedence over the policy of its tier and currency; omitted fields are cleared.
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param request body WalletLimitsRequest true "Tier and policy"
+// @Success 200 {object} WalletLimitsResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet or limit policy not found"
+// @Failure 409 {object} response.Response "Limit policy is in another currency than the wallet"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Router /api/v1/wallets/{id}/limits [put]
+// @Router /api/v2/wallets/{id}/limits [put]
+func (h *Handler) SetWalletLimits(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	var req WalletLimitsRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	limits := service.WalletLimits{Tier: strings.TrimSpace(req.Tier), Policy: strings.TrimSpace(req.Policy)}
+	if err = h.service.SetWalletLimits(r.Context(), walletID, limits); err != nil {
+		if writeLockError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidWalletLimits):
+			response.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrWalletNotFound):
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+		case errors.Is(err, service.ErrLimitPolicyNotFound):
+			response.WriteError(w, http.StatusNotFound, "limit policy not found")
+		case errors.Is(err, service.ErrLimitCurrencyMismatch):
+			response.WriteError(w, http.StatusConflict, "limit policy currency does not match wallet currency")
+		default:
+			h.log.Error("failed to set wallet limits",
+				slog.String("error", err.Error()),
+				slog.String("wallet_id", walletID.String()),
+			)
+			response.WriteError(w, http.StatusInternalServerError, "failed to set wallet limits")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(WalletLimitsResponse{
+		WalletID: walletID.String(),
+		Tier:     limits.Tier,
+		Policy:   limits.Policy,
+	})
+}
+
+// writeLimitError writes a 422 response naming the broken limit and reports
+// whether err was such an error.
+func writeLimitError(w http.ResponseWriter, err error) bool {
+	var exceeded *service.LimitExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(LimitExceededResponse{
+		Status: "error",
+		Error:  "limit exceeded",
+		Limit:  exceeded.Limit,
+		Policy: exceeded.Policy,
+		Value:  exceeded.Value.String(),
+	})
+	return true
+}
+
+func toLimitPolicyResponse(policy service.LimitPolicy) LimitPolicyResponse {
+	return LimitPolicyResponse{
+		Name:              policy.Name,
+		Tier:              policy.Tier,
+		Currency:          policy.Currency,
+		MaxWithdrawal:     policy.MaxWithdrawal,
+		DailyWithdrawal:   policy.DailyWithdrawal,
+		MonthlyWithdrawal: policy.MonthlyWithdrawal,
+		MaxBalance:        policy.MaxBalance,
+		CreatedAt:         policy.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:         policy.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
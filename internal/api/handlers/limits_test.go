@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestPutLimitPolicy_Success() {
+	daily := decimal.NewNullDecimal(decimal.NewFromInt(15000))
+	policy := &service.LimitPolicy{
+		Name:            "unverified",
+		Tier:            "basic",
+		Currency:        "USD",
+		DailyWithdrawal: daily,
+		CreatedAt:       time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+		UpdatedAt:       time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.walletService.EXPECT().
+		PutLimitPolicy(gomock.Any(), service.LimitPolicy{Name: "unverified", Tier: "basic", Currency: "USD", DailyWithdrawal: daily}).
+		Return(policy, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/limit-policies/unverified",
+		strings.NewReader(`{"tier":"basic","currency":"USD","dailyWithdrawal":"15000","maxBalance":null}`))
+	w := httptest.NewRecorder()
+
+	s.handler.PutLimitPolicy(w, withURLParam(req, "name", "unverified"))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{
+		"name": "unverified",
+		"tier": "basic",
+		"currency": "USD",
+		"maxWithdrawal": null,
+		"dailyWithdrawal": "15000",
+		"monthlyWithdrawal": null,
+		"maxBalance": null,
+		"createdAt": "2025-01-31T12:00:00Z",
+		"updatedAt": "2025-01-31T12:00:00Z"
+	}`, w.Body.String())
+}
+
+func (s *WalletHandlersSuite) TestPutLimitPolicy_Errors() {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{service.ErrInvalidLimitPolicy, http.StatusBadRequest},
+		{service.ErrLimitTierTaken, http.StatusConflict},
+		{service.ErrLimitCurrencyMismatch, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		s.walletService.EXPECT().
+			PutLimitPolicy(gomock.Any(), gomock.Any()).
+			Return(nil, tt.err)
+
+		req := httptest.NewRequest(http.MethodPut, "/admin/limit-policies/p", strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		s.handler.PutLimitPolicy(w, withURLParam(req, "name", "p"))
+
+		s.Equal(tt.code, w.Code, tt.err.Error())
+	}
+}
+
+func (s *WalletHandlersSuite) TestSetWalletLimits() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		SetWalletLimits(gomock.Any(), walletID, service.WalletLimits{Tier: "basic"}).
+		Return(nil)
+	s.walletService.EXPECT().
+		SetWalletLimits(gomock.Any(), walletID, service.WalletLimits{Policy: "missing"}).
+		Return(service.ErrLimitPolicyNotFound)
+	s.walletService.EXPECT().
+		SetWalletLimits(gomock.Any(), walletID, service.WalletLimits{Policy: "usd"}).
+		Return(service.ErrLimitCurrencyMismatch)
+
+	for _, tt := range []struct {
+		body WalletLimitsRequest
+		code int
+	}{
+		{WalletLimitsRequest{Tier: " basic"}, http.StatusOK},
+		{WalletLimitsRequest{Policy: "missing"}, http.StatusNotFound},
+		{WalletLimitsRequest{Policy: "usd"}, http.StatusConflict},
+	} {
+		data, _ := json.Marshal(tt.body)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets/"+walletID.String()+"/limits", bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		s.handler.SetWalletLimits(w, withURLParam(req, "id", walletID.String()))
+
+		s.Equal(tt.code, w.Code)
+	}
+}
+
+func (s *WalletHandlersSuite) TestOperation_LimitExceeded() {
+	walletID := uuid.New()
+	body, _ := json.Marshal(OperationRequest{WalletID: walletID.String(), OperationType: "WITHDRAW", Amount: 100})
+
+	s.walletService.EXPECT().
+		Withdraw(gomock.Any(), walletID, decimal.NewFromFloat(100), "").
+		Return(&service.LimitExceededError{Policy: "unverified", Limit: "daily_withdrawal", Value: decimal.NewFromInt(50)})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusUnprocessableEntity, w.Code)
+	var response LimitExceededResponse
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal(LimitExceededResponse{
+		Status: "error",
+		Error:  "limit exceeded",
+		Limit:  "daily_withdrawal",
+		Policy: "unverified",
+		Value:  "50",
+	}, response)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ReconciliationResponse struct {
+	WalletID string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// Consistent is false when the three balances do not agree.
+	Consistent       bool   `json:"consistent" example:"true"`
+	Balance          string `json:"balance" example:"5000.50"`
+	Computed         string `json:"computed" example:"5000.50"`
+	LastBalanceAfter string `json:"lastBalanceAfter" example:"5000.50"`
+	Drift            string `json:"drift" example:"0"`
+	Operations       int    `json:"operations" example:"42"`
+}
+
+// Reconcile godoc
+// @Summary Reconcile wallet balance
+// @Description Recomputes the balance of a wallet as the sum of the signed amounts of its operations and compares it with the cached balance and the balanceAfter of its latest operation. drift is the cached balance minus the computed one.
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Success 200 {object} ReconciliationResponse
+// @Failure 400 {object} response.Response "Invalid wallet ID"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /admin/wallets/{id}/reconcile [get]
+func (h *Handler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	rc, err := h.service.ReconcileWallet(r.Context(), walletID)
+	if err != nil {
+		if errors.Is(err, service.ErrWalletNotFound) {
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+			return
+		}
+		h.log.Error("failed to reconcile wallet",
+			slog.String("error", err.Error()),
+			slog.String("wallet_id", walletID.String()),
+		)
+		response.WriteError(w, http.StatusInternalServerError, "failed to reconcile wallet")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReconciliationResponse{
+		WalletID:         rc.WalletID.String(),
+		Consistent:       rc.Consistent(),
+		Balance:          rc.Balance.String(),
+		Computed:         rc.Computed.String(),
+		LastBalanceAfter: rc.LastBalanceAfter.String(),
+		Drift:            rc.Drift().String(),
+		Operations:       rc.Operations,
+	})
+}
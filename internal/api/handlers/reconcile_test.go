@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestReconcile_Drift() {
+	walletID := uuid.New()
+	s.walletService.EXPECT().
+		ReconcileWallet(gomock.Any(), walletID).
+		Return(&service.Reconciliation{
+			WalletID:         walletID,
+			Balance:          decimal.RequireFromString("100.5"),
+			Computed:         decimal.RequireFromString("100"),
+			LastBalanceAfter: decimal.RequireFromString("100"),
+			Operations:       3,
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/wallets/"+walletID.String()+"/reconcile", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.Reconcile(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{
+		"walletId": "`+walletID.String()+`",
+		"consistent": false,
+		"balance": "100.5",
+		"computed": "100",
+		"lastBalanceAfter": "100",
+		"drift": "0.5",
+		"operations": 3
+	}`, w.Body.String())
+}
+
+func (s *WalletHandlersSuite) TestReconcile_Errors() {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{service.ErrWalletNotFound, http.StatusNotFound},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		s.walletService.EXPECT().ReconcileWallet(gomock.Any(), gomock.Any()).Return(nil, tt.err)
+
+		walletID := uuid.NewString()
+		req := httptest.NewRequest(http.MethodGet, "/admin/wallets/"+walletID+"/reconcile", nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Reconcile(w, withURLParam(req, "id", walletID))
+
+		s.Equal(tt.code, w.Code, tt.err.Error())
+	}
+}
+
+func (s *WalletHandlersSuite) TestReconcile_InvalidWalletID() {
+	req := httptest.NewRequest(http.MethodGet, "/admin/wallets/invalid-uuid/reconcile", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.Reconcile(w, withURLParam(req, "id", "invalid-uuid"))
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestOperation_Reversal() {
+	walletID := uuid.New()
+	originalID := uuid.New()
+	body, _ := json.Marshal(OperationRequest{
+		WalletID:            walletID.String(),
+		OperationType:       "REVERSAL",
+		Amount:              40,
+		OriginalOperationID: originalID.String(),
+	})
+
+	s.walletService.EXPECT().
+		Reverse(gomock.Any(), walletID, originalID, decimal.NewFromFloat(40), "").
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperation_ReversalInvalidOriginalID() {
+	body, _ := json.Marshal(OperationRequest{WalletID: uuid.NewString(), OperationType: "REVERSAL", Amount: 40})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperationV2_ReversalErrors() {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{service.ErrOperationNotFound, http.StatusNotFound},
+		{service.ErrOperationNotReversible, http.StatusConflict},
+		{service.ErrAlreadyReversed, http.StatusConflict},
+		{service.ErrReversalExceedsOriginal, http.StatusBadRequest},
+		{service.ErrInsufficientFunds, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		s.walletService.EXPECT().
+			Reverse(gomock.Any(), gomock.Any(), gomock.Any(), decimal.RequireFromString("12.30"), "").
+			Return(tt.err)
+
+		body, _ := json.Marshal(OperationRequestV2{
+			WalletID:            uuid.NewString(),
+			OperationType:       "REVERSAL",
+			Amount:              "12.30",
+			OriginalOperationID: uuid.NewString(),
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/wallet", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		s.handler.OperationV2(w, req)
+
+		s.Equal(tt.code, w.Code, tt.err.Error())
+	}
+}
+
+func (s *WalletHandlersSuite) TestListOperationsV2_ReversalChain() {
+	walletID := uuid.New()
+	depositID := uuid.New()
+	reversalID := uuid.New()
+	createdAt := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	s.walletService.EXPECT().
+		ListOperations(gomock.Any(), walletID, gomock.Any()).
+		Return(&service.OperationsPage{Currency: "RUB", Operations: []service.Operation{
+			{
+				ID:           reversalID,
+				Type:         "REVERSAL",
+				Amount:       decimal.RequireFromString("40"),
+				BalanceAfter: decimal.RequireFromString("60"),
+				ReversalOf:   &depositID,
+				CreatedAt:    createdAt,
+			},
+			{
+				ID:           depositID,
+				Type:         "DEPOSIT",
+				Amount:       decimal.RequireFromString("100"),
+				BalanceAfter: decimal.RequireFromString("100"),
+				ReversedBy:   []uuid.UUID{reversalID},
+				CreatedAt:    createdAt.Add(-time.Hour),
+			},
+		}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/wallets/"+walletID.String()+"/operations", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperationsV2(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{
+		"walletId": "`+walletID.String()+`",
+		"operations": [
+			{
+				"id": "`+reversalID.String()+`",
+				"operationType": "REVERSAL",
+				"amount": "40.00",
+				"balanceAfter": "60.00",
+				"reversalOf": "`+depositID.String()+`",
+				"createdAt": "2025-01-31T12:00:00Z"
+			},
+			{
+				"id": "`+depositID.String()+`",
+				"operationType": "DEPOSIT",
+				"amount": "100.00",
+				"balanceAfter": "100.00",
+				"reversedBy": ["`+reversalID.String()+`"],
+				"createdAt": "2025-01-31T11:00:00Z"
+			}
+		]
+	}`, strings.TrimSpace(w.Body.String()))
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var statementContentTypes = map[string]string{
+	service.StatementCSV:   "text/csv; charset=utf-8",
+	service.StatementJSONL: "application/x-ndjson",
+}
+
+// statementWriteTimeout bounds how long the client may take to accept each
+// write of a statement. It replaces the server's write timeout, which covers
+// the whole response and would cut long statements short.
+const statementWriteTimeout = 30 * time.Second
+
+// statementResponse sets the statement headers on the first write, so that
+// errors returned before it can still be written as JSON. Without a
+// Content-Length the statement is sent chunked as it is written.
+type statementResponse struct {
+	http.ResponseWriter
+	rc          *http.ResponseController
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (sr *statementResponse) Write(p []byte) (int, error) {
+	// Writers that cannot move the deadline are left with their own.
+	_ = sr.rc.SetWriteDeadline(time.Now().Add(statementWriteTimeout))
+	if !sr.started {
+		sr.started = true
+		sr.Header().Set("Content-Type", sr.contentType)
+		sr.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sr.filename))
+		sr.WriteHeader(http.StatusOK)
+	}
+	return sr.ResponseWriter.Write(p)
+}
+
+// Statement godoc
+// @Summary Export wallet statement
+// @Description Streams the operations of a period with the opening balance, the signed amount and running balance of every operation, and the closing balance. The statement is not subject to the request timeout; it is sent for as long as the client keeps reading it. CSV has one row per operation between OPENING_BALANCE and CLOSING_BALANCE rows; JSON Lines has one object per line, told apart by type.
+// @Tags Wallet
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param id path string true "Wallet UUID"
+// @Param from query string false "Include operations created at or after this time (RFC3339); the whole history by default"
+// @Param to query string false "Include operations created before this time (RFC3339); now by default"
+// @Param format query string false "Statement format" Enums(csv, jsonl) default(csv)
+// @Success 200 {string} string "Statement"
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v1/wallets/{id}/statement [get]
+// @Router /api/v2/wallets/{id}/statement [get]
+func (h *Handler) Statement(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	params := r.URL.Query()
+	query := service.StatementQuery{Format: params.Get("format")}
+	if query.Format == "" {
+		query.Format = service.StatementCSV
+	}
+	contentType, ok := statementContentTypes[query.Format]
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+	if v := params.Get("from"); v != "" {
+		if query.From, err = time.Parse(time.RFC3339, v); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "from must be an RFC3339 timestamp")
+			return
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if query.To, err = time.Parse(time.RFC3339, v); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "to must be an RFC3339 timestamp")
+			return
+		}
+	}
+
+	sr := &statementResponse{
+		ResponseWriter: w,
+		rc:             http.NewResponseController(w),
+		contentType:    contentType,
+		filename:       fmt.Sprintf("statement-%s.%s", walletID, query.Format),
+	}
+	err = h.service.WriteStatement(r.Context(), walletID, query, sr)
+	if err == nil {
+		return
+	}
+	if sr.started {
+		// The status is already sent; drop the connection so that the client
+		// does not take the cut short statement for a whole one.
+		h.log.Error("statement interrupted",
+			slog.String("error", err.Error()),
+			slog.String("wallet_id", walletID.String()),
+		)
+		panic(http.ErrAbortHandler)
+	}
+
+	switch {
+	case errors.Is(err, service.ErrWalletNotFound):
+		response.WriteError(w, http.StatusNotFound, "wallet not found")
+	case errors.Is(err, service.ErrInvalidTimeRange):
+		response.WriteError(w, http.StatusBadRequest, "from must be before to")
+	case errors.Is(err, service.ErrInvalidStatementFormat):
+		response.WriteError(w, http.StatusBadRequest, "format must be csv or jsonl")
+	default:
+		h.log.Error("failed to write statement",
+			slog.String("error", err.Error()),
+			slog.String("wallet_id", walletID.String()),
+		)
+		response.WriteError(w, http.StatusInternalServerError, "failed to write statement")
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestStatement_CSV() {
+	walletID := uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	s.walletService.EXPECT().
+		WriteStatement(gomock.Any(), walletID, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, query service.StatementQuery, w io.Writer) error {
+			s.Equal(service.StatementCSV, query.Format)
+			s.True(from.Equal(query.From))
+			s.True(to.Equal(query.To))
+			_, err := io.WriteString(w, "date,operation_id\n")
+			return err
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/statement?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.Statement(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	s.Equal(`attachment; filename="statement-`+walletID.String()+`.csv"`, w.Header().Get("Content-Disposition"))
+	s.Equal("date,operation_id\n", w.Body.String())
+}
+
+func (s *WalletHandlersSuite) TestStatement_JSONL() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		WriteStatement(gomock.Any(), walletID, service.StatementQuery{Format: service.StatementJSONL}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _ service.StatementQuery, w io.Writer) error {
+			_, err := io.WriteString(w, `{"type":"opening"}`+"\n")
+			return err
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/statement?format=jsonl", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.Statement(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("application/x-ndjson", w.Header().Get("Content-Type"))
+}
+
+// TestStatement_OutlastsWriteTimeout streams a statement for longer than the
+// server's write timeout, which must not cut it short.
+func (s *WalletHandlersSuite) TestStatement_OutlastsWriteTimeout() {
+	walletID := uuid.NewString()
+	chunk := strings.Repeat("x", 16<<10) + "\n"
+
+	s.walletService.EXPECT().
+		WriteStatement(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _ service.StatementQuery, w io.Writer) error {
+			for range 5 {
+				if _, err := io.WriteString(w, chunk); err != nil {
+					return err
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			return nil
+		})
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handler.Statement(w, withURLParam(r, "id", walletID))
+	}))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/wallets/" + walletID + "/statement")
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(strings.Repeat(chunk, 5), string(body))
+}
+
+func (s *WalletHandlersSuite) TestStatement_InvalidQuery() {
+	walletID := uuid.NewString()
+	for _, query := range []string{"?format=xlsx", "?from=yesterday", "?to=2025-01-31"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID+"/statement"+query, nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Statement(w, withURLParam(req, "id", walletID))
+
+		s.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func (s *WalletHandlersSuite) TestStatement_Errors() {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{service.ErrWalletNotFound, http.StatusNotFound},
+		{service.ErrInvalidTimeRange, http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		s.walletService.EXPECT().
+			WriteStatement(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(tt.err)
+
+		walletID := uuid.NewString()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID+"/statement", nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Statement(w, withURLParam(req, "id", walletID))
+
+		s.Equal(tt.code, w.Code, tt.err.Error())
+		s.Equal("application/json", w.Header().Get("Content-Type"))
+	}
+}
+
+func (s *WalletHandlersSuite) TestStatement_InterruptedAbortsResponse() {
+	s.walletService.EXPECT().
+		WriteStatement(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _ service.StatementQuery, w io.Writer) error {
+			_, _ = io.WriteString(w, "date,operation_id\n")
+			return errors.New("connection reset")
+		})
+
+	walletID := uuid.NewString()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID+"/statement", nil)
+	w := httptest.NewRecorder()
+
+	s.PanicsWithValue(http.ErrAbortHandler, func() {
+		s.handler.Statement(w, withURLParam(req, "id", walletID))
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SetStatusRequest struct {
+	Status        string `json:"status" example:"FROZEN" enums:"ACTIVE,FROZEN,CLOSED"`
+	BlockDeposits bool   `json:"blockDeposits,omitempty" example:"false"`
+	Reason        string `json:"reason" example:"Suspicious activity, case 4711"`
+	Actor         string `json:"actor" example:"compliance@example.com"`
+}
+
+type StatusChangeResponse struct {
+	ID              string `json:"id" example:"950e8400-e29b-41d4-a716-446655440000"`
+	WalletID        string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	FromStatus      string `json:"fromStatus" example:"ACTIVE" enums:"ACTIVE,FROZEN,CLOSED"`
+	Status          string `json:"status" example:"FROZEN" enums:"ACTIVE,FROZEN,CLOSED"`
+	DepositsBlocked bool   `json:"depositsBlocked" example:"false"`
+	Reason          string `json:"reason" example:"Suspicious activity, case 4711"`
+	Actor           string `json:"actor" example:"compliance@example.com"`
+	CreatedAt       string `json:"createdAt" example:"2025-01-31T23:59:59Z"`
+}
+
+// SetStatus godoc
+// @Summary Change wallet status
+// @Description Freezes, unfreezes or closes a wallet. A FROZEN wallet rejects withdrawals, transfers out, new holds and captures, and deposits too when blockDeposits is set; a CLOSED wallet rejects everything and can only be closed with a zero balance. Every change is recorded with its reason and actor.
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param request body SetStatusRequest true "New status"
+// @Success 200 {object} StatusChangeResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 409 {object} response.Response "Transition not allowed or wallet balance is not zero"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v1/wallets/{id}/status [patch]
+// @Router /api/v2/wallets/{id}/status [patch]
+func (h *Handler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	var req SetStatusRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	change, err := h.service.SetWalletStatus(r.Context(), walletID, service.StatusUpdate{
+		Status:          req.Status,
+		DepositsBlocked: req.BlockDeposits,
+		Reason:          req.Reason,
+		Actor:           req.Actor,
+	})
+	if err != nil {
+		if writeLockError(w, err) || writeCommitError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrWalletNotFound):
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+		case errors.Is(err, service.ErrInvalidWalletStatus):
+			response.WriteError(w, http.StatusBadRequest, "status must be ACTIVE, FROZEN or CLOSED")
+		case errors.Is(err, service.ErrStatusReasonRequired):
+			response.WriteError(w, http.StatusBadRequest, "reason and actor are required")
+		case errors.Is(err, service.ErrInvalidStatusTransition):
+			response.WriteError(w, http.StatusConflict, "status transition is not allowed")
+		case errors.Is(err, service.ErrWalletNotEmpty):
+			response.WriteError(w, http.StatusConflict, "wallet balance must be zero to close it")
+		default:
+			h.log.Error("failed to set wallet status",
+				slog.String("error", err.Error()),
+				slog.String("wallet_id", walletID.String()),
+			)
+			response.WriteError(w, http.StatusInternalServerError, "failed to set wallet status")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(StatusChangeResponse{
+		ID:              change.ID.String(),
+		WalletID:        change.WalletID.String(),
+		FromStatus:      change.FromStatus,
+		Status:          change.ToStatus,
+		DepositsBlocked: change.DepositsBlocked,
+		Reason:          change.Reason,
+		Actor:           change.Actor,
+		CreatedAt:       change.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// writeStatusError writes a 409 response when the wallet status does not
+// allow the operation and reports whether err was such an error.
+func writeStatusError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrWalletFrozen):
+		response.WriteError(w, http.StatusConflict, "wallet is frozen")
+	case errors.Is(err, service.ErrWalletClosed):
+		response.WriteError(w, http.StatusConflict, "wallet is closed")
+	default:
+		return false
+	}
+	return true
+}
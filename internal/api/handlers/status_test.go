@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) newStatusRequest(walletID string, body any) *http.Request {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/wallets/"+walletID+"/status", bytes.NewReader(data))
+	return withURLParam(req, "id", walletID)
+}
+
+func (s *WalletHandlersSuite) TestSetStatus_Success() {
+	walletID := uuid.New()
+	change := &service.StatusChange{
+		ID:              uuid.New(),
+		WalletID:        walletID,
+		FromStatus:      "ACTIVE",
+		ToStatus:        "FROZEN",
+		DepositsBlocked: true,
+		Reason:          "fraud",
+		Actor:           "compliance",
+		CreatedAt:       time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+
+	s.walletService.EXPECT().
+		SetWalletStatus(gomock.Any(), walletID, service.StatusUpdate{
+			Status:          "FROZEN",
+			DepositsBlocked: true,
+			Reason:          "fraud",
+			Actor:           "compliance",
+		}).
+		Return(change, nil)
+
+	w := httptest.NewRecorder()
+	s.handler.SetStatus(w, s.newStatusRequest(walletID.String(), SetStatusRequest{
+		Status:        "FROZEN",
+		BlockDeposits: true,
+		Reason:        "fraud",
+		Actor:         "compliance",
+	}))
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response StatusChangeResponse
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal(change.ID.String(), response.ID)
+	s.Equal("ACTIVE", response.FromStatus)
+	s.Equal("FROZEN", response.Status)
+	s.True(response.DepositsBlocked)
+	s.Equal("compliance", response.Actor)
+	s.Equal("2025-01-31T12:00:00Z", response.CreatedAt)
+}
+
+func (s *WalletHandlersSuite) TestSetStatus_Errors() {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{service.ErrWalletNotFound, http.StatusNotFound},
+		{service.ErrInvalidWalletStatus, http.StatusBadRequest},
+		{service.ErrStatusReasonRequired, http.StatusBadRequest},
+		{service.ErrInvalidStatusTransition, http.StatusConflict},
+		{service.ErrWalletNotEmpty, http.StatusConflict},
+		{service.ErrWalletBusy, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		walletID := uuid.New()
+		s.walletService.EXPECT().
+			SetWalletStatus(gomock.Any(), walletID, gomock.Any()).
+			Return(nil, tt.err)
+
+		w := httptest.NewRecorder()
+		s.handler.SetStatus(w, s.newStatusRequest(walletID.String(), SetStatusRequest{Status: "CLOSED", Reason: "r", Actor: "a"}))
+
+		s.Equal(tt.code, w.Code, tt.err.Error())
+	}
+}
+
+func (s *WalletHandlersSuite) TestSetStatus_InvalidWalletID() {
+	w := httptest.NewRecorder()
+	s.handler.SetStatus(w, s.newStatusRequest("not-a-uuid", SetStatusRequest{Status: "FROZEN"}))
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperation_WalletFrozen() {
+	walletID := uuid.New()
+	body, _ := json.Marshal(OperationRequest{WalletID: walletID.String(), OperationType: "WITHDRAW", Amount: 100})
+
+	s.walletService.EXPECT().
+		Withdraw(gomock.Any(), walletID, decimal.NewFromFloat(100), "").
+		Return(service.ErrWalletFrozen)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusConflict, w.Code)
+	s.Contains(w.Body.String(), "wallet is frozen")
+}
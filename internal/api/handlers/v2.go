@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+	"ITK/pkg/currency"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// The v2 API carries every amount as a JSON string holding a plain decimal
+// number, so values reach the service without passing through float64.
+
+// amountPattern accepts plain decimal notation only: no exponent, no leading
+// plus sign and no surrounding whitespace.
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+var errInvalidAmountFormat = errors.New("amount must be a decimal string")
+
+type OperationRequestV2 struct {
+	WalletID            string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	OperationType       string `json:"operationType" example:"DEPOSIT" enums:"DEPOSIT,WITHDRAW,REVERSAL"`
+	Amount              string `json:"amount" example:"1000.50"`
+	Currency            string `json:"currency,omitempty" example:"USD"`
+	OriginalOperationID string `json:"originalOperationId,omitempty" example:"650e8400-e29b-41d4-a716-446655440000"`
+}
+
+type TransferRequestV2 struct {
+	FromWalletID string `json:"fromWalletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ToWalletID   string `json:"toWalletId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Amount       string `json:"amount" example:"250.00"`
+	Currency     string `json:"currency,omitempty" example:"USD"`
+}
+
+type BalanceResponseV2 struct {
+	WalletID  string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Currency  string `json:"currency" example:"USD"`
+	Status    string `json:"status" example:"ACTIVE" enums:"ACTIVE,FROZEN,CLOSED"`
+	Balance   string `json:"balance" example:"5000.50"`
+	Available string `json:"available" example:"4500.50"`
+}
+
+type HistoricalBalanceResponseV2 struct {
+	WalletID string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Currency string `json:"currency" example:"USD"`
+	At       string `json:"at" example:"2025-01-31T21:00:00Z"`
+	Balance  string `json:"balance" example:"5000.50"`
+}
+
+type OperationResponseV2 struct {
+	ID            string   `json:"id" example:"650e8400-e29b-41d4-a716-446655440000"`
+	OperationType string   `json:"operationType" example:"DEPOSIT" enums:"DEPOSIT,WITHDRAW,TRANSFER_IN,TRANSFER_OUT,REVERSAL"`
+	Amount        string   `json:"amount" example:"1000.50"`
+	BalanceAfter  string   `json:"balanceAfter" example:"5000.50"`
+	TransferID    string   `json:"transferId,omitempty" example:"750e8400-e29b-41d4-a716-446655440000"`
+	ReversalOf    string   `json:"reversalOf,omitempty" example:"650e8400-e29b-41d4-a716-446655440001"`
+	ReversedBy    []string `json:"reversedBy,omitempty"`
+	CreatedAt     string   `json:"createdAt" example:"2025-01-31T23:59:59.123456Z"`
+}
+
+type OperationsResponseV2 struct {
+	WalletID   string                `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Operations []OperationResponseV2 `json:"operations"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+type PlaceHoldRequestV2 struct {
+	Amount     string `json:"amount" example:"150.00"`
+	TTLSeconds int    `json:"ttlSeconds,omitempty" example:"3600"`
+}
+
+type CaptureHoldRequestV2 struct {
+	Amount string `json:"amount,omitempty" example:"120.00"`
+}
+
+type HoldResponseV2 struct {
+	ID             string `json:"id" example:"850e8400-e29b-41d4-a716-446655440000"`
+	WalletID       string `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Amount         string `json:"amount" example:"150.00"`
+	CapturedAmount string `json:"capturedAmount" example:"0"`
+	Status         string `json:"status" example:"ACTIVE" enums:"ACTIVE,CAPTURED,RELEASED,EXPIRED"`
+	ExpiresAt      string `json:"expiresAt" example:"2025-01-31T23:59:59Z"`
+	CreatedAt      string `json:"createdAt" example:"2025-01-30T23:59:59Z"`
+}
+
+// parseAmount parses an amount sent as a decimal string. The number of
+// decimal places is checked later against the wallet currency.
+func parseAmount(s string) (decimal.Decimal, error) {
+	if !amountPattern.MatchString(s) {
+		return decimal.Zero, errInvalidAmountFormat
+	}
+	return decimal.NewFromString(s)
+}
+
+// formatAmount renders amount with as many decimal places as its currency
+// has, so that 10.50 USD is sent as "10.50" rather than "10.5".
+func formatAmount(amount decimal.Decimal, code string) string {
+	cur, ok := currency.Lookup(code)
+	if !ok {
+		return amount.String()
+	}
+	return amount.StringFixed(cur.MinorUnits)
+}
+
+// OperationV2 godoc
+// @Summary Execute wallet operation
+// @Description Executes a deposit, withdrawal or reversal on a wallet. The amount is a decimal string and must not have more decimal places than the wallet currency allows. A REVERSAL applies the opposite of originalOperationId's balance change; reversals of one operation may not add up to more than its amount, and reversing a transfer leg reverses both legs.
+// @Tags Wallet v2
+// @Accept json
+// @Produce json
+// @Param request body OperationRequestV2 true "Operation details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} response.Response "Invalid request or reversal exceeds the original amount"
+// @Failure 404 {object} response.Response "Wallet or original operation not found"
+// @Failure 409 {object} response.Response "Insufficient funds, wallet is frozen or closed, or operation cannot be reversed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v2/wallet [post]
+func (h *Handler) OperationV2(w http.ResponseWriter, r *http.Request) {
+	var req OperationRequestV2
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	amount, err := parseAmount(req.Amount)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, errInvalidAmountFormat.Error())
+		return
+	}
+
+	h.operation(w, r, req.WalletID, req.OperationType, req.OriginalOperationID, amount, req.Currency)
+}
+
+// TransferV2 godoc
+// @Summary Transfer funds between wallets
+// @Description Atomically debits the source wallet and credits the destination wallet. The amount is a decimal string.
+// @Tags Wallet v2
+// @Accept json
+// @Produce json
+// @Param request body TransferRequestV2 true "Transfer details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 409 {object} response.Response "Insufficient funds, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v2/transfers [post]
+func (h *Handler) TransferV2(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequestV2
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	amount, err := parseAmount(req.Amount)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, errInvalidAmountFormat.Error())
+		return
+	}
+
+	h.transfer(w, r, req.FromWalletID, req.ToWalletID, amount, req.Currency)
+}
+
+// GetBalanceV2 godoc
+// @Summary Get wallet balance
+// @Description Returns the current and available balance of a wallet as decimal strings with the decimal places of the wallet currency
+// @Tags Wallet v2
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Success 200 {object} BalanceResponseV2
+// @Failure 400 {object} response.Response "Invalid wallet ID"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v2/wallets/{id} [get]
+func (h *Handler) GetBalanceV2(w http.ResponseWriter, r *http.Request) {
+	balance, ok := h.getBalance(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BalanceResponseV2{
+		WalletID:  balance.WalletID.String(),
+		Currency:  balance.Currency,
+		Status:    balance.Status,
+		Balance:   formatAmount(balance.Balance, balance.Currency),
+		Available: formatAmount(balance.Available, balance.Currency),
+	})
+}
+
+// GetBalanceAtV2 godoc
+// @Summary Get wallet balance at a point in time
+// @Description Returns the balance of a wallet right after its last operation created at or before the given time as a decimal string with the decimal places of the wallet currency
+// @Tags Wallet v2
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param at query string true "Point in time (RFC3339)"
+// @Success 200 {object} HistoricalBalanceResponseV2
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v2/wallets/{id}/balance [get]
+func (h *Handler) GetBalanceAtV2(w http.ResponseWriter, r *http.Request) {
+	balance, ok := h.getBalanceAt(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HistoricalBalanceResponseV2{
+		WalletID: balance.WalletID.String(),
+		Currency: balance.Currency,
+		At:       balance.At.UTC().Format(time.RFC3339Nano),
+		Balance:  formatAmount(balance.Balance, balance.Currency),
+	})
+}
+
+// ListOperationsV2 godoc
+// @Summary List wallet operations
+// @Description Returns wallet operations from newest to oldest using cursor pagination. Amounts are decimal strings with the decimal places of the wallet currency.
+// @Tags Wallet v2
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param type query []string false "Operation types to include" collectionFormat(csv)
+// @Param from query string false "Include operations created at or after this time (RFC3339)"
+// @Param to query string false "Include operations created before this time (RFC3339)"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} OperationsResponseV2
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v2/wallets/{id}/operations [get]
+func (h *Handler) ListOperationsV2(w http.ResponseWriter, r *http.Request) {
+	walletID, page, ok := h.listOperations(w, r)
+	if !ok {
+		return
+	}
+
+	resp := OperationsResponseV2{
+		WalletID:   walletID.String(),
+		Operations: make([]OperationResponseV2, 0, len(page.Operations)),
+		NextCursor: page.NextCursor,
+	}
+	for _, op := range page.Operations {
+		item := OperationResponseV2{
+			ID:            op.ID.String(),
+			OperationType: op.Type,
+			Amount:        formatAmount(op.Amount, page.Currency),
+			BalanceAfter:  formatAmount(op.BalanceAfter, page.Currency),
+			CreatedAt:     op.CreatedAt.UTC().Format(time.RFC3339Nano),
+		}
+		if op.TransferID != nil {
+			item.TransferID = op.TransferID.String()
+		}
+		item.ReversalOf, item.ReversedBy = reversalLinks(op)
+		resp.Operations = append(resp.Operations, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PlaceHoldV2 godoc
+// @Summary Place a hold on wallet funds
+// @Description Reserves part of the wallet balance. The amount is a decimal string.
+// @Tags Holds v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param request body PlaceHoldRequestV2 true "Hold details"
+// @Success 201 {object} HoldResponseV2
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 409 {object} response.Response "Insufficient available funds, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v2/wallets/{id}/holds [post]
+func (h *Handler) PlaceHoldV2(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return
+	}
+
+	var req PlaceHoldRequestV2
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	amount, err := parseAmount(req.Amount)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, errInvalidAmountFormat.Error())
+		return
+	}
+
+	hold, ok := h.placeHold(w, r, walletID, amount, req.TTLSeconds)
+	if !ok {
+		return
+	}
+
+	writeHoldV2(w, http.StatusCreated, hold)
+}
+
+// GetHoldV2 godoc
+// @Summary Get hold
+// @Description Returns a hold by its ID with amounts as decimal strings
+// @Tags Holds v2
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Success 200 {object} HoldResponseV2
+// @Failure 400 {object} response.Response "Invalid hold ID"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v2/holds/{holdId} [get]
+func (h *Handler) GetHoldV2(w http.ResponseWriter, r *http.Request) {
+	if hold, ok := h.holdAction(w, r, h.service.GetHold, "failed to get hold"); ok {
+		writeHoldV2(w, http.StatusOK, hold)
+	}
+}
+
+// CaptureHoldV2 godoc
+// @Summary Capture a hold
+// @Description Withdraws the given amount (or the whole hold if omitted) from the held funds and releases the rest. The amount is a decimal string.
+// @Tags Holds v2
+// @Accept json
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Param request body CaptureHoldRequestV2 false "Capture details"
+// @Success 200 {object} HoldResponseV2
+// @Failure 400 {object} response.Response "Invalid request or amount exceeds hold"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 409 {object} response.Response "Hold is not active, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v2/holds/{holdId}/capture [post]
+func (h *Handler) CaptureHoldV2(w http.ResponseWriter, r *http.Request) {
+	holdID, err := uuid.Parse(chi.URLParam(r, "holdId"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid hold ID format")
+		return
+	}
+
+	var req CaptureHoldRequestV2
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// An omitted amount means "capture the whole hold".
+	amount := decimal.Zero
+	if req.Amount != "" {
+		if amount, err = parseAmount(req.Amount); err != nil {
+			response.WriteError(w, http.StatusBadRequest, errInvalidAmountFormat.Error())
+			return
+		}
+		if !amount.IsPositive() {
+			response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+			return
+		}
+	}
+
+	hold, err := h.service.CaptureHold(r.Context(), holdID, amount)
+	if err != nil {
+		h.writeHoldError(w, err, holdID, "failed to capture hold")
+		return
+	}
+
+	writeHoldV2(w, http.StatusOK, hold)
+}
+
+// ReleaseHoldV2 godoc
+// @Summary Release a hold
+// @Description Cancels an active hold and returns the reserved funds to the available balance
+// @Tags Holds v2
+// @Produce json
+// @Param holdId path string true "Hold UUID"
+// @Success 200 {object} HoldResponseV2
+// @Failure 400 {object} response.Response "Invalid hold ID"
+// @Failure 404 {object} response.Response "Hold not found"
+// @Failure 409 {object} response.Response "Hold is not active"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v2/holds/{holdId}/release [post]
+func (h *Handler) ReleaseHoldV2(w http.ResponseWriter, r *http.Request) {
+	if hold, ok := h.holdAction(w, r, h.service.ReleaseHold, "failed to release hold"); ok {
+		writeHoldV2(w, http.StatusOK, hold)
+	}
+}
+
+func writeHoldV2(w http.ResponseWriter, status int, hold *service.Hold) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(HoldResponseV2{
+		ID:             hold.ID.String(),
+		WalletID:       hold.WalletID.String(),
+		Amount:         hold.Amount.String(),
+		CapturedAmount: hold.CapturedAmount.String(),
+		Status:         hold.Status,
+		ExpiresAt:      hold.ExpiresAt.UTC().Format(time.RFC3339),
+		CreatedAt:      hold.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
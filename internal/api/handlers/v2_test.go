@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletHandlersSuite) TestOperationV2_ExactAmount() {
+	walletID := uuid.New()
+
+	body := []byte(`{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":"12345678901234567.89"}`)
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, decimal.RequireFromString("12345678901234567.89"), "").
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.OperationV2(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperationV2_RejectsNonStringAmount() {
+	walletID := uuid.New()
+
+	for _, amount := range []string{`100.5`, `"1e3"`, `" 10"`, `"abc"`, `""`} {
+		body := []byte(`{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":` + amount + `}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/wallet", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		s.handler.OperationV2(w, req)
+
+		s.Equal(http.StatusBadRequest, w.Code, amount)
+	}
+}
+
+func (s *WalletHandlersSuite) TestOperationV2_NegativeAmount() {
+	body := []byte(`{"walletId":"` + uuid.NewString() + `","operationType":"WITHDRAW","amount":"-1.00"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.OperationV2(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperationV2_TooManyDecimalPlaces() {
+	walletID := uuid.New()
+	amount := decimal.RequireFromString("10.005")
+
+	body := []byte(`{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":"10.005"}`)
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, amount, "").
+		Return(service.ErrInvalidAmountPrecision)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.OperationV2(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestTransferV2_Success() {
+	fromID := uuid.New()
+	toID := uuid.New()
+
+	body, _ := json.Marshal(TransferRequestV2{
+		FromWalletID: fromID.String(),
+		ToWalletID:   toID.String(),
+		Amount:       "0.30",
+	})
+
+	s.walletService.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, decimal.RequireFromString("0.30"), "").
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/transfers", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.TransferV2(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceV2_ExactStrings() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		GetBalance(gomock.Any(), walletID).
+		Return(&service.WalletBalance{
+			WalletID:  walletID,
+			Currency:  "RUB",
+			Balance:   decimal.RequireFromString("98765432109876543.21"),
+			Available: decimal.RequireFromString("0.3"),
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/wallets/"+walletID.String(), nil)
+	req = withURLParam(req, "id", walletID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.GetBalanceV2(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response map[string]any
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal("98765432109876543.21", response["balance"])
+	s.Equal("0.30", response["available"])
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceV2_CurrencyScale() {
+	for _, tt := range []struct {
+		currency, balance, want string
+	}{
+		{"USD", "10.5", "10.50"},
+		{"JPY", "1500", "1500"},
+		{"BHD", "1.2", "1.200"},
+	} {
+		walletID := uuid.New()
+		s.walletService.EXPECT().
+			GetBalance(gomock.Any(), walletID).
+			Return(&service.WalletBalance{
+				WalletID:  walletID,
+				Currency:  tt.currency,
+				Balance:   decimal.RequireFromString(tt.balance),
+				Available: decimal.RequireFromString(tt.balance),
+			}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/wallets/"+walletID.String(), nil)
+		w := httptest.NewRecorder()
+
+		s.handler.GetBalanceV2(w, withURLParam(req, "id", walletID.String()))
+
+		var response BalanceResponseV2
+		s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+		s.Equal(tt.want, response.Balance, tt.currency)
+		s.Equal(tt.want, response.Available, tt.currency)
+	}
+}
+
+func (s *WalletHandlersSuite) TestListOperationsV2_CurrencyScale() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		ListOperations(gomock.Any(), walletID, gomock.Any()).
+		Return(&service.OperationsPage{Currency: "BHD", Operations: []service.Operation{{
+			ID:           uuid.New(),
+			Type:         "DEPOSIT",
+			Amount:       decimal.RequireFromString("1.5"),
+			BalanceAfter: decimal.RequireFromString("10"),
+		}}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/wallets/"+walletID.String()+"/operations", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperationsV2(w, withURLParam(req, "id", walletID.String()))
+
+	var response OperationsResponseV2
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Require().Len(response.Operations, 1)
+	s.Equal("1.500", response.Operations[0].Amount)
+	s.Equal("10.000", response.Operations[0].BalanceAfter)
+}
+
+func (s *WalletHandlersSuite) TestCaptureHoldV2_PartialAmount() {
+	holdID := uuid.New()
+	amount := decimal.RequireFromString("120.10")
+	hold := &service.Hold{ID: holdID, WalletID: uuid.New(), Amount: decimal.RequireFromString("150"), CapturedAmount: amount, Status: "CAPTURED"}
+
+	s.walletService.EXPECT().
+		CaptureHold(gomock.Any(), holdID, amount).
+		Return(hold, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/holds/"+holdID.String()+"/capture", bytes.NewReader([]byte(`{"amount":"120.10"}`)))
+	req = withURLParam(req, "holdId", holdID.String())
+	w := httptest.NewRecorder()
+
+	s.handler.CaptureHoldV2(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response HoldResponseV2
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal("120.1", response.CapturedAmount)
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceAtV2_ExactString() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		BalanceAt(gomock.Any(), walletID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, at time.Time) (*service.HistoricalBalance, error) {
+			return &service.HistoricalBalance{
+				WalletID: walletID,
+				Currency: "RUB",
+				At:       at,
+				Balance:  decimal.RequireFromString("98765432109876543.21"),
+			}, nil
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/wallets/"+walletID.String()+"/balance?at=2025-01-31T21:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.GetBalanceAtV2(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response map[string]any
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	s.Equal("98765432109876543.21", response["balance"])
+	s.Equal("2025-01-31T21:00:00Z", response["at"])
+}
@@ -6,9 +6,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"ITK/internal/api/middleware/idempotency"
 	"ITK/internal/service"
 	"ITK/pkg/api/response"
 
@@ -18,10 +23,29 @@ import (
 )
 
 type WalletService interface {
-	CreateWallet(ctx context.Context) (uuid.UUID, error)
+	CreateWallet(ctx context.Context, currency string) (uuid.UUID, error)
 	GetBalance(ctx context.Context, walletID uuid.UUID) (*service.WalletBalance, error)
-	Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error
-	Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error
+	Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error
+	Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error
+	Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal, currency string) error
+	ListOperations(ctx context.Context, walletID uuid.UUID, query service.OperationsQuery) (*service.OperationsPage, error)
+	BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*service.HistoricalBalance, error)
+	WriteStatement(ctx context.Context, walletID uuid.UUID, query service.StatementQuery, w io.Writer) error
+	PlaceHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*service.Hold, error)
+	GetHold(ctx context.Context, holdID uuid.UUID) (*service.Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*service.Hold, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) (*service.Hold, error)
+	SetWalletStatus(ctx context.Context, walletID uuid.UUID, update service.StatusUpdate) (*service.StatusChange, error)
+	PutLimitPolicy(ctx context.Context, policy service.LimitPolicy) (*service.LimitPolicy, error)
+	ListLimitPolicies(ctx context.Context) ([]service.LimitPolicy, error)
+	SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits service.WalletLimits) error
+	Reverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal, currency string) error
+	TrialBalance(ctx context.Context) (*service.TrialBalance, error)
+	ReconcileWallet(ctx context.Context, walletID uuid.UUID) (*service.Reconciliation, error)
+}
+
+type CreateWalletRequest struct {
+	Currency string `json:"currency,omitempty" example:"USD"`
 }
 
 type CreateWalletResponse struct {
@@ -30,15 +54,53 @@ type CreateWalletResponse struct {
 
 type OperationRequest struct {
 	WalletID      string  `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
-	OperationType string  `json:"operationType" example:"DEPOSIT" enums:"DEPOSIT,WITHDRAW"`
+	OperationType string  `json:"operationType" example:"DEPOSIT" enums:"DEPOSIT,WITHDRAW,REVERSAL"`
 	Amount        float64 `json:"amount" example:"1000.50"`
+	Currency      string  `json:"currency,omitempty" example:"USD"`
+	// OriginalOperationID names the operation of the wallet that a REVERSAL
+	// reverses. Other operation types ignore it.
+	OriginalOperationID string `json:"originalOperationId,omitempty" example:"650e8400-e29b-41d4-a716-446655440000"`
+}
+
+type TransferRequest struct {
+	FromWalletID string  `json:"fromWalletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ToWalletID   string  `json:"toWalletId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Amount       float64 `json:"amount" example:"250.00"`
+	Currency     string  `json:"currency,omitempty" example:"USD"`
 }
 
 type BalanceResponse struct {
+	WalletID  string  `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Currency  string  `json:"currency" example:"USD"`
+	Status    string  `json:"status" example:"ACTIVE" enums:"ACTIVE,FROZEN,CLOSED"`
+	Balance   float64 `json:"balance" example:"5000.50"`
+	Available float64 `json:"available" example:"4500.50"`
+}
+
+type HistoricalBalanceResponse struct {
 	WalletID string  `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Currency string  `json:"currency" example:"USD"`
+	At       string  `json:"at" example:"2025-01-31T21:00:00Z"`
 	Balance  float64 `json:"balance" example:"5000.50"`
 }
 
+type OperationResponse struct {
+	ID            string   `json:"id" example:"650e8400-e29b-41d4-a716-446655440000"`
+	OperationType string   `json:"operationType" example:"DEPOSIT" enums:"DEPOSIT,WITHDRAW,TRANSFER_IN,TRANSFER_OUT,REVERSAL"`
+	Amount        float64  `json:"amount" example:"1000.50"`
+	BalanceAfter  float64  `json:"balanceAfter" example:"5000.50"`
+	TransferID    string   `json:"transferId,omitempty" example:"750e8400-e29b-41d4-a716-446655440000"`
+	ReversalOf    string   `json:"reversalOf,omitempty" example:"650e8400-e29b-41d4-a716-446655440001"`
+	ReversedBy    []string `json:"reversedBy,omitempty"`
+	CreatedAt     string   `json:"createdAt" example:"2025-01-31T23:59:59.123456Z"`
+}
+
+type OperationsResponse struct {
+	WalletID   string              `json:"walletId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Operations []OperationResponse `json:"operations"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
 type SuccessResponse struct {
 	Status string `json:"status" example:"success"`
 }
@@ -57,18 +119,39 @@ func New(service WalletService, log *slog.Logger) *Handler {
 
 // Create godoc
 // @Summary Create new wallet
-// @Description Creates a new wallet with zero balance and returns its UUID
+// @Description Creates a new wallet with zero balance in the given ISO 4217 currency (or the default one) and returns its UUID
 // @Tags Wallet
 // @Accept json
 // @Produce json
+// @Param request body CreateWalletRequest false "Wallet details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
 // @Success 201 {object} CreateWalletResponse
+// @Failure 400 {object} response.Response "Unsupported currency"
+// @Failure 409 {object} response.Response "Request with the same idempotency key is in progress"
+// @Failure 422 {object} response.Response "Idempotency key reused with a different request"
 // @Failure 500 {object} response.Response
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
 // @Router /api/v1/wallet/create [post]
+// @Router /api/v2/wallet/create [post]
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	walletID, err := h.service.CreateWallet(ctx)
+	var req CreateWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	walletID, err := h.service.CreateWallet(ctx, req.Currency)
 	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedCurrency) {
+			response.WriteError(w, http.StatusBadRequest, "unsupported currency")
+			return
+		}
+		if writeCommitError(w, err) {
+			return
+		}
 		h.log.Error("failed to create wallet", slog.String("error", err.Error()))
 		response.WriteError(w, http.StatusInternalServerError, "failed to create wallet")
 		return
@@ -83,19 +166,24 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 // Operation godoc
 // @Summary Execute wallet operation
-// @Description Executes a deposit or withdrawal operation on a wallet
+// @Description Executes a deposit, withdrawal or reversal on a wallet. A REVERSAL applies the opposite of originalOperationId's balance change; reversals of one operation may not add up to more than its amount, and reversing a transfer leg reverses both legs.
 // @Tags Wallet
 // @Accept json
 // @Produce json
 // @Param request body OperationRequest true "Operation details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
 // @Success 200 {object} SuccessResponse
-// @Failure 400 {object} response.Response "Invalid request or insufficient funds"
-// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 400 {object} response.Response "Invalid request or reversal exceeds the original amount"
+// @Failure 404 {object} response.Response "Wallet or original operation not found"
+// @Failure 409 {object} response.Response "Insufficient funds, wallet is frozen or closed, or operation cannot be reversed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
 // @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
 // @Router /api/v1/wallet [post]
 func (h *Handler) Operation(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
 	var req OperationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.log.Warn("invalid request body", slog.String("error", err.Error()))
@@ -103,33 +191,51 @@ func (h *Handler) Operation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.operation(w, r, req.WalletID, req.OperationType, req.OriginalOperationID, decimal.NewFromFloat(req.Amount), req.Currency)
+}
+
+// operation validates and executes a deposit, withdrawal or reversal. It is
+// shared by the v1 and v2 handlers, which differ only in how amounts are
+// encoded.
+func (h *Handler) operation(w http.ResponseWriter, r *http.Request, walletIDStr, opType, originalIDStr string, amount decimal.Decimal, currencyCode string) {
+	ctx := r.Context()
+
 	// Validate wallet ID
-	walletID, err := uuid.Parse(req.WalletID)
+	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
 		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
 		return
 	}
 
 	// Validate operation type
-	if req.OperationType != "DEPOSIT" && req.OperationType != "WITHDRAW" {
-		response.WriteError(w, http.StatusBadRequest, "operation type must be DEPOSIT or WITHDRAW")
+	if opType != "DEPOSIT" && opType != "WITHDRAW" && opType != "REVERSAL" {
+		response.WriteError(w, http.StatusBadRequest, "operation type must be DEPOSIT, WITHDRAW or REVERSAL")
 		return
 	}
 
+	var originalID uuid.UUID
+	if opType == "REVERSAL" {
+		if originalID, err = uuid.Parse(originalIDStr); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid original operation ID format")
+			return
+		}
+	}
+
 	// Validate amount
-	if req.Amount <= 0 {
+	if !amount.IsPositive() {
 		response.WriteError(w, http.StatusBadRequest, "amount must be positive")
 		return
 	}
 
-	amount := decimal.NewFromFloat(req.Amount)
-
 	// Execute operation
 	var opErr error
-	if req.OperationType == "DEPOSIT" {
-		opErr = h.service.Deposit(ctx, walletID, amount)
-	} else {
-		opErr = h.service.Withdraw(ctx, walletID, amount)
+	switch opType {
+	case "DEPOSIT":
+		opErr = h.service.Deposit(ctx, walletID, amount, currencyCode)
+	case "WITHDRAW":
+		opErr = h.service.Withdraw(ctx, walletID, amount, currencyCode)
+	default:
+		opErr = h.service.Reverse(ctx, walletID, originalID, amount, currencyCode)
 	}
 
 	if opErr != nil {
@@ -145,10 +251,14 @@ func (h *Handler) Operation(w http.ResponseWriter, r *http.Request) {
 			response.WriteError(w, http.StatusBadRequest, "amount must be positive")
 			return
 		}
+		if writeCurrencyError(w, opErr) || writeLockError(w, opErr) || writeCommitError(w, opErr) || writeStatusError(w, opErr) ||
+			writeLimitError(w, opErr) || writeReversalError(w, opErr) {
+			return
+		}
 		h.log.Error("failed to execute operation",
 			slog.String("error", opErr.Error()),
 			slog.String("wallet_id", walletID.String()),
-			slog.String("operation", req.OperationType),
+			slog.String("operation", opType),
 		)
 		response.WriteError(w, http.StatusInternalServerError, "failed to execute operation")
 		return
@@ -159,9 +269,95 @@ func (h *Handler) Operation(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(SuccessResponse{Status: "success"})
 }
 
+// Transfer godoc
+// @Summary Transfer funds between wallets
+// @Description Atomically debits the source wallet and credits the destination wallet
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Param request body TransferRequest true "Transfer details"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 409 {object} response.Response "Insufficient funds, or wallet is frozen or closed"
+// @Failure 421 {object} response.Response "Wallet is served by another instance, see X-Wallet-Owner"
+// @Failure 422 {object} LimitExceededResponse "Limit policy of the wallet exceeded, or idempotency key reused with a different request"
+// @Failure 429 {object} response.Response "Wallet is busy, see Retry-After"
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response "Timed out waiting for the wallet, see Retry-After"
+// @Failure 504 {object} response.Response "Outcome of the request is unknown, check the wallet before repeating it"
+// @Router /api/v1/transfers [post]
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.transfer(w, r, req.FromWalletID, req.ToWalletID, decimal.NewFromFloat(req.Amount), req.Currency)
+}
+
+func (h *Handler) transfer(w http.ResponseWriter, r *http.Request, fromIDStr, toIDStr string, amount decimal.Decimal, currencyCode string) {
+	ctx := r.Context()
+
+	fromID, err := uuid.Parse(fromIDStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid source wallet ID format")
+		return
+	}
+
+	toID, err := uuid.Parse(toIDStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid destination wallet ID format")
+		return
+	}
+
+	if !amount.IsPositive() {
+		response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+
+	err = h.service.Transfer(ctx, fromID, toID, amount, currencyCode)
+	if err != nil {
+		if errors.Is(err, service.ErrWalletNotFound) {
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+			return
+		}
+		if errors.Is(err, service.ErrInsufficientFunds) {
+			response.WriteError(w, http.StatusConflict, "insufficient funds")
+			return
+		}
+		if errors.Is(err, service.ErrSameWallet) {
+			response.WriteError(w, http.StatusBadRequest, "source and destination wallets must differ")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidAmount) {
+			response.WriteError(w, http.StatusBadRequest, "amount must be positive")
+			return
+		}
+		if writeCurrencyError(w, err) || writeLockError(w, err) || writeCommitError(w, err) || writeStatusError(w, err) ||
+			writeLimitError(w, err) {
+			return
+		}
+		h.log.Error("failed to execute transfer",
+			slog.String("error", err.Error()),
+			slog.String("from_wallet_id", fromID.String()),
+			slog.String("to_wallet_id", toID.String()),
+		)
+		response.WriteError(w, http.StatusInternalServerError, "failed to execute transfer")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SuccessResponse{Status: "success"})
+}
+
 // GetBalance godoc
 // @Summary Get wallet balance
-// @Description Returns the current balance of a wallet
+// @Description Returns the current balance of a wallet and the part of it not reserved by holds
 // @Tags Wallet
 // @Produce json
 // @Param id path string true "Wallet UUID"
@@ -171,26 +367,67 @@ func (h *Handler) Operation(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} response.Response
 // @Router /api/v1/wallets/{id} [get]
 func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	balance, ok := h.getBalance(w, r)
+	if !ok {
+		return
+	}
+
+	balanceFloat, _ := balance.Balance.Float64()
+	availableFloat, _ := balance.Available.Float64()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BalanceResponse{
+		WalletID:  balance.WalletID.String(),
+		Currency:  balance.Currency,
+		Status:    balance.Status,
+		Balance:   balanceFloat,
+		Available: availableFloat,
+	})
+}
 
+// getBalance loads the balance of the wallet named in the URL. On failure it
+// writes the error response and returns false.
+func (h *Handler) getBalance(w http.ResponseWriter, r *http.Request) (*service.WalletBalance, bool) {
 	walletIDStr := chi.URLParam(r, "id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
 		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
-		return
+		return nil, false
 	}
 
-	balance, err := h.service.GetBalance(ctx, walletID)
+	balance, err := h.service.GetBalance(r.Context(), walletID)
 	if err != nil {
 		if errors.Is(err, service.ErrWalletNotFound) {
 			response.WriteError(w, http.StatusNotFound, "wallet not found")
-			return
+			return nil, false
 		}
 		h.log.Error("failed to get balance",
 			slog.String("error", err.Error()),
 			slog.String("wallet_id", walletID.String()),
 		)
 		response.WriteError(w, http.StatusInternalServerError, "failed to get balance")
+		return nil, false
+	}
+
+	return balance, true
+}
+
+// GetBalanceAt godoc
+// @Summary Get wallet balance at a point in time
+// @Description Returns the balance of a wallet right after its last operation created at or before the given time, or zero if there were none
+// @Tags Wallet
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param at query string true "Point in time (RFC3339)"
+// @Success 200 {object} HistoricalBalanceResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v1/wallets/{id}/balance [get]
+func (h *Handler) GetBalanceAt(w http.ResponseWriter, r *http.Request) {
+	balance, ok := h.getBalanceAt(w, r)
+	if !ok {
 		return
 	}
 
@@ -198,8 +435,250 @@ func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(BalanceResponse{
+	json.NewEncoder(w).Encode(HistoricalBalanceResponse{
 		WalletID: balance.WalletID.String(),
+		Currency: balance.Currency,
+		At:       balance.At.UTC().Format(time.RFC3339Nano),
 		Balance:  balanceFloat,
 	})
 }
+
+// getBalanceAt loads the balance of the wallet named in the URL as of the
+// at query parameter. On failure it writes the error response and returns
+// false.
+func (h *Handler) getBalanceAt(w http.ResponseWriter, r *http.Request) (*service.HistoricalBalance, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return nil, false
+	}
+
+	at, err := time.Parse(time.RFC3339, r.URL.Query().Get("at"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "at must be an RFC3339 timestamp")
+		return nil, false
+	}
+
+	balance, err := h.service.BalanceAt(r.Context(), walletID, at)
+	if err != nil {
+		if errors.Is(err, service.ErrWalletNotFound) {
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+			return nil, false
+		}
+		h.log.Error("failed to get historical balance",
+			slog.String("error", err.Error()),
+			slog.String("wallet_id", walletID.String()),
+		)
+		response.WriteError(w, http.StatusInternalServerError, "failed to get balance")
+		return nil, false
+	}
+
+	return balance, true
+}
+
+// ListOperations godoc
+// @Summary List wallet operations
+// @Description Returns wallet operations from newest to oldest using cursor pagination
+// @Tags Wallet
+// @Produce json
+// @Param id path string true "Wallet UUID"
+// @Param type query []string false "Operation types to include" collectionFormat(csv)
+// @Param from query string false "Include operations created at or after this time (RFC3339)"
+// @Param to query string false "Include operations created before this time (RFC3339)"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} OperationsResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Wallet not found"
+// @Failure 500 {object} response.Response
+// @Router /api/v1/wallets/{id}/operations [get]
+func (h *Handler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	walletID, page, ok := h.listOperations(w, r)
+	if !ok {
+		return
+	}
+
+	resp := OperationsResponse{
+		WalletID:   walletID.String(),
+		Operations: make([]OperationResponse, 0, len(page.Operations)),
+		NextCursor: page.NextCursor,
+	}
+	for _, op := range page.Operations {
+		resp.Operations = append(resp.Operations, toOperationResponse(op))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// listOperations parses the history query and loads one page of operations.
+// On failure it writes the error response and returns false.
+func (h *Handler) listOperations(w http.ResponseWriter, r *http.Request) (uuid.UUID, *service.OperationsPage, bool) {
+	walletID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid wallet ID format")
+		return uuid.Nil, nil, false
+	}
+
+	params := r.URL.Query()
+	query := service.OperationsQuery{
+		Cursor: params.Get("cursor"),
+	}
+
+	for _, v := range params["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				query.Types = append(query.Types, strings.ToUpper(t))
+			}
+		}
+	}
+
+	if v := params.Get("from"); v != "" {
+		if query.From, err = time.Parse(time.RFC3339, v); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "from must be an RFC3339 timestamp")
+			return uuid.Nil, nil, false
+		}
+	}
+	if v := params.Get("to"); v != "" {
+		if query.To, err = time.Parse(time.RFC3339, v); err != nil {
+			response.WriteError(w, http.StatusBadRequest, "to must be an RFC3339 timestamp")
+			return uuid.Nil, nil, false
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return uuid.Nil, nil, false
+		}
+	}
+
+	page, err := h.service.ListOperations(r.Context(), walletID, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWalletNotFound):
+			response.WriteError(w, http.StatusNotFound, "wallet not found")
+		case errors.Is(err, service.ErrInvalidCursor):
+			response.WriteError(w, http.StatusBadRequest, "invalid cursor")
+		case errors.Is(err, service.ErrInvalidOperationType):
+			response.WriteError(w, http.StatusBadRequest, "invalid operation type")
+		case errors.Is(err, service.ErrInvalidTimeRange):
+			response.WriteError(w, http.StatusBadRequest, "from must be before to")
+		default:
+			h.log.Error("failed to list operations",
+				slog.String("error", err.Error()),
+				slog.String("wallet_id", walletID.String()),
+			)
+			response.WriteError(w, http.StatusInternalServerError, "failed to list operations")
+		}
+		return uuid.Nil, nil, false
+	}
+
+	return walletID, page, true
+}
+
+func toOperationResponse(op service.Operation) OperationResponse {
+	amount, _ := op.Amount.Float64()
+	balanceAfter, _ := op.BalanceAfter.Float64()
+
+	resp := OperationResponse{
+		ID:            op.ID.String(),
+		OperationType: op.Type,
+		Amount:        amount,
+		BalanceAfter:  balanceAfter,
+		CreatedAt:     op.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if op.TransferID != nil {
+		resp.TransferID = op.TransferID.String()
+	}
+	resp.ReversalOf, resp.ReversedBy = reversalLinks(op)
+	return resp
+}
+
+// reversalLinks formats the reversal chain of op for responses.
+func reversalLinks(op service.Operation) (reversalOf string, reversedBy []string) {
+	if op.ReversalOf != nil {
+		reversalOf = op.ReversalOf.String()
+	}
+	for _, id := range op.ReversedBy {
+		reversedBy = append(reversedBy, id.String())
+	}
+	return reversalOf, reversedBy
+}
+
+// writeReversalError writes the response for errors specific to reversals
+// and reports whether err was one of them.
+func writeReversalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrOperationNotFound):
+		response.WriteError(w, http.StatusNotFound, "operation not found")
+	case errors.Is(err, service.ErrOperationNotReversible):
+		response.WriteError(w, http.StatusConflict, "operation cannot be reversed")
+	case errors.Is(err, service.ErrAlreadyReversed):
+		response.WriteError(w, http.StatusConflict, "operation is already fully reversed")
+	case errors.Is(err, service.ErrReversalExceedsOriginal):
+		response.WriteError(w, http.StatusBadRequest, "reversal amount exceeds the amount not yet reversed")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeCurrencyError writes a 400 response for currency validation errors and
+// reports whether err was one of them.
+func writeCurrencyError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrUnsupportedCurrency):
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency")
+	case errors.Is(err, service.ErrCurrencyMismatch):
+		response.WriteError(w, http.StatusBadRequest, "currency does not match wallet currency")
+	case errors.Is(err, service.ErrInvalidAmountPrecision):
+		response.WriteError(w, http.StatusBadRequest, "amount has more decimal places than the currency allows")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeCommitError writes idempotency.StatusOutcomeUnknown when the service
+// could not learn whether a write was committed and reports whether err was
+// such an error. The idempotency middleware keeps the answer so that a retry
+// with the same key does not apply the write a second time.
+func writeCommitError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrCommitUnknown) {
+		return false
+	}
+	response.WriteError(w, idempotency.StatusOutcomeUnknown, "outcome of the request is unknown, check the wallet before repeating it")
+	return true
+}
+
+// retryAfterSeconds is the Retry-After hint sent when a wallet lock could not
+// be taken in time.
+const retryAfterSeconds = "1"
+
+// HeaderWalletOwner names the instance that owns the wallet when wallet locks
+// are routed by consistent hashing.
+const HeaderWalletOwner = "X-Wallet-Owner"
+
+// writeLockError writes a retryable response when the request gave up waiting
+// for the wallet lock and reports whether err was such an error. A wallet
+// with too long a queue answers 429; a request cancelled by the client or the
+// router timeout answers 503. A wallet owned by another instance answers 421
+// with the owner in HeaderWalletOwner.
+func writeLockError(w http.ResponseWriter, err error) bool {
+	var notOwner *service.NotOwnerError
+	switch {
+	case errors.As(err, &notOwner):
+		w.Header().Set(HeaderWalletOwner, notOwner.Owner)
+		response.WriteError(w, http.StatusMisdirectedRequest, "wallet is served by another instance")
+	case errors.Is(err, service.ErrWalletBusy):
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		response.WriteError(w, http.StatusTooManyRequests, "wallet is busy, retry later")
+	case errors.Is(err, service.ErrRequestAborted):
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		response.WriteError(w, http.StatusServiceUnavailable, "request timed out waiting for wallet")
+	default:
+		return false
+	}
+	return true
+}
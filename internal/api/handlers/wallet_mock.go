@@ -12,7 +12,9 @@ package handlers
 import (
 	service "ITK/internal/service"
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	decimal "github.com/shopspring/decimal"
@@ -43,33 +45,63 @@ func (m *MockWalletService) EXPECT() *MockWalletServiceMockRecorder {
 	return m.recorder
 }
 
+// BalanceAt mocks base method.
+func (m *MockWalletService) BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*service.HistoricalBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BalanceAt", ctx, walletID, at)
+	ret0, _ := ret[0].(*service.HistoricalBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceAt indicates an expected call of BalanceAt.
+func (mr *MockWalletServiceMockRecorder) BalanceAt(ctx, walletID, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceAt", reflect.TypeOf((*MockWalletService)(nil).BalanceAt), ctx, walletID, at)
+}
+
+// CaptureHold mocks base method.
+func (m *MockWalletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*service.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, holdID, amount)
+	ret0, _ := ret[0].(*service.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockWalletServiceMockRecorder) CaptureHold(ctx, holdID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockWalletService)(nil).CaptureHold), ctx, holdID, amount)
+}
+
 // CreateWallet mocks base method.
-func (m *MockWalletService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+func (m *MockWalletService) CreateWallet(ctx context.Context, currency string) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateWallet", ctx)
+	ret := m.ctrl.Call(m, "CreateWallet", ctx, currency)
 	ret0, _ := ret[0].(uuid.UUID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateWallet indicates an expected call of CreateWallet.
-func (mr *MockWalletServiceMockRecorder) CreateWallet(ctx any) *gomock.Call {
+func (mr *MockWalletServiceMockRecorder) CreateWallet(ctx, currency any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockWalletService)(nil).CreateWallet), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockWalletService)(nil).CreateWallet), ctx, currency)
 }
 
 // Deposit mocks base method.
-func (m *MockWalletService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (m *MockWalletService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deposit", ctx, walletID, amount)
+	ret := m.ctrl.Call(m, "Deposit", ctx, walletID, amount, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Deposit indicates an expected call of Deposit.
-func (mr *MockWalletServiceMockRecorder) Deposit(ctx, walletID, amount any) *gomock.Call {
+func (mr *MockWalletServiceMockRecorder) Deposit(ctx, walletID, amount, currency any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockWalletService)(nil).Deposit), ctx, walletID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockWalletService)(nil).Deposit), ctx, walletID, amount, currency)
 }
 
 // GetBalance mocks base method.
@@ -87,16 +119,207 @@ func (mr *MockWalletServiceMockRecorder) GetBalance(ctx, walletID any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockWalletService)(nil).GetBalance), ctx, walletID)
 }
 
+// GetHold mocks base method.
+func (m *MockWalletService) GetHold(ctx context.Context, holdID uuid.UUID) (*service.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(*service.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockWalletServiceMockRecorder) GetHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockWalletService)(nil).GetHold), ctx, holdID)
+}
+
+// ListLimitPolicies mocks base method.
+func (m *MockWalletService) ListLimitPolicies(ctx context.Context) ([]service.LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLimitPolicies", ctx)
+	ret0, _ := ret[0].([]service.LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLimitPolicies indicates an expected call of ListLimitPolicies.
+func (mr *MockWalletServiceMockRecorder) ListLimitPolicies(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLimitPolicies", reflect.TypeOf((*MockWalletService)(nil).ListLimitPolicies), ctx)
+}
+
+// ListOperations mocks base method.
+func (m *MockWalletService) ListOperations(ctx context.Context, walletID uuid.UUID, query service.OperationsQuery) (*service.OperationsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOperations", ctx, walletID, query)
+	ret0, _ := ret[0].(*service.OperationsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOperations indicates an expected call of ListOperations.
+func (mr *MockWalletServiceMockRecorder) ListOperations(ctx, walletID, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOperations", reflect.TypeOf((*MockWalletService)(nil).ListOperations), ctx, walletID, query)
+}
+
+// PlaceHold mocks base method.
+func (m *MockWalletService) PlaceHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*service.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceHold", ctx, walletID, amount, ttl)
+	ret0, _ := ret[0].(*service.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceHold indicates an expected call of PlaceHold.
+func (mr *MockWalletServiceMockRecorder) PlaceHold(ctx, walletID, amount, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceHold", reflect.TypeOf((*MockWalletService)(nil).PlaceHold), ctx, walletID, amount, ttl)
+}
+
+// PutLimitPolicy mocks base method.
+func (m *MockWalletService) PutLimitPolicy(ctx context.Context, policy service.LimitPolicy) (*service.LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutLimitPolicy", ctx, policy)
+	ret0, _ := ret[0].(*service.LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutLimitPolicy indicates an expected call of PutLimitPolicy.
+func (mr *MockWalletServiceMockRecorder) PutLimitPolicy(ctx, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutLimitPolicy", reflect.TypeOf((*MockWalletService)(nil).PutLimitPolicy), ctx, policy)
+}
+
+// ReconcileWallet mocks base method.
+func (m *MockWalletService) ReconcileWallet(ctx context.Context, walletID uuid.UUID) (*service.Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileWallet", ctx, walletID)
+	ret0, _ := ret[0].(*service.Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileWallet indicates an expected call of ReconcileWallet.
+func (mr *MockWalletServiceMockRecorder) ReconcileWallet(ctx, walletID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileWallet", reflect.TypeOf((*MockWalletService)(nil).ReconcileWallet), ctx, walletID)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockWalletService) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*service.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID)
+	ret0, _ := ret[0].(*service.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockWalletServiceMockRecorder) ReleaseHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockWalletService)(nil).ReleaseHold), ctx, holdID)
+}
+
+// Reverse mocks base method.
+func (m *MockWalletService) Reverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", ctx, walletID, operationID, amount, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockWalletServiceMockRecorder) Reverse(ctx, walletID, operationID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockWalletService)(nil).Reverse), ctx, walletID, operationID, amount, currency)
+}
+
+// SetWalletLimits mocks base method.
+func (m *MockWalletService) SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits service.WalletLimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWalletLimits", ctx, walletID, limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWalletLimits indicates an expected call of SetWalletLimits.
+func (mr *MockWalletServiceMockRecorder) SetWalletLimits(ctx, walletID, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWalletLimits", reflect.TypeOf((*MockWalletService)(nil).SetWalletLimits), ctx, walletID, limits)
+}
+
+// SetWalletStatus mocks base method.
+func (m *MockWalletService) SetWalletStatus(ctx context.Context, walletID uuid.UUID, update service.StatusUpdate) (*service.StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWalletStatus", ctx, walletID, update)
+	ret0, _ := ret[0].(*service.StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetWalletStatus indicates an expected call of SetWalletStatus.
+func (mr *MockWalletServiceMockRecorder) SetWalletStatus(ctx, walletID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWalletStatus", reflect.TypeOf((*MockWalletService)(nil).SetWalletStatus), ctx, walletID, update)
+}
+
+// Transfer mocks base method.
+func (m *MockWalletService) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, fromID, toID, amount, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockWalletServiceMockRecorder) Transfer(ctx, fromID, toID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockWalletService)(nil).Transfer), ctx, fromID, toID, amount, currency)
+}
+
+// TrialBalance mocks base method.
+func (m *MockWalletService) TrialBalance(ctx context.Context) (*service.TrialBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrialBalance", ctx)
+	ret0, _ := ret[0].(*service.TrialBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrialBalance indicates an expected call of TrialBalance.
+func (mr *MockWalletServiceMockRecorder) TrialBalance(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrialBalance", reflect.TypeOf((*MockWalletService)(nil).TrialBalance), ctx)
+}
+
 // Withdraw mocks base method.
-func (m *MockWalletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (m *MockWalletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Withdraw", ctx, walletID, amount)
+	ret := m.ctrl.Call(m, "Withdraw", ctx, walletID, amount, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Withdraw indicates an expected call of Withdraw.
-func (mr *MockWalletServiceMockRecorder) Withdraw(ctx, walletID, amount any) *gomock.Call {
+func (mr *MockWalletServiceMockRecorder) Withdraw(ctx, walletID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockWalletService)(nil).Withdraw), ctx, walletID, amount, currency)
+}
+
+// WriteStatement mocks base method.
+func (m *MockWalletService) WriteStatement(ctx context.Context, walletID uuid.UUID, query service.StatementQuery, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteStatement", ctx, walletID, query, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteStatement indicates an expected call of WriteStatement.
+func (mr *MockWalletServiceMockRecorder) WriteStatement(ctx, walletID, query, w any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockWalletService)(nil).Withdraw), ctx, walletID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteStatement", reflect.TypeOf((*MockWalletService)(nil).WriteStatement), ctx, walletID, query, w)
 }
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"ITK/internal/api/middleware/idempotency"
+	"ITK/internal/repository"
 	"ITK/internal/service"
 
 	"github.com/go-chi/chi/v5"
@@ -51,7 +55,7 @@ func (s *WalletHandlersSuite) TestCreate_Success() {
 	walletID := uuid.New()
 
 	s.walletService.EXPECT().
-		CreateWallet(gomock.Any()).
+		CreateWallet(gomock.Any(), "").
 		Return(walletID, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/create", nil)
@@ -69,7 +73,7 @@ func (s *WalletHandlersSuite) TestCreate_Success() {
 
 func (s *WalletHandlersSuite) TestCreate_ServiceError() {
 	s.walletService.EXPECT().
-		CreateWallet(gomock.Any()).
+		CreateWallet(gomock.Any(), "").
 		Return(uuid.Nil, service.ErrInvalidAmount)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/create", nil)
@@ -80,6 +84,58 @@ func (s *WalletHandlersSuite) TestCreate_ServiceError() {
 	s.Equal(http.StatusInternalServerError, w.Code)
 }
 
+func (s *WalletHandlersSuite) TestCreate_WithCurrency() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		CreateWallet(gomock.Any(), "USD").
+		Return(walletID, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/create", bytes.NewReader([]byte(`{"currency":"USD"}`)))
+	w := httptest.NewRecorder()
+
+	s.handler.Create(w, req)
+
+	s.Equal(http.StatusCreated, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestCreate_UnsupportedCurrency() {
+	s.walletService.EXPECT().
+		CreateWallet(gomock.Any(), "XYZ").
+		Return(uuid.Nil, service.ErrUnsupportedCurrency)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/create", bytes.NewReader([]byte(`{"currency":"XYZ"}`)))
+	w := httptest.NewRecorder()
+
+	s.handler.Create(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestOperation_CurrencyMismatch() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(100)
+
+	operationReq := OperationRequest{
+		WalletID:      walletID.String(),
+		OperationType: "DEPOSIT",
+		Amount:        100,
+		Currency:      "EUR",
+	}
+	body, _ := json.Marshal(operationReq)
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, amount, "EUR").
+		Return(service.ErrCurrencyMismatch)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
 func (s *WalletHandlersSuite) TestOperation_DepositSuccess() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(1000.50)
@@ -92,7 +148,7 @@ func (s *WalletHandlersSuite) TestOperation_DepositSuccess() {
 	body, _ := json.Marshal(operationReq)
 
 	s.walletService.EXPECT().
-		Deposit(gomock.Any(), walletID, amount).
+		Deposit(gomock.Any(), walletID, amount, "").
 		Return(nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
@@ -108,6 +164,29 @@ func (s *WalletHandlersSuite) TestOperation_DepositSuccess() {
 	s.Equal("success", response.Status)
 }
 
+func (s *WalletHandlersSuite) TestOperation_CommitUnknownIsNotRepeated() {
+	walletID := uuid.New()
+	body := fmt.Sprintf(`{"walletId":"%s","operationType":"DEPOSIT","amount":100}`, walletID)
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, decimal.NewFromFloat(100), "").
+		Return(fmt.Errorf("failed to deposit: %w", service.ErrCommitUnknown)).
+		Times(1)
+
+	handler := idempotency.New(repository.NewMemoryIdempotency(), s.logger, time.Hour)(http.HandlerFunc(s.handler.Operation))
+
+	for _, replayed := range []string{"", "true"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewBufferString(body))
+		req.Header.Set(idempotency.HeaderKey, "key-1")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		s.Equal(idempotency.StatusOutcomeUnknown, w.Code)
+		s.Equal(replayed, w.Header().Get(idempotency.HeaderReplayed))
+	}
+}
+
 func (s *WalletHandlersSuite) TestOperation_WithdrawSuccess() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(500.25)
@@ -120,7 +199,7 @@ func (s *WalletHandlersSuite) TestOperation_WithdrawSuccess() {
 	body, _ := json.Marshal(operationReq)
 
 	s.walletService.EXPECT().
-		Withdraw(gomock.Any(), walletID, amount).
+		Withdraw(gomock.Any(), walletID, amount, "").
 		Return(nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
@@ -200,7 +279,7 @@ func (s *WalletHandlersSuite) TestOperation_WalletNotFound() {
 	body, _ := json.Marshal(operationReq)
 
 	s.walletService.EXPECT().
-		Deposit(gomock.Any(), walletID, amount).
+		Deposit(gomock.Any(), walletID, amount, "").
 		Return(service.ErrWalletNotFound)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
@@ -223,7 +302,7 @@ func (s *WalletHandlersSuite) TestOperation_InsufficientFunds() {
 	body, _ := json.Marshal(operationReq)
 
 	s.walletService.EXPECT().
-		Withdraw(gomock.Any(), walletID, amount).
+		Withdraw(gomock.Any(), walletID, amount, "").
 		Return(service.ErrInsufficientFunds)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
@@ -234,11 +313,173 @@ func (s *WalletHandlersSuite) TestOperation_InsufficientFunds() {
 	s.Equal(http.StatusConflict, w.Code)
 }
 
+func (s *WalletHandlersSuite) TestOperation_WalletBusy() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	body, _ := json.Marshal(OperationRequest{
+		WalletID:      walletID.String(),
+		OperationType: "DEPOSIT",
+		Amount:        1000,
+	})
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, amount, "").
+		Return(service.ErrWalletBusy)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("1", w.Header().Get("Retry-After"))
+}
+
+func (s *WalletHandlersSuite) TestOperation_RequestAborted() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	body, _ := json.Marshal(OperationRequest{
+		WalletID:      walletID.String(),
+		OperationType: "WITHDRAW",
+		Amount:        1000,
+	})
+
+	s.walletService.EXPECT().
+		Withdraw(gomock.Any(), walletID, amount, "").
+		Return(fmt.Errorf("%w: %w", service.ErrRequestAborted, context.DeadlineExceeded))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusServiceUnavailable, w.Code)
+	s.Equal("1", w.Header().Get("Retry-After"))
+}
+
+func (s *WalletHandlersSuite) TestOperation_WalletOwnedByAnotherInstance() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	body, _ := json.Marshal(OperationRequest{
+		WalletID:      walletID.String(),
+		OperationType: "DEPOSIT",
+		Amount:        1000,
+	})
+
+	s.walletService.EXPECT().
+		Deposit(gomock.Any(), walletID, amount, "").
+		Return(&service.NotOwnerError{Key: walletID.String(), Owner: "wallet-2:8080"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Operation(w, req)
+
+	s.Equal(http.StatusMisdirectedRequest, w.Code)
+	s.Equal("wallet-2:8080", w.Header().Get(HeaderWalletOwner))
+}
+
+func (s *WalletHandlersSuite) TestTransfer_Success() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(250.75)
+
+	transferReq := TransferRequest{
+		FromWalletID: fromID.String(),
+		ToWalletID:   toID.String(),
+		Amount:       250.75,
+	}
+	body, _ := json.Marshal(transferReq)
+
+	s.walletService.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, amount, "").
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Transfer(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response SuccessResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	s.NoError(err)
+	s.Equal("success", response.Status)
+}
+
+func (s *WalletHandlersSuite) TestTransfer_InvalidWalletID() {
+	transferReq := TransferRequest{
+		FromWalletID: uuid.New().String(),
+		ToWalletID:   "invalid-uuid",
+		Amount:       100,
+	}
+	body, _ := json.Marshal(transferReq)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Transfer(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestTransfer_SameWallet() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(100)
+
+	transferReq := TransferRequest{
+		FromWalletID: walletID.String(),
+		ToWalletID:   walletID.String(),
+		Amount:       100,
+	}
+	body, _ := json.Marshal(transferReq)
+
+	s.walletService.EXPECT().
+		Transfer(gomock.Any(), walletID, walletID, amount, "").
+		Return(service.ErrSameWallet)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Transfer(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestTransfer_InsufficientFunds() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	transferReq := TransferRequest{
+		FromWalletID: fromID.String(),
+		ToWalletID:   toID.String(),
+		Amount:       1000,
+	}
+	body, _ := json.Marshal(transferReq)
+
+	s.walletService.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, amount, "").
+		Return(service.ErrInsufficientFunds)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Transfer(w, req)
+
+	s.Equal(http.StatusConflict, w.Code)
+}
+
 func (s *WalletHandlersSuite) TestGetBalance_Success() {
 	walletID := uuid.New()
 	balance := &service.WalletBalance{
-		WalletID: walletID,
-		Balance:  decimal.NewFromFloat(5000.50),
+		WalletID:  walletID,
+		Balance:   decimal.NewFromFloat(5000.50),
+		Available: decimal.NewFromFloat(4500.50),
 	}
 
 	s.walletService.EXPECT().
@@ -262,6 +503,7 @@ func (s *WalletHandlersSuite) TestGetBalance_Success() {
 	s.NoError(err)
 	s.Equal(walletID.String(), response.WalletID)
 	s.Equal(5000.50, response.Balance)
+	s.Equal(4500.50, response.Available)
 }
 
 func (s *WalletHandlersSuite) TestGetBalance_InvalidWalletID() {
@@ -297,3 +539,170 @@ func (s *WalletHandlersSuite) TestGetBalance_WalletNotFound() {
 
 	s.Equal(http.StatusNotFound, w.Code)
 }
+
+func (s *WalletHandlersSuite) TestListOperations_Success() {
+	walletID := uuid.New()
+	transferID := uuid.New()
+	createdAt := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	page := &service.OperationsPage{
+		Operations: []service.Operation{
+			{
+				ID:           uuid.New(),
+				WalletID:     walletID,
+				Type:         "TRANSFER_IN",
+				Amount:       decimal.NewFromFloat(100.25),
+				BalanceAfter: decimal.NewFromFloat(600.25),
+				TransferID:   &transferID,
+				CreatedAt:    createdAt,
+			},
+		},
+		NextCursor: "next",
+	}
+
+	s.walletService.EXPECT().
+		ListOperations(gomock.Any(), walletID, service.OperationsQuery{
+			Types:  []string{"DEPOSIT", "TRANSFER_IN"},
+			From:   createdAt.Add(-time.Hour),
+			Cursor: "abc",
+			Limit:  10,
+		}).
+		Return(page, nil)
+
+	target := "/api/v1/wallets/" + walletID.String() + "/operations?type=deposit,TRANSFER_IN&from=2025-01-31T11:00:00Z&cursor=abc&limit=10"
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", walletID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperations(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+
+	var response OperationsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	s.NoError(err)
+	s.Equal("next", response.NextCursor)
+	s.Require().Len(response.Operations, 1)
+	s.Equal("TRANSFER_IN", response.Operations[0].OperationType)
+	s.Equal(100.25, response.Operations[0].Amount)
+	s.Equal(600.25, response.Operations[0].BalanceAfter)
+	s.Equal(transferID.String(), response.Operations[0].TransferID)
+	s.Equal("2025-01-31T12:00:00Z", response.Operations[0].CreatedAt)
+}
+
+func (s *WalletHandlersSuite) TestListOperations_InvalidLimit() {
+	walletID := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/operations?limit=abc", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", walletID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperations(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestListOperations_InvalidCursor() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		ListOperations(gomock.Any(), walletID, gomock.Any()).
+		Return(nil, service.ErrInvalidCursor)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/operations?cursor=bad", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", walletID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperations(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestListOperations_WalletNotFound() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		ListOperations(gomock.Any(), walletID, gomock.Any()).
+		Return(nil, service.ErrWalletNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/operations", nil)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", walletID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	s.handler.ListOperations(w, req)
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceAt_Success() {
+	walletID := uuid.New()
+	at := time.Date(2025, 1, 31, 21, 0, 0, 0, time.UTC)
+
+	s.walletService.EXPECT().
+		BalanceAt(gomock.Any(), walletID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, got time.Time) (*service.HistoricalBalance, error) {
+			s.True(at.Equal(got), got)
+			return &service.HistoricalBalance{
+				WalletID: walletID,
+				Currency: "RUB",
+				At:       got,
+				Balance:  decimal.RequireFromString("1500.5"),
+			}, nil
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/balance?at=2025-02-01T00:00:00%2B03:00", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.GetBalanceAt(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{
+		"walletId": "`+walletID.String()+`",
+		"currency": "RUB",
+		"at": "2025-01-31T21:00:00Z",
+		"balance": 1500.5
+	}`, w.Body.String())
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceAt_InvalidTime() {
+	walletID := uuid.NewString()
+	for _, query := range []string{"", "?at=yesterday", "?at=2025-01-31"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID+"/balance"+query, nil)
+		w := httptest.NewRecorder()
+
+		s.handler.GetBalanceAt(w, withURLParam(req, "id", walletID))
+
+		s.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func (s *WalletHandlersSuite) TestGetBalanceAt_WalletNotFound() {
+	walletID := uuid.New()
+
+	s.walletService.EXPECT().
+		BalanceAt(gomock.Any(), walletID, gomock.Any()).
+		Return(nil, service.ErrWalletNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/balance?at=2025-01-31T21:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	s.handler.GetBalanceAt(w, withURLParam(req, "id", walletID.String()))
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,307 @@
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=webhooks_mock.go -source=webhooks.go -package=handlers
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ITK/internal/service"
+	"ITK/pkg/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (*service.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]service.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]service.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id uuid.UUID) (*service.WebhookDelivery, error)
+	ReplayDeadDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" example:"https://example.com/hooks/wallet"`
+	Secret     string   `json:"secret,omitempty" example:"7f3c1e0b9a4d4c2e8b6a5f1d3e2c4b6a"`
+	EventTypes []string `json:"eventTypes,omitempty" example:"operation.created"`
+}
+
+// WebhookResponse describes a subscription. Secret is only set in the
+// response to Create.
+type WebhookResponse struct {
+	ID         string   `json:"id" example:"950e8400-e29b-41d4-a716-446655440000"`
+	URL        string   `json:"url" example:"https://example.com/hooks/wallet"`
+	Secret     string   `json:"secret,omitempty" example:"7f3c1e0b9a4d4c2e8b6a5f1d3e2c4b6a"`
+	EventTypes []string `json:"eventTypes"`
+	CreatedAt  string   `json:"createdAt" example:"2025-01-31T23:59:59Z"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string `json:"id" example:"a50e8400-e29b-41d4-a716-446655440000"`
+	EventID        string `json:"eventId" example:"b50e8400-e29b-41d4-a716-446655440000"`
+	SubscriptionID string `json:"subscriptionId" example:"950e8400-e29b-41d4-a716-446655440000"`
+	Status         string `json:"status" example:"DEAD" enums:"PENDING,DELIVERED,DEAD"`
+	Attempts       int    `json:"attempts" example:"12"`
+	NextAttemptAt  string `json:"nextAttemptAt" example:"2025-01-31T23:59:59Z"`
+	LastError      string `json:"lastError,omitempty" example:"unexpected status 503"`
+	CreatedAt      string `json:"createdAt" example:"2025-01-31T23:59:59Z"`
+}
+
+type ReplayResponse struct {
+	Replayed int64 `json:"replayed" example:"3"`
+}
+
+type WebhookHandler struct {
+	service WebhookService
+	log     *slog.Logger
+}
+
+func NewWebhooks(service WebhookService, log *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		log:     log.With(slog.String("component", "handlers/webhooks")),
+	}
+}
+
+// Create godoc
+// @Summary Register a webhook
+// @Description Subscribes a URL to wallet events. If no secret is given a random one is generated; the secret is only returned by this call.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Subscription details"
+// @Success 201 {object} WebhookResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 500 {object} response.Response
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Warn("invalid request body", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(r.Context(), req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebhookURL),
+			errors.Is(err, service.ErrInvalidWebhookSecret),
+			errors.Is(err, service.ErrUnknownEventType):
+			response.WriteError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("failed to create webhook", slog.String("error", err.Error()))
+			response.WriteError(w, http.StatusInternalServerError, "failed to create webhook")
+		}
+		return
+	}
+
+	resp := toWebhookResponse(*sub)
+	resp.Secret = sub.Secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// List godoc
+// @Summary List webhooks
+// @Description Returns all webhook subscriptions without their secrets
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} WebhookResponse
+// @Failure 500 {object} response.Response
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListSubscriptions(r.Context())
+	if err != nil {
+		h.log.Error("failed to list webhooks", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+
+	resp := make([]WebhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		resp = append(resp, toWebhookResponse(sub))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Delete godoc
+// @Summary Delete a webhook
+// @Description Removes a webhook subscription together with its pending and past deliveries
+// @Tags Admin
+// @Param id path string true "Subscription UUID"
+// @Success 204
+// @Failure 400 {object} response.Response "Invalid subscription ID"
+// @Failure 404 {object} response.Response "Subscription not found"
+// @Failure 500 {object} response.Response
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid subscription ID format")
+		return
+	}
+
+	if err = h.service.DeleteSubscription(r.Context(), id); err != nil {
+		h.writeError(w, err, "failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Returns the newest deliveries of a subscription
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Subscription UUID"
+// @Param status query string false "Delivery status" Enums(PENDING, DELIVERED, DEAD)
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {array} WebhookDeliveryResponse
+// @Failure 400 {object} response.Response "Invalid request"
+// @Failure 404 {object} response.Response "Subscription not found"
+// @Failure 500 {object} response.Response
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid subscription ID format")
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			response.WriteError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), id, status, limit)
+	if err != nil {
+		h.writeError(w, err, "failed to list webhook deliveries")
+		return
+	}
+
+	resp := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, toWebhookDeliveryResponse(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ReplayDead godoc
+// @Summary Replay dead deliveries
+// @Description Schedules every dead delivery of the subscription to be sent again with a fresh attempt budget
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Subscription UUID"
+// @Success 200 {object} ReplayResponse
+// @Failure 400 {object} response.Response "Invalid subscription ID"
+// @Failure 404 {object} response.Response "Subscription not found"
+// @Failure 500 {object} response.Response
+// @Router /admin/webhooks/{id}/replay [post]
+func (h *WebhookHandler) ReplayDead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid subscription ID format")
+		return
+	}
+
+	replayed, err := h.service.ReplayDeadDeliveries(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "failed to replay webhook deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReplayResponse{Replayed: replayed})
+}
+
+// ReplayDelivery godoc
+// @Summary Replay a delivery
+// @Description Schedules a single delivery to be sent again with a fresh attempt budget
+// @Tags Admin
+// @Produce json
+// @Param deliveryId path string true "Delivery UUID"
+// @Success 200 {object} WebhookDeliveryResponse
+// @Failure 400 {object} response.Response "Invalid delivery ID"
+// @Failure 404 {object} response.Response "Delivery not found"
+// @Failure 500 {object} response.Response
+// @Router /admin/webhook-deliveries/{deliveryId}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid delivery ID format")
+		return
+	}
+
+	delivery, err := h.service.ReplayDelivery(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "failed to replay webhook delivery")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toWebhookDeliveryResponse(*delivery))
+}
+
+func (h *WebhookHandler) writeError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, service.ErrWebhookSubscriptionNotFound):
+		response.WriteError(w, http.StatusNotFound, "webhook subscription not found")
+	case errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		response.WriteError(w, http.StatusNotFound, "webhook delivery not found")
+	case errors.Is(err, service.ErrInvalidDeliveryStatus):
+		response.WriteError(w, http.StatusBadRequest, "status must be PENDING, DELIVERED or DEAD")
+	default:
+		h.log.Error(msg, slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusInternalServerError, msg)
+	}
+}
+
+func toWebhookResponse(sub service.WebhookSubscription) WebhookResponse {
+	eventTypes := sub.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return WebhookResponse{
+		ID:         sub.ID.String(),
+		URL:        sub.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  sub.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func toWebhookDeliveryResponse(d service.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             d.ID.String(),
+		EventID:        d.EventID.String(),
+		SubscriptionID: d.SubscriptionID.String(),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt.UTC().Format(time.RFC3339),
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"ITK/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type WebhookHandlersSuite struct {
+	suite.Suite
+
+	ctrl           *gomock.Controller
+	webhookService *MockWebhookService
+	handler        *WebhookHandler
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	suite.Run(t, &WebhookHandlersSuite{})
+}
+
+func (s *WebhookHandlersSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.webhookService = NewMockWebhookService(s.ctrl)
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	s.handler = NewWebhooks(s.webhookService, logger)
+}
+
+func (s *WebhookHandlersSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *WebhookHandlersSuite) TestCreate_ReturnsSecretOnce() {
+	sub := &service.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        "https://example.com/hook",
+		Secret:     "generated-secret-value",
+		EventTypes: []string{"operation.created"},
+	}
+
+	s.webhookService.EXPECT().
+		CreateSubscription(gomock.Any(), "https://example.com/hook", "", []string{"operation.created"}).
+		Return(sub, nil)
+	s.webhookService.EXPECT().
+		ListSubscriptions(gomock.Any()).
+		Return([]service.WebhookSubscription{*sub}, nil)
+
+	body := []byte(`{"url":"https://example.com/hook","eventTypes":["operation.created"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	s.handler.Create(w, req)
+
+	s.Equal(http.StatusCreated, w.Code)
+	var created WebhookResponse
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &created))
+	s.Equal("generated-secret-value", created.Secret)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	w = httptest.NewRecorder()
+
+	s.handler.List(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	var listed []WebhookResponse
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &listed))
+	s.Len(listed, 1)
+	s.Empty(listed[0].Secret)
+	s.NotContains(w.Body.String(), "secret")
+}
+
+func (s *WebhookHandlersSuite) TestCreate_InvalidURL() {
+	s.webhookService.EXPECT().
+		CreateSubscription(gomock.Any(), "not a url", "", nil).
+		Return(nil, service.ErrInvalidWebhookURL)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhooks", bytes.NewReader([]byte(`{"url":"not a url"}`)))
+	w := httptest.NewRecorder()
+
+	s.handler.Create(w, req)
+
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+func (s *WebhookHandlersSuite) TestDelete_NotFound() {
+	id := uuid.New()
+	s.webhookService.EXPECT().
+		DeleteSubscription(gomock.Any(), id).
+		Return(service.ErrWebhookSubscriptionNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/webhooks/"+id.String(), nil)
+	req = withURLParam(req, "id", id.String())
+	w := httptest.NewRecorder()
+
+	s.handler.Delete(w, req)
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *WebhookHandlersSuite) TestListDeliveries_FiltersByStatus() {
+	id := uuid.New()
+	s.webhookService.EXPECT().
+		ListDeliveries(gomock.Any(), id, "DEAD", 20).
+		Return([]service.WebhookDelivery{{ID: uuid.New(), SubscriptionID: id, Status: "DEAD", Attempts: 12, LastError: "unexpected status 503"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/"+id.String()+"/deliveries?status=dead&limit=20", nil)
+	req = withURLParam(req, "id", id.String())
+	w := httptest.NewRecorder()
+
+	s.handler.ListDeliveries(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	var resp []WebhookDeliveryResponse
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	s.Len(resp, 1)
+	s.Equal("unexpected status 503", resp[0].LastError)
+}
+
+func (s *WebhookHandlersSuite) TestReplayDead() {
+	id := uuid.New()
+	s.webhookService.EXPECT().
+		ReplayDeadDeliveries(gomock.Any(), id).
+		Return(int64(3), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhooks/"+id.String()+"/replay", nil)
+	req = withURLParam(req, "id", id.String())
+	w := httptest.NewRecorder()
+
+	s.handler.ReplayDead(w, req)
+
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"replayed":3}`, w.Body.String())
+}
+
+func (s *WebhookHandlersSuite) TestReplayDelivery_NotFound() {
+	id := uuid.New()
+	s.webhookService.EXPECT().
+		ReplayDelivery(gomock.Any(), id).
+		Return(nil, service.ErrWebhookDeliveryNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhook-deliveries/"+id.String()+"/replay", nil)
+	req = withURLParam(req, "deliveryId", id.String())
+	w := httptest.NewRecorder()
+
+	s.handler.ReplayDelivery(w, req)
+
+	s.Equal(http.StatusNotFound, w.Code)
+}
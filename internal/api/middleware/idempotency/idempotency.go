@@ -0,0 +1,170 @@
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=idempotency_mock.go -source=idempotency.go -package=idempotency
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/pkg/api/response"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// StatusOutcomeUnknown is answered by handlers when a write may or may not
+// have been committed. The response is stored like a final one: executing
+// the request again could apply it twice, so retries with the same key get
+// this answer back instead.
+const StatusOutcomeUnknown = http.StatusGatewayTimeout
+
+type Store interface {
+	Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (*repository.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, key string) error
+}
+
+// New returns middleware that makes POST handlers safe to retry. A request
+// carrying an Idempotency-Key header is executed once; later requests with the
+// same key and body get the stored response, while a reused key with a
+// different body is rejected with 422.
+func New(store Store, log *slog.Logger, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(slog.String("component", "middleware/idempotency"))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				response.WriteError(w, http.StatusBadRequest, "idempotency key is too long")
+				return
+			}
+
+			ctx := r.Context()
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				response.WriteError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := requestHash(r, body)
+
+			reserved, err := store.Reserve(ctx, key, hash, ttl)
+			if err != nil {
+				log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+				response.WriteError(w, http.StatusInternalServerError, "failed to process idempotency key")
+				return
+			}
+
+			if !reserved {
+				replay(ctx, w, store, log, key, hash)
+				return
+			}
+
+			rec := &recorder{header: make(http.Header), status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// Retryable responses are not cached so that the client can
+			// retry them, possibly on another instance.
+			if retryable(rec.status) {
+				if err := store.Release(context.WithoutCancel(ctx), key); err != nil {
+					log.Error("failed to release idempotency key", slog.String("error", err.Error()))
+				}
+			} else if err := store.Complete(context.WithoutCancel(ctx), key, rec.status, rec.body.Bytes()); err != nil {
+				log.Error("failed to store idempotent response", slog.String("error", err.Error()))
+			}
+
+			for k, v := range rec.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.status)
+			_, _ = w.Write(rec.body.Bytes())
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func retryable(status int) bool {
+	if status == StatusOutcomeUnknown {
+		return false
+	}
+	return status >= http.StatusInternalServerError ||
+		status == http.StatusTooManyRequests ||
+		status == http.StatusMisdirectedRequest
+}
+
+func replay(ctx context.Context, w http.ResponseWriter, store Store, log *slog.Logger, key, hash string) {
+	stored, err := store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+			// The original request failed and released the key in between.
+			response.WriteError(w, http.StatusConflict, "request with this idempotency key is in progress")
+			return
+		}
+		log.Error("failed to get idempotency key", slog.String("error", err.Error()))
+		response.WriteError(w, http.StatusInternalServerError, "failed to process idempotency key")
+		return
+	}
+
+	if stored.RequestHash != hash {
+		response.WriteError(w, http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+		return
+	}
+
+	if stored.StatusCode == 0 {
+		response.WriteError(w, http.StatusConflict, "request with this idempotency key is in progress")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(stored.StatusCode)
+	_, _ = w.Write(stored.ResponseBody)
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
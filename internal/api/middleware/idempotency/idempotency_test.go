@@ -0,0 +1,216 @@
+package idempotency
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type IdempotencySuite struct {
+	suite.Suite
+
+	ctrl    *gomock.Controller
+	store   *MockStore
+	handler http.Handler
+	calls   int
+	status  int
+}
+
+func TestIdempotency(t *testing.T) {
+	suite.Run(t, &IdempotencySuite{})
+}
+
+func (s *IdempotencySuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.store = NewMockStore(s.ctrl)
+	s.calls = 0
+	s.status = http.StatusOK
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	})
+
+	s.handler = New(s.store, logger, time.Hour)(next)
+}
+
+func (s *IdempotencySuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *IdempotencySuite) newRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	return req
+}
+
+func (s *IdempotencySuite) TestNoKey_PassesThrough() {
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("", `{}`))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(1, s.calls)
+}
+
+func (s *IdempotencySuite) TestFirstRequest_StoresResponse() {
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(true, nil)
+	s.store.EXPECT().
+		Complete(gomock.Any(), "key-1", http.StatusOK, []byte(`{"status":"success"}`)).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{"amount":1}`))
+
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(`{"status":"success"}`, w.Body.String())
+	s.Equal(1, s.calls)
+}
+
+func (s *IdempotencySuite) TestServerError_ReleasesKey() {
+	s.status = http.StatusInternalServerError
+
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(true, nil)
+	s.store.EXPECT().
+		Release(gomock.Any(), "key-1").
+		Return(nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{"amount":1}`))
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+}
+
+func (s *IdempotencySuite) TestTooManyRequests_ReleasesKey() {
+	s.status = http.StatusTooManyRequests
+
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(true, nil)
+	s.store.EXPECT().
+		Release(gomock.Any(), "key-1").
+		Return(nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{"amount":1}`))
+
+	s.Equal(http.StatusTooManyRequests, w.Code)
+}
+
+func (s *IdempotencySuite) TestOutcomeUnknown_StoresResponse() {
+	s.status = StatusOutcomeUnknown
+
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(true, nil)
+	s.store.EXPECT().
+		Complete(gomock.Any(), "key-1", StatusOutcomeUnknown, []byte(`{"status":"success"}`)).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{"amount":1}`))
+
+	s.Equal(StatusOutcomeUnknown, w.Code)
+}
+
+func (s *IdempotencySuite) TestRetry_ReplaysStoredResponse() {
+	body := `{"amount":1}`
+	hash := requestHash(s.newRequest("key-1", body), []byte(body))
+
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", hash, time.Hour).
+		Return(false, nil)
+	s.store.EXPECT().
+		Get(gomock.Any(), "key-1").
+		Return(&repository.IdempotencyRecord{
+			Key:          "key-1",
+			RequestHash:  hash,
+			StatusCode:   http.StatusCreated,
+			ResponseBody: []byte(`{"walletId":"x"}`),
+		}, nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", body))
+
+	s.Equal(http.StatusCreated, w.Code)
+	s.Equal(`{"walletId":"x"}`, w.Body.String())
+	s.Equal("true", w.Header().Get(HeaderReplayed))
+	s.Equal(0, s.calls)
+}
+
+func (s *IdempotencySuite) TestReusedKeyWithDifferentBody() {
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(false, nil)
+	s.store.EXPECT().
+		Get(gomock.Any(), "key-1").
+		Return(&repository.IdempotencyRecord{
+			Key:         "key-1",
+			RequestHash: "other",
+			StatusCode:  http.StatusOK,
+		}, nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{"amount":2}`))
+
+	s.Equal(http.StatusUnprocessableEntity, w.Code)
+	s.Equal(0, s.calls)
+}
+
+func (s *IdempotencySuite) TestRequestInProgress() {
+	body := `{"amount":1}`
+	hash := requestHash(s.newRequest("key-1", body), []byte(body))
+
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", hash, time.Hour).
+		Return(false, nil)
+	s.store.EXPECT().
+		Get(gomock.Any(), "key-1").
+		Return(&repository.IdempotencyRecord{Key: "key-1", RequestHash: hash}, nil)
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", body))
+
+	s.Equal(http.StatusConflict, w.Code)
+	s.Equal(0, s.calls)
+}
+
+func (s *IdempotencySuite) TestReserveError() {
+	s.store.EXPECT().
+		Reserve(gomock.Any(), "key-1", gomock.Any(), time.Hour).
+		Return(false, errors.New("database error"))
+
+	w := httptest.NewRecorder()
+
+	s.handler.ServeHTTP(w, s.newRequest("key-1", `{}`))
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Equal(0, s.calls)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ITK/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+
+	router chi.Router
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, &MetricsSuite{})
+}
+
+func (s *MetricsSuite) SetupTest() {
+	metrics.HTTPRequests.Reset()
+	metrics.HTTPRequestDuration.Reset()
+
+	s.router = chi.NewRouter()
+	s.router.Use(New())
+	s.router.Get("/wallets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	})
+}
+
+func (s *MetricsSuite) serve(method, path string) {
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+}
+
+func (s *MetricsSuite) TestLabelsByRoutePattern() {
+	s.serve(http.MethodGet, "/wallets/a")
+	s.serve(http.MethodGet, "/wallets/b")
+	s.serve(http.MethodGet, "/wallets/missing")
+
+	s.Equal(2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/wallets/{id}", "200")))
+	s.Equal(1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/wallets/{id}", "404")))
+	s.Equal(2, testutil.CollectAndCount(metrics.HTTPRequestDuration))
+}
+
+func (s *MetricsSuite) TestUnmatchedRoute() {
+	s.serve(http.MethodGet, "/nope/123")
+
+	s.Equal(1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")))
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracingSuite struct {
+	suite.Suite
+
+	recorder *tracetest.SpanRecorder
+	router   chi.Router
+	traceID  trace.TraceID
+}
+
+func TestTracing(t *testing.T) {
+	suite.Run(t, &TracingSuite{})
+}
+
+func (s *TracingSuite) SetupSuite() {
+	s.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func (s *TracingSuite) SetupTest() {
+	s.recorder.Reset()
+
+	s.router = chi.NewRouter()
+	s.router.Use(middleware.RequestID)
+	s.router.Use(New())
+	s.router.Get("/wallets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.traceID = trace.SpanContextFromContext(r.Context()).TraceID()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+}
+
+func (s *TracingSuite) TestContinuesIncomingTrace() {
+	req := httptest.NewRequest(http.MethodGet, "/wallets/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	span := spans[0]
+	s.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	s.Equal("00f067aa0ba902b7", span.Parent().SpanID().String())
+	s.Equal(span.SpanContext().TraceID(), s.traceID)
+	s.Equal("GET /wallets/{id}", span.Name())
+	s.Contains(span.Attributes(), attribute.String("http.route", "/wallets/{id}"))
+	s.Contains(span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+}
+
+func (s *TracingSuite) TestStartsNewTrace() {
+	s.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wallets/123", nil))
+
+	spans := s.recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.True(spans[0].SpanContext().IsValid())
+	s.False(spans[0].Parent().IsValid())
+}
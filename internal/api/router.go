@@ -6,38 +6,108 @@ import (
 	"time"
 
 	"ITK/internal/api/handlers"
+	"ITK/internal/api/middleware/idempotency"
 	"ITK/internal/api/middleware/logger"
+	"ITK/internal/api/middleware/metrics"
+	"ITK/internal/api/middleware/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func NewRouter(log *slog.Logger, walletHandler *handlers.Handler) chi.Router {
+func NewRouter(
+	log *slog.Logger,
+	walletHandler *handlers.Handler,
+	webhookHandler *handlers.WebhookHandler,
+	idempotencyStore idempotency.Store,
+	idempotencyTTL time.Duration,
+) chi.Router {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
+	router.Use(tracing.New())
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
-	router.Use(middleware.Timeout(30 * time.Second))
 
 	router.Use(logger.New(log))
+	router.Use(metrics.New())
 
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+	// Statements stream large histories for as long as the client reads them
+	// and are the only routes without a request timeout.
+	timeout := middleware.Timeout(30 * time.Second)
+
+	router.With(timeout).Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	router.With(timeout).Handle("/metrics", promhttp.Handler())
+
 	fs := http.FileServer(http.Dir(".static/swagger"))
-	router.Handle("/static/swagger/*", http.StripPrefix("/static/swagger", fs))
+	router.With(timeout).Handle("/static/swagger/*", http.StripPrefix("/static/swagger", fs))
 
-	router.Get("/swagger/*", httpSwagger.Handler(
+	router.With(timeout).Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/static/swagger/swagger.json"),
 	))
 
+	idempotent := idempotency.New(idempotencyStore, log, idempotencyTTL)
+
 	router.Route("/api/v1", func(r chi.Router) {
-		r.Post("/wallet/create", walletHandler.Create)
-		r.Post("/wallet", walletHandler.Operation)
+		r.Get("/wallets/{id}/statement", walletHandler.Statement)
+
+		r = r.With(timeout)
+		r.With(idempotent).Post("/wallet/create", walletHandler.Create)
+		r.With(idempotent).Post("/wallet", walletHandler.Operation)
+		r.With(idempotent).Post("/transfers", walletHandler.Transfer)
 		r.Get("/wallets/{id}", walletHandler.GetBalance)
+		r.Get("/wallets/{id}/balance", walletHandler.GetBalanceAt)
+		r.Get("/wallets/{id}/operations", walletHandler.ListOperations)
+		r.Patch("/wallets/{id}/status", walletHandler.SetStatus)
+		r.Put("/wallets/{id}/limits", walletHandler.SetWalletLimits)
+
+		r.With(idempotent).Post("/wallets/{id}/holds", walletHandler.PlaceHold)
+		r.Get("/holds/{holdId}", walletHandler.GetHold)
+		r.With(idempotent).Post("/holds/{holdId}/capture", walletHandler.CaptureHold)
+		r.Post("/holds/{holdId}/release", walletHandler.ReleaseHold)
+	})
+
+	// v2 carries amounts as decimal strings instead of JSON numbers.
+	router.Route("/api/v2", func(r chi.Router) {
+		r.Get("/wallets/{id}/statement", walletHandler.Statement)
+
+		r = r.With(timeout)
+		r.With(idempotent).Post("/wallet/create", walletHandler.Create)
+		r.With(idempotent).Post("/wallet", walletHandler.OperationV2)
+		r.With(idempotent).Post("/transfers", walletHandler.TransferV2)
+		r.Get("/wallets/{id}", walletHandler.GetBalanceV2)
+		r.Get("/wallets/{id}/balance", walletHandler.GetBalanceAtV2)
+		r.Get("/wallets/{id}/operations", walletHandler.ListOperationsV2)
+		r.Patch("/wallets/{id}/status", walletHandler.SetStatus)
+		r.Put("/wallets/{id}/limits", walletHandler.SetWalletLimits)
+
+		r.With(idempotent).Post("/wallets/{id}/holds", walletHandler.PlaceHoldV2)
+		r.Get("/holds/{holdId}", walletHandler.GetHoldV2)
+		r.With(idempotent).Post("/holds/{holdId}/capture", walletHandler.CaptureHoldV2)
+		r.Post("/holds/{holdId}/release", walletHandler.ReleaseHoldV2)
+	})
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(timeout)
+		r.Get("/limit-policies", walletHandler.ListLimitPolicies)
+		r.Put("/limit-policies/{name}", walletHandler.PutLimitPolicy)
+		r.Get("/ledger/trial-balance", walletHandler.TrialBalance)
+		r.Get("/wallets/{id}/reconcile", walletHandler.Reconcile)
+
+		// Webhooks need the Postgres outbox; webhookHandler is nil without it.
+		if webhookHandler != nil {
+			r.Post("/webhooks", webhookHandler.Create)
+			r.Get("/webhooks", webhookHandler.List)
+			r.Delete("/webhooks/{id}", webhookHandler.Delete)
+			r.Get("/webhooks/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Post("/webhooks/{id}/replay", webhookHandler.ReplayDead)
+			r.Post("/webhook-deliveries/{deliveryId}/replay", webhookHandler.ReplayDelivery)
+		}
 	})
 
 	return router
@@ -1,9 +1,11 @@
 ﻿package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"ITK/pkg/postgres"
@@ -12,15 +14,94 @@ import (
 )
 
 type Config struct {
-	Env        string
-	HTTPServer HTTPServer
-	DB         postgres.DBConfig
-	Retry      RetryConfig
+	Env string
+	// Storage is StoragePostgres or StorageMemory.
+	Storage     string
+	HTTPServer  HTTPServer
+	DB          postgres.DBConfig
+	Retry       RetryConfig
+	Wallets     WalletsConfig
+	Idempotency IdempotencyConfig
+	Holds       HoldsConfig
+	Webhooks    WebhooksConfig
+	Tracing     TracingConfig
+	Lock        LockConfig
 }
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type RetryConfig struct {
-	MaxAttempts  int
-	BaseDelayMS  int
+	MaxAttempts int
+	BaseDelayMS int
+	// MaxDelay caps a single backoff; zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is "none", "full" or "decorrelated".
+	Jitter string
+	// BudgetTokens sizes the retry budget shared by all transactions; zero
+	// disables it. Every success returns BudgetRatio tokens.
+	BudgetTokens float64
+	BudgetRatio  float64
+	// SQLStates lists the retryable error codes. Connection failures are
+	// always retried.
+	SQLStates []string
+}
+
+type WalletsConfig struct {
+	DefaultCurrency string
+	LockTimeout     time.Duration
+	// GroupCommit applies concurrent deposits and withdrawals on one wallet
+	// in shared transactions of up to MaxBatch operations.
+	GroupCommit bool
+	MaxBatch    int
+}
+
+type IdempotencyConfig struct {
+	TTL             time.Duration
+	CleanupInterval time.Duration
+}
+
+type HoldsConfig struct {
+	TTL            time.Duration
+	MaxTTL         time.Duration
+	SweepInterval  time.Duration
+	SweepBatchSize int
+}
+
+type WebhooksConfig struct {
+	DispatchInterval time.Duration
+	BatchSize        int
+	Timeout          time.Duration
+	MaxAttempts      int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+}
+
+const (
+	LockModeMemory   = "memory"
+	LockModePostgres = "postgres"
+	LockModeRing     = "ring"
+)
+
+type LockConfig struct {
+	// Mode is LockModeMemory, LockModePostgres or LockModeRing.
+	Mode string
+	// RingNodes lists every instance; RingSelf is this one.
+	RingNodes []string
+	RingSelf  string
+	// PGSessions is the number of database sessions LockModePostgres spreads
+	// the held locks over.
+	PGSessions int
+}
+
+type TracingConfig struct {
+	// Exporter is "otlp", "stdout" or "none".
+	Exporter     string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	SampleRatio  float64
 }
 
 type HTTPServer struct {
@@ -49,8 +130,9 @@ func MustLoad() *Config {
 		idleTimeout = 60 * time.Second
 	}
 
-	return &Config{
-		Env: getEnv("ENV", "local"),
+	cfg := &Config{
+		Env:     getEnv("ENV", "local"),
+		Storage: getEnv("STORAGE", StoragePostgres),
 		HTTPServer: HTTPServer{
 			Address:     getEnv("HTTP_ADDRESS", "0.0.0.0:8080"),
 			Timeout:     timeout,
@@ -65,12 +147,70 @@ func MustLoad() *Config {
 			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
 			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 20),
 			ConnMaxLifetime: getEnvAsInt("DB_CONN_MAX_LIFETIME", 3600),
+			AutoMigrate:     getEnvAsBool("DB_AUTO_MIGRATE", false),
 		},
 		Retry: RetryConfig{
-			MaxAttempts: getEnvAsInt("RETRY_MAX_ATTEMPTS", 10),
-			BaseDelayMS: getEnvAsInt("RETRY_BASE_DELAY_MS", 10),
+			MaxAttempts:  getEnvAsInt("RETRY_MAX_ATTEMPTS", 10),
+			BaseDelayMS:  getEnvAsInt("RETRY_BASE_DELAY_MS", 10),
+			MaxDelay:     getEnvAsDuration("RETRY_MAX_DELAY", time.Second),
+			Jitter:       getEnv("RETRY_JITTER", "full"),
+			BudgetTokens: getEnvAsFloat("RETRY_BUDGET_TOKENS", 0),
+			BudgetRatio:  getEnvAsFloat("RETRY_BUDGET_RATIO", 0.1),
+			SQLStates:    getEnvAsSlice("RETRY_SQLSTATES"),
+		},
+		Wallets: WalletsConfig{
+			DefaultCurrency: getEnv("WALLET_DEFAULT_CURRENCY", "RUB"),
+			LockTimeout:     getEnvAsDuration("WALLET_LOCK_TIMEOUT", 2*time.Second),
+			GroupCommit:     getEnvAsBool("WALLET_GROUP_COMMIT", false),
+			MaxBatch:        getEnvAsInt("WALLET_GROUP_COMMIT_MAX_BATCH", 100),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:             getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			CleanupInterval: getEnvAsDuration("IDEMPOTENCY_CLEANUP_INTERVAL", 10*time.Minute),
 		},
+		Holds: HoldsConfig{
+			TTL:            getEnvAsDuration("HOLD_TTL", 24*time.Hour),
+			MaxTTL:         getEnvAsDuration("HOLD_MAX_TTL", 30*24*time.Hour),
+			SweepInterval:  getEnvAsDuration("HOLD_SWEEP_INTERVAL", time.Minute),
+			SweepBatchSize: getEnvAsInt("HOLD_SWEEP_BATCH_SIZE", 100),
+		},
+		Webhooks: WebhooksConfig{
+			DispatchInterval: getEnvAsDuration("WEBHOOK_DISPATCH_INTERVAL", time.Second),
+			BatchSize:        getEnvAsInt("WEBHOOK_BATCH_SIZE", 100),
+			Timeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:      getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 12),
+			BackoffBase:      getEnvAsDuration("WEBHOOK_BACKOFF_BASE", 5*time.Second),
+			BackoffMax:       getEnvAsDuration("WEBHOOK_BACKOFF_MAX", time.Hour),
+		},
+		Lock: LockConfig{
+			Mode:       getEnv("LOCK_MODE", LockModeMemory),
+			RingNodes:  getEnvAsSlice("LOCK_RING_NODES"),
+			RingSelf:   getEnv("LOCK_RING_SELF", ""),
+			PGSessions: getEnvAsInt("LOCK_PG_SESSIONS", 8),
+		},
+		Tracing: TracingConfig{
+			Exporter:     getEnv("TRACING_EXPORTER", "none"),
+			OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318"),
+			OTLPInsecure: getEnvAsBool("TRACING_OTLP_INSECURE", true),
+			SampleRatio:  getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+	}
+
+	if err := cfg.validate(); err != nil {
+		log.Fatalf("invalid config: %v", err)
 	}
+	return cfg
+}
+
+func (c *Config) validate() error {
+	// A write that waits for its wallet past the server's write timeout can
+	// still commit after the connection is closed, and the client never
+	// learns that it did.
+	if c.HTTPServer.Timeout > 0 && (c.Wallets.LockTimeout <= 0 || c.Wallets.LockTimeout >= c.HTTPServer.Timeout) {
+		return fmt.Errorf("WALLET_LOCK_TIMEOUT (%s) must be positive and below HTTP_TIMEOUT (%s)",
+			c.Wallets.LockTimeout, c.HTTPServer.Timeout)
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -87,3 +227,37 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
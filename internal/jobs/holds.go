@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type HoldExpirer interface {
+	ExpireHolds(ctx context.Context, limit int) (int, error)
+}
+
+// HoldSweeper periodically expires holds whose deadline has passed, returning
+// the reserved funds to the available balance.
+type HoldSweeper struct {
+	store     HoldExpirer
+	log       *slog.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+func NewHoldSweeper(store HoldExpirer, log *slog.Logger, interval time.Duration, batchSize int) *HoldSweeper {
+	return &HoldSweeper{
+		store:     store,
+		log:       log.With(slog.String("component", "jobs/holds")),
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (j *HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *HoldSweeper) runOnce(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		expired, err := j.store.ExpireHolds(ctx, j.batchSize)
+		total += expired
+		if err != nil {
+			j.log.Error("failed to expire holds", slog.String("error", err.Error()))
+			break
+		}
+		if expired < j.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		j.log.Info("expired holds released", slog.Int("count", total))
+	}
+}
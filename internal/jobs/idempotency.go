@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type IdempotencyStore interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// IdempotencyCleanup periodically removes expired idempotency keys.
+type IdempotencyCleanup struct {
+	store    IdempotencyStore
+	log      *slog.Logger
+	interval time.Duration
+}
+
+func NewIdempotencyCleanup(store IdempotencyStore, log *slog.Logger, interval time.Duration) *IdempotencyCleanup {
+	return &IdempotencyCleanup{
+		store:    store,
+		log:      log.With(slog.String("component", "jobs/idempotency")),
+		interval: interval,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (j *IdempotencyCleanup) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *IdempotencyCleanup) runOnce(ctx context.Context) {
+	deleted, err := j.store.DeleteExpired(ctx)
+	if err != nil {
+		j.log.Error("failed to delete expired idempotency keys", slog.String("error", err.Error()))
+		return
+	}
+	if deleted > 0 {
+		j.log.Info("expired idempotency keys deleted", slog.Int64("count", deleted))
+	}
+}
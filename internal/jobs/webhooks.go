@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/pkg/webhook"
+
+	"github.com/google/uuid"
+)
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=webhooks_mock.go -source=webhooks.go -package=jobs
+type WebhookStore interface {
+	FanOutEvents(ctx context.Context, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]repository.PendingDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID) error
+	RetryDelivery(ctx context.Context, id uuid.UUID, lastError string, delay time.Duration) error
+	MarkDeliveryDead(ctx context.Context, id uuid.UUID, lastError string) error
+}
+
+type WebhookDispatcherConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	Timeout     time.Duration
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// WebhookDispatcher moves outbox events to webhook subscribers. Each tick it
+// fans new events out into deliveries and then sends the due ones. Failed
+// deliveries are retried with exponential backoff and become dead after
+// MaxAttempts.
+type WebhookDispatcher struct {
+	store  WebhookStore
+	log    *slog.Logger
+	client *http.Client
+	cfg    WebhookDispatcherConfig
+}
+
+// webhookEnvelope is the JSON body posted to subscribers.
+type webhookEnvelope struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func NewWebhookDispatcher(store WebhookStore, log *slog.Logger, cfg WebhookDispatcherConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:  store,
+		log:    log.With(slog.String("component", "jobs/webhooks")),
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+}
+
+// Run blocks until ctx is cancelled.
+func (j *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *WebhookDispatcher) runOnce(ctx context.Context) {
+	for ctx.Err() == nil {
+		fanned, err := j.store.FanOutEvents(ctx, j.cfg.BatchSize)
+		if err != nil {
+			j.log.Error("failed to fan out outbox events", slog.String("error", err.Error()))
+			break
+		}
+		if fanned < j.cfg.BatchSize {
+			break
+		}
+	}
+
+	for ctx.Err() == nil {
+		// The lease outlives a send, so a delivery is not picked up again
+		// while its request is still in flight.
+		deliveries, err := j.store.ClaimDeliveries(ctx, j.cfg.BatchSize, 2*j.cfg.Timeout)
+		if err != nil {
+			j.log.Error("failed to claim webhook deliveries", slog.String("error", err.Error()))
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				j.deliver(ctx, d)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < j.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (j *WebhookDispatcher) deliver(ctx context.Context, d repository.PendingDelivery) {
+	sendErr := j.send(ctx, d)
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = j.store.MarkDelivered(ctx, d.ID)
+	case d.Attempts >= j.cfg.MaxAttempts:
+		j.log.Warn("webhook delivery is dead",
+			slog.String("delivery_id", d.ID.String()),
+			slog.String("url", d.URL),
+			slog.Int("attempts", d.Attempts),
+			slog.String("error", sendErr.Error()),
+		)
+		err = j.store.MarkDeliveryDead(ctx, d.ID, sendErr.Error())
+	default:
+		err = j.store.RetryDelivery(ctx, d.ID, sendErr.Error(), j.backoff(d.Attempts))
+	}
+	if err != nil {
+		j.log.Error("failed to record webhook delivery result",
+			slog.String("delivery_id", d.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (j *WebhookDispatcher) send(ctx context.Context, d repository.PendingDelivery) error {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        d.EventID,
+		Type:      d.EventType,
+		CreatedAt: d.EventCreatedAt.UTC(),
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEventID, d.EventID.String())
+	req.Header.Set(webhook.HeaderEventType, d.EventType)
+	req.Header.Set(webhook.HeaderDelivery, d.ID.String())
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.Secret, timestamp, body))
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay before the attempt following the given one:
+// BackoffBase doubled for every previous attempt, capped at BackoffMax.
+func (j *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := j.cfg.BackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= j.cfg.BackoffMax {
+			return j.cfg.BackoffMax
+		}
+	}
+	return delay
+}
@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/pkg/webhook"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type WebhookDispatcherSuite struct {
+	suite.Suite
+
+	ctrl       *gomock.Controller
+	store      *MockWebhookStore
+	dispatcher *WebhookDispatcher
+	ctx        context.Context
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	suite.Run(t, &WebhookDispatcherSuite{})
+}
+
+func (s *WebhookDispatcherSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.store = NewMockWebhookStore(s.ctrl)
+	s.ctx = context.Background()
+	s.dispatcher = NewWebhookDispatcher(s.store, slog.New(slog.NewJSONHandler(io.Discard, nil)), WebhookDispatcherConfig{
+		Interval:    time.Second,
+		BatchSize:   10,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		BackoffBase: time.Second,
+		BackoffMax:  5 * time.Second,
+	})
+}
+
+func (s *WebhookDispatcherSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *WebhookDispatcherSuite) pending(url string, attempts int) repository.PendingDelivery {
+	return repository.PendingDelivery{
+		WebhookDelivery: repository.WebhookDelivery{
+			ID:             uuid.New(),
+			EventID:        uuid.New(),
+			SubscriptionID: uuid.New(),
+			Status:         repository.DeliveryPending,
+			Attempts:       attempts,
+		},
+		URL:            url,
+		Secret:         testSecret,
+		EventType:      repository.EventOperationCreated,
+		Payload:        []byte(`{"operationType":"DEPOSIT","amount":"100.5"}`),
+		EventCreatedAt: time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (s *WebhookDispatcherSuite) expectBatch(deliveries ...repository.PendingDelivery) {
+	s.store.EXPECT().FanOutEvents(gomock.Any(), 10).Return(0, nil)
+	s.store.EXPECT().ClaimDeliveries(gomock.Any(), 10, 2*time.Second).Return(deliveries, nil)
+}
+
+func (s *WebhookDispatcherSuite) TestDeliversSignedPayload() {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+
+		s.True(webhook.Verify(testSecret, timestamp, body, r.Header.Get(webhook.HeaderSignature)))
+		s.Equal(repository.EventOperationCreated, r.Header.Get(webhook.HeaderEventType))
+
+		var envelope struct {
+			ID   string          `json:"id"`
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		s.NoError(json.Unmarshal(body, &envelope))
+		s.Equal(r.Header.Get(webhook.HeaderEventID), envelope.ID)
+		s.JSONEq(`{"operationType":"DEPOSIT","amount":"100.5"}`, string(envelope.Data))
+
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := s.pending(receiver.URL, 1)
+	s.expectBatch(d)
+	s.store.EXPECT().MarkDelivered(gomock.Any(), d.ID).Return(nil)
+
+	s.dispatcher.runOnce(s.ctx)
+
+	s.Equal(int32(1), received.Load())
+}
+
+func (s *WebhookDispatcherSuite) TestRetriesWithBackoff() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	first := s.pending(receiver.URL, 1)
+	second := s.pending(receiver.URL, 2)
+	s.expectBatch(first, second)
+	s.store.EXPECT().RetryDelivery(gomock.Any(), first.ID, "unexpected status 503", time.Second).Return(nil)
+	s.store.EXPECT().RetryDelivery(gomock.Any(), second.ID, "unexpected status 503", 2*time.Second).Return(nil)
+
+	s.dispatcher.runOnce(s.ctx)
+}
+
+func (s *WebhookDispatcherSuite) TestDeadAfterMaxAttempts() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	d := s.pending(receiver.URL, 3)
+	s.expectBatch(d)
+	s.store.EXPECT().MarkDeliveryDead(gomock.Any(), d.ID, "unexpected status 500").Return(nil)
+
+	s.dispatcher.runOnce(s.ctx)
+}
+
+func (s *WebhookDispatcherSuite) TestBackoffIsCapped() {
+	s.Equal(time.Second, s.dispatcher.backoff(1))
+	s.Equal(4*time.Second, s.dispatcher.backoff(3))
+	s.Equal(5*time.Second, s.dispatcher.backoff(4))
+	s.Equal(5*time.Second, s.dispatcher.backoff(30))
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "wallet"
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	Operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Wallet operations by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	SerializationRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "serialization_retries_total",
+		Help:      "Transactions retried after a retryable failure (serialization failure, deadlock, lost connection).",
+	})
+
+	SerializationBackoff = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "serialization_backoff_seconds_total",
+		Help:      "Time spent sleeping between transaction retries.",
+	})
+
+	SerializationRetriesExhausted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "serialization_retries_exhausted_total",
+		Help:      "Transactions that failed after using up their retry attempts or the retry budget.",
+	})
+
+	LockWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent waiting for the in-process per-wallet lock.",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	})
+
+	LockKeys = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lock_keys",
+		Help:      "Number of keys held by the in-process per-wallet lock.",
+	})
+
+	BatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_size",
+		Help:      "Operations applied per transaction in group commit mode.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	})
+)
+
+// Register adds the service metrics to reg.
+func Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		HTTPRequests,
+		HTTPRequestDuration,
+		Operations,
+		SerializationRetries,
+		SerializationBackoff,
+		SerializationRetriesExhausted,
+		LockWait,
+		LockKeys,
+		BatchSize,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
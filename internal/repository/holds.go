@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds hold amount")
+)
+
+const (
+	HoldActive   = "ACTIVE"
+	HoldCaptured = "CAPTURED"
+	HoldReleased = "RELEASED"
+	HoldExpired  = "EXPIRED"
+)
+
+// Hold reserves part of a wallet balance. While a hold is active its amount
+// is counted in wallets.held and cannot be withdrawn.
+type Hold struct {
+	ID             uuid.UUID
+	WalletID       uuid.UUID
+	Amount         decimal.Decimal
+	CapturedAmount decimal.Decimal
+	Status         string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+var holdColumns = []string{"id", "wallet_id", "amount", "captured_amount", "status", "expires_at", "created_at", "updated_at"}
+
+func scanHold(row pgx.Row) (*Hold, error) {
+	var h Hold
+	err := row.Scan(&h.ID, &h.WalletID, &h.Amount, &h.CapturedAmount, &h.Status, &h.ExpiresAt, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *walletRepo) CreateHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "repository.CreateHold", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	var hold *Hold
+	err = r.withRetry(ctx, walletID, func(ctx context.Context) error {
+		var err error
+		hold, err = r.executeCreateHold(ctx, walletID, amount, ttl)
+		return err
+	})
+	return hold, err
+}
+
+func (r *walletRepo) executeCreateHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockWallet(ctx, tx, walletID); err != nil {
+		return nil, err
+	}
+
+	if err = applyHeldDelta(ctx, tx, walletID, amount); err != nil {
+		return nil, err
+	}
+
+	insertSQL, insertArgs, err := squirrel.Insert("holds").
+		Columns("wallet_id", "amount", "status", "expires_at").
+		Values(walletID, amount, HoldActive, squirrel.Expr("NOW() + make_interval(secs => ?)", ttl.Seconds())).
+		Suffix("RETURNING " + strings.Join(holdColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert SQL: %w", err)
+	}
+
+	hold, err := scanHold(tx.QueryRow(ctx, insertSQL, insertArgs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert hold: %w", err)
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("hold created",
+		slog.String("hold_id", hold.ID.String()),
+		slog.String("wallet_id", walletID.String()),
+		slog.String("amount", amount.String()),
+	)
+
+	return hold, nil
+}
+
+func (r *walletRepo) GetHold(ctx context.Context, holdID uuid.UUID) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "repository.GetHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select(holdColumns...).
+		From("holds").
+		Where(squirrel.Eq{"id": holdID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	hold, err := scanHold(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold turns an active hold into a WITHDRAW of amount, which must not
+// exceed the held amount. Whatever is not captured is released. The capture
+// counts toward the withdrawal limits of the wallet and fails when it would
+// break one.
+func (r *walletRepo) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "repository.CaptureHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() { tracing.End(span, err) }()
+
+	return r.finishHold(ctx, holdID, HoldCaptured, amount)
+}
+
+func (r *walletRepo) ReleaseHold(ctx context.Context, holdID uuid.UUID) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReleaseHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() { tracing.End(span, err) }()
+
+	return r.finishHold(ctx, holdID, HoldReleased, decimal.Zero)
+}
+
+// ExpireHolds releases up to limit active holds whose deadline has passed and
+// returns how many were expired.
+func (r *walletRepo) ExpireHolds(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ExpireHolds")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select("id").
+		From("holds").
+		Where(squirrel.Eq{"status": HoldActive}).
+		Where("expires_at <= NOW()").
+		OrderBy("expires_at").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		_, err = r.finishHold(ctx, id, HoldExpired, decimal.Zero)
+		if err != nil {
+			// Captured or released concurrently.
+			if errors.Is(err, ErrHoldNotActive) {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (r *walletRepo) finishHold(ctx context.Context, holdID uuid.UUID, status string, captured decimal.Decimal) (*Hold, error) {
+	current, err := r.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	var hold *Hold
+	err = r.withRetry(ctx, current.WalletID, func(ctx context.Context) error {
+		var err error
+		hold, err = r.executeFinishHold(ctx, current.WalletID, holdID, status, captured)
+		return err
+	})
+	return hold, err
+}
+
+func (r *walletRepo) executeFinishHold(ctx context.Context, walletID, holdID uuid.UUID, status string, captured decimal.Decimal) (*Hold, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockWallet(ctx, tx, walletID); err != nil {
+		return nil, err
+	}
+
+	selectSQL, selectArgs, err := squirrel.Select(holdColumns...).
+		From("holds").
+		Where(squirrel.Eq{"id": holdID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	hold, err := scanHold(tx.QueryRow(ctx, selectSQL, selectArgs...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	if hold.Status != HoldActive {
+		return nil, ErrHoldNotActive
+	}
+	if status == HoldCaptured {
+		// An expired hold that the sweeper has not reached yet must not be captured.
+		expiredSQL, expiredArgs, err := squirrel.Select("expires_at <= NOW()").
+			From("holds").
+			Where(squirrel.Eq{"id": holdID}).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build query: %w", err)
+		}
+
+		var expired bool
+		if err = tx.QueryRow(ctx, expiredSQL, expiredArgs...).Scan(&expired); err != nil {
+			return nil, fmt.Errorf("failed to check hold expiry: %w", err)
+		}
+		if expired {
+			return nil, ErrHoldNotActive
+		}
+		if captured.GreaterThan(hold.Amount) {
+			return nil, ErrCaptureExceedsHold
+		}
+	}
+
+	if err = applyHeldDelta(ctx, tx, walletID, hold.Amount.Neg()); err != nil {
+		return nil, err
+	}
+
+	if captured.IsPositive() {
+		newBalance, err := applyDelta(ctx, tx, walletID, captured.Neg())
+		if err != nil {
+			return nil, err
+		}
+		if err = enforceLimits(ctx, tx, walletID, captured.Neg(), newBalance); err != nil {
+			return nil, err
+		}
+
+		op := &Operation{
+			WalletID:     walletID,
+			Type:         OperationWithdraw,
+			Amount:       captured,
+			BalanceAfter: newBalance,
+			HoldID:       &holdID,
+		}
+		if err = insertOperations(ctx, tx, op); err != nil {
+			return nil, err
+		}
+		if err = postEntries(ctx, tx, operationEntry(op, captured.Neg(), AccountCashOut)); err != nil {
+			return nil, err
+		}
+	}
+
+	updateSQL, updateArgs, err := squirrel.Update("holds").
+		Set("status", status).
+		Set("captured_amount", captured).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": holdID}).
+		Suffix("RETURNING " + strings.Join(holdColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	hold, err = scanHold(tx.QueryRow(ctx, updateSQL, updateArgs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("hold finished",
+		slog.String("hold_id", holdID.String()),
+		slog.String("wallet_id", walletID.String()),
+		slog.String("status", status),
+		slog.String("captured_amount", captured.String()),
+	)
+
+	return hold, nil
+}
+
+// applyHeldDelta changes the amount reserved on a wallet. Reserving more than
+// the available balance fails with ErrInsufficientFunds; only an active
+// wallet can reserve funds, while releasing them is always allowed.
+func applyHeldDelta(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, delta decimal.Decimal) error {
+	update := squirrel.Update("wallets").
+		Set("held", squirrel.Expr("held + ?", delta)).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where("id = ? AND (held + ?) <= balance", walletID, delta)
+	if delta.IsPositive() {
+		update = update.Where(squirrel.Eq{"status": WalletActive})
+	}
+	updateSQL, updateArgs, err := update.
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, updateSQL, updateArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update held amount: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return rejectedDelta(ctx, tx, walletID, false)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+// IdempotencyRecord is a stored idempotency key. StatusCode is zero while the
+// original request is still being processed.
+type IdempotencyRecord struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=idempotency_mock.go -source=idempotency.go -package=repository
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyRepo struct {
+	pool *pgxpool.Pool
+	log  *slog.Logger
+}
+
+func NewIdempotency(pool *pgxpool.Pool, log *slog.Logger) IdempotencyRepository {
+	return &idempotencyRepo{
+		pool: pool,
+		log:  log.With(slog.String("component", "repository/idempotency")),
+	}
+}
+
+// Reserve claims the key for a new request. It returns false if the key is
+// already taken by a request that has not expired yet.
+func (r *idempotencyRepo) Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (_ bool, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReserveIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Insert("idempotency_keys").
+		Columns("key", "request_hash", "created_at", "expires_at").
+		Values(key, requestHash, squirrel.Expr("NOW()"), squirrel.Expr("NOW() + make_interval(secs => ?)", ttl.Seconds())).
+		Suffix(`ON CONFLICT (key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()`).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build SQL query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		r.log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *idempotencyRepo) Get(ctx context.Context, key string) (_ *IdempotencyRecord, err error) {
+	ctx, span := tracer.Start(ctx, "repository.GetIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select("key", "request_hash", "COALESCE(status_code, 0)", "response_body", "created_at", "expires_at").
+		From("idempotency_keys").
+		Where(squirrel.Eq{"key": key}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var rec IdempotencyRecord
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&rec.Key, &rec.RequestHash, &rec.StatusCode, &rec.ResponseBody, &rec.CreatedAt, &rec.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &rec, nil
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, key string, statusCode int, body []byte) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.CompleteIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Update("idempotency_keys").
+		Set("status_code", statusCode).
+		Set("response_body", body).
+		Where(squirrel.Eq{"key": key}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	if _, err = r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release drops a reservation whose request failed, so the client may retry
+// with the same key.
+func (r *idempotencyRepo) Release(ctx context.Context, key string) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReleaseIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Delete("idempotency_keys").
+		Where(squirrel.Eq{"key": key}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete SQL: %w", err)
+	}
+
+	if _, err = r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "repository.DeleteExpiredIdempotencyKeys")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Delete("idempotency_keys").
+		Where("expires_at < NOW()").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete SQL: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// System ledger accounts. Every currency has one of each; money deposited
+// into wallets is taken from CASH_IN and money withdrawn goes to CASH_OUT.
+// OPENING_BALANCE holds the other side of balances that existed before the
+// ledger was introduced.
+const (
+	AccountCashIn         = "CASH_IN"
+	AccountCashOut        = "CASH_OUT"
+	AccountOpeningBalance = "OPENING_BALANCE"
+)
+
+// Journal entry types other than operation types.
+const (
+	EntryTransfer       = "TRANSFER"
+	EntryOpeningBalance = "OPENING_BALANCE"
+)
+
+var systemAccounts = []string{AccountCashIn, AccountCashOut, AccountOpeningBalance}
+
+// TrialBalanceWallets is the Account of the trial balance line that sums up
+// the wallet accounts of a currency.
+const TrialBalanceWallets = "WALLETS"
+
+// maxBalanceMismatches bounds the mismatches reported by TrialBalance.
+const maxBalanceMismatches = 100
+
+// TrialBalance lists ledger balances by currency and account. Postings of an
+// entry sum to zero, so the lines of every currency do as well.
+type TrialBalance struct {
+	Lines []TrialBalanceLine
+	// Mismatches lists wallets whose cached balance differs from the sum of
+	// their postings, at most maxBalanceMismatches of them.
+	Mismatches []BalanceMismatch
+}
+
+// TrialBalanceLine is the balance of a system account, or of all wallet
+// accounts of a currency when Account is TrialBalanceWallets.
+type TrialBalanceLine struct {
+	Currency string
+	Account  string
+	Accounts int
+	Balance  decimal.Decimal
+}
+
+type BalanceMismatch struct {
+	WalletID uuid.UUID
+	Cached   decimal.Decimal
+	Ledger   decimal.Decimal
+}
+
+// Totals returns the sum of the lines of every currency.
+func (tb *TrialBalance) Totals() map[string]decimal.Decimal {
+	totals := make(map[string]decimal.Decimal)
+	for _, line := range tb.Lines {
+		totals[line.Currency] = totals[line.Currency].Add(line.Balance)
+	}
+	return totals
+}
+
+// Balanced reports whether every currency sums to zero and every cached
+// wallet balance matches the ledger.
+func (tb *TrialBalance) Balanced() bool {
+	for _, total := range tb.Totals() {
+		if !total.IsZero() {
+			return false
+		}
+	}
+	return len(tb.Mismatches) == 0
+}
+
+// journalEntry is a set of postings recorded together.
+type journalEntry struct {
+	entryType string
+	postings  []posting
+}
+
+// posting adds amount to a wallet account, or to the system account named
+// by system in the currency of the wallet.
+type posting struct {
+	walletID uuid.UUID
+	system   string
+	amount   decimal.Decimal
+	// op is the operation recorded for a wallet posting. Its ID is read
+	// when the posting is written, after insertOperations assigned it.
+	op *Operation
+}
+
+func walletPosting(op *Operation, delta decimal.Decimal) posting {
+	return posting{walletID: op.WalletID, amount: delta, op: op}
+}
+
+// operationEntry books delta on the wallet of op against a system account.
+func operationEntry(op *Operation, delta decimal.Decimal, system string) journalEntry {
+	return journalEntry{entryType: op.Type, postings: []posting{
+		walletPosting(op, delta),
+		{walletID: op.WalletID, system: system, amount: delta.Neg()},
+	}}
+}
+
+// transferEntry books amount moving from the wallet of out to the wallet of
+// in.
+func transferEntry(out, in *Operation, amount decimal.Decimal) journalEntry {
+	return journalEntry{entryType: EntryTransfer, postings: []posting{
+		walletPosting(out, amount.Neg()),
+		walletPosting(in, amount),
+	}}
+}
+
+// cashAccount returns the system account deposits and withdrawals are
+// booked against.
+func cashAccount(opType string) string {
+	if opType == OperationDeposit {
+		return AccountCashIn
+	}
+	return AccountCashOut
+}
+
+// ensureSystemAccounts creates the system accounts of a currency unless they
+// exist.
+func ensureSystemAccounts(ctx context.Context, tx pgx.Tx, currency string) error {
+	insert := squirrel.Insert("ledger_accounts").
+		Columns("id", "account_type", "code", "currency")
+	for _, code := range systemAccounts {
+		insert = insert.Values(uuid.New(), "SYSTEM", code, currency)
+	}
+
+	sql, args, err := insert.
+		Suffix("ON CONFLICT (code, currency) DO NOTHING").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert SQL: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to create system accounts: %w", err)
+	}
+	return nil
+}
+
+// postEntries records entries in the ledger. The database rejects the
+// transaction at commit if an entry does not balance.
+func postEntries(ctx context.Context, tx pgx.Tx, entries ...journalEntry) error {
+	journal := squirrel.Insert("journal_entries").Columns("id", "entry_type")
+	postings := squirrel.Insert("postings").Columns("entry_id", "account_id", "operation_id", "amount")
+	for _, entry := range entries {
+		entryID := uuid.Must(uuid.NewV7())
+		journal = journal.Values(entryID, entry.entryType)
+		for _, p := range entry.postings {
+			var (
+				account     any = p.walletID
+				operationID *uuid.UUID
+			)
+			if p.system != "" {
+				account = squirrel.Expr(
+					"(SELECT id FROM ledger_accounts WHERE code = ? AND currency = (SELECT currency FROM wallets WHERE id = ?))",
+					p.system, p.walletID,
+				)
+			}
+			if p.op != nil {
+				operationID = &p.op.ID
+			}
+			postings = postings.Values(entryID, account, operationID, p.amount)
+		}
+	}
+
+	for _, insert := range []squirrel.InsertBuilder{journal, postings} {
+		sql, args, err := insert.PlaceholderFormat(squirrel.Dollar).ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build ledger SQL: %w", err)
+		}
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("failed to post journal entries: %w", err)
+		}
+	}
+	return nil
+}
+
+// TrialBalance sums the ledger by currency and account and compares every
+// cached wallet balance with its postings.
+func (r *walletRepo) TrialBalance(ctx context.Context) (_ *TrialBalance, err error) {
+	ctx, span := tracer.Start(ctx, "repository.TrialBalance")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	linesSQL, linesArgs, err := squirrel.Select("a.currency").
+		Column(squirrel.Expr("COALESCE(a.code, ?)", TrialBalanceWallets)).
+		Columns("COUNT(DISTINCT a.id)", "COALESCE(SUM(p.amount), 0)").
+		From("ledger_accounts a").
+		LeftJoin("postings p ON p.account_id = a.id").
+		GroupBy("a.currency", "a.code").
+		OrderBy("a.currency", "2").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	tb := &TrialBalance{}
+	rows, err := tx.Query(ctx, linesSQL, linesArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum ledger: %w", err)
+	}
+	for rows.Next() {
+		var line TrialBalanceLine
+		if err = rows.Scan(&line.Currency, &line.Account, &line.Accounts, &line.Balance); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan trial balance line: %w", err)
+		}
+		tb.Lines = append(tb.Lines, line)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to sum ledger: %w", err)
+	}
+
+	mismatchSQL, mismatchArgs, err := squirrel.Select("w.id", "w.balance", "COALESCE(SUM(p.amount), 0)").
+		From("wallets w").
+		LeftJoin("postings p ON p.account_id = w.id").
+		GroupBy("w.id", "w.balance").
+		Having("w.balance <> COALESCE(SUM(p.amount), 0)").
+		OrderBy("w.id").
+		Limit(maxBalanceMismatches).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, mismatchSQL, mismatchArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare balances: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m BalanceMismatch
+		if err = rows.Scan(&m.WalletID, &m.Cached, &m.Ledger); err != nil {
+			return nil, fmt.Errorf("failed to scan balance mismatch: %w", err)
+		}
+		tb.Mismatches = append(tb.Mismatches, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compare balances: %w", err)
+	}
+
+	return tb, nil
+}
+
+// sortTrialBalance orders lines the way TrialBalance does in SQL.
+func sortTrialBalance(lines []TrialBalanceLine) {
+	slices.SortFunc(lines, func(a, b TrialBalanceLine) int {
+		return cmp.Or(strings.Compare(a.Currency, b.Currency), strings.Compare(a.Account, b.Account))
+	})
+}
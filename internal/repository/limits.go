@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrLimitExceeded       = errors.New("limit exceeded")
+	ErrLimitPolicyNotFound = errors.New("limit policy not found")
+	ErrLimitTierTaken      = errors.New("tier already has a limit policy in this currency")
+	// ErrLimitCurrencyMismatch is returned when a wallet would be governed by
+	// a policy of another currency.
+	ErrLimitCurrencyMismatch = errors.New("limit policy currency does not match wallet currency")
+)
+
+// Limits a LimitPolicy can set, as reported by LimitExceededError.
+const (
+	LimitMaxWithdrawal     = "max_withdrawal"
+	LimitDailyWithdrawal   = "daily_withdrawal"
+	LimitMonthlyWithdrawal = "monthly_withdrawal"
+	LimitMaxBalance        = "max_balance"
+)
+
+// outgoingOperations are the operation types that count toward the daily
+// and monthly withdrawal totals.
+var outgoingOperations = []string{OperationWithdraw, OperationTransferOut}
+
+// LimitPolicy caps how much money can leave or stay in a wallet. Limits are
+// amounts in Currency and unset ones do not apply. A policy applies to the
+// wallets it is assigned to, which must hold its currency, and, when Tier is
+// set, to every wallet of that tier and currency without a policy of its
+// own. Withdrawal totals cover withdrawals, captured holds and transfers out
+// since the start of the current calendar day or month in the database time
+// zone, less what has been reversed of them.
+type LimitPolicy struct {
+	Name              string
+	Tier              string
+	Currency          string
+	MaxWithdrawal     decimal.NullDecimal
+	DailyWithdrawal   decimal.NullDecimal
+	MonthlyWithdrawal decimal.NullDecimal
+	MaxBalance        decimal.NullDecimal
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// WalletLimits selects the limit policy of a wallet: Policy when set,
+// otherwise the policy of Tier. Empty fields are cleared.
+type WalletLimits struct {
+	Tier   string
+	Policy string
+}
+
+// LimitExceededError names the limit an operation would break. It matches
+// ErrLimitExceeded.
+type LimitExceededError struct {
+	Policy string
+	Limit  string
+	Value  decimal.Decimal
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s limit of %s set by policy %s exceeded", e.Limit, e.Value, e.Policy)
+}
+
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// limitUsage is what a wallet has withdrawn in the current day and month.
+type limitUsage struct {
+	day, month decimal.Decimal
+}
+
+// checkLimits reports the first limit of policy broken by moving delta with
+// usage already withdrawn and balance left afterwards. A nil policy allows
+// everything.
+func checkLimits(policy *LimitPolicy, usage limitUsage, delta, balance decimal.Decimal) error {
+	if policy == nil {
+		return nil
+	}
+
+	exceeded := func(limit string, value decimal.Decimal) error {
+		return &LimitExceededError{Policy: policy.Name, Limit: limit, Value: value}
+	}
+	if delta.IsPositive() {
+		if policy.MaxBalance.Valid && balance.GreaterThan(policy.MaxBalance.Decimal) {
+			return exceeded(LimitMaxBalance, policy.MaxBalance.Decimal)
+		}
+		return nil
+	}
+
+	amount := delta.Neg()
+	switch {
+	case policy.MaxWithdrawal.Valid && amount.GreaterThan(policy.MaxWithdrawal.Decimal):
+		return exceeded(LimitMaxWithdrawal, policy.MaxWithdrawal.Decimal)
+	case policy.DailyWithdrawal.Valid && usage.day.Add(amount).GreaterThan(policy.DailyWithdrawal.Decimal):
+		return exceeded(LimitDailyWithdrawal, policy.DailyWithdrawal.Decimal)
+	case policy.MonthlyWithdrawal.Valid && usage.month.Add(amount).GreaterThan(policy.MonthlyWithdrawal.Decimal):
+		return exceeded(LimitMonthlyWithdrawal, policy.MonthlyWithdrawal.Decimal)
+	}
+	return nil
+}
+
+// walletLimits is the policy in force for a wallet within a transaction,
+// together with what the wallet has withdrawn so far.
+type walletLimits struct {
+	policy *LimitPolicy
+	usage  limitUsage
+}
+
+// check enforces the limits on moving delta, leaving balance, and counts an
+// accepted withdrawal toward the totals of the following checks.
+func (l *walletLimits) check(delta, balance decimal.Decimal) error {
+	if err := checkLimits(l.policy, l.usage, delta, balance); err != nil {
+		return err
+	}
+	if delta.IsNegative() {
+		l.usage.day = l.usage.day.Add(delta.Neg())
+		l.usage.month = l.usage.month.Add(delta.Neg())
+	}
+	return nil
+}
+
+// loadLimits reads the limit policy of a wallet and, when it caps withdrawal
+// totals, what the wallet has withdrawn in the current day and month. The
+// wallet must be locked by tx.
+func loadLimits(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*walletLimits, error) {
+	policySQL, policyArgs, err := squirrel.Select(
+		"p.name", "COALESCE(p.tier, '')", "p.currency", "p.max_withdrawal", "p.daily_withdrawal", "p.monthly_withdrawal", "p.max_balance", "p.created_at", "p.updated_at",
+	).
+		From("wallets w").
+		Join("limit_policies p ON p.currency = w.currency AND (p.name = w.limit_policy OR (w.limit_policy IS NULL AND p.tier = w.tier))").
+		Where(squirrel.Eq{"w.id": walletID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	policy, err := scanLimitPolicy(tx.QueryRow(ctx, policySQL, policyArgs...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &walletLimits{}, nil
+		}
+		return nil, fmt.Errorf("failed to get limit policy: %w", err)
+	}
+
+	limits := &walletLimits{policy: policy}
+	if !policy.DailyWithdrawal.Valid && !policy.MonthlyWithdrawal.Valid {
+		return limits, nil
+	}
+
+	// created_at is written in the session time zone, which is what
+	// LOCALTIMESTAMP reports too. A reversal takes its amount off the total
+	// of the period the reversed withdrawal was made in.
+	usageSQL, usageArgs, err := squirrel.Select(
+		"COALESCE(SUM(o.amount - r.reversed) FILTER (WHERE o.created_at >= date_trunc('day', LOCALTIMESTAMP)), 0)",
+		"COALESCE(SUM(o.amount - r.reversed), 0)",
+	).
+		From("operations o").
+		JoinClause("CROSS JOIN LATERAL (SELECT COALESCE(SUM(rev.amount), 0) AS reversed FROM operations rev WHERE rev.reversal_of = o.id) r").
+		Where(squirrel.Eq{"o.wallet_id": walletID, "o.operation_type": outgoingOperations}).
+		Where("o.created_at >= date_trunc('month', LOCALTIMESTAMP)").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	if err = tx.QueryRow(ctx, usageSQL, usageArgs...).Scan(&limits.usage.day, &limits.usage.month); err != nil {
+		return nil, fmt.Errorf("failed to sum withdrawals: %w", err)
+	}
+
+	return limits, nil
+}
+
+// enforceLimits checks a balance change already applied by applyDelta
+// against the limits of the wallet. The caller rolls back on error.
+func enforceLimits(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, delta, balance decimal.Decimal) error {
+	limits, err := loadLimits(ctx, tx, walletID)
+	if err != nil {
+		return err
+	}
+	return limits.check(delta, balance)
+}
+
+var limitPolicyColumns = []string{
+	"name", "COALESCE(tier, '')", "currency", "max_withdrawal", "daily_withdrawal", "monthly_withdrawal", "max_balance", "created_at", "updated_at",
+}
+
+func scanLimitPolicy(row pgx.Row) (*LimitPolicy, error) {
+	var p LimitPolicy
+	err := row.Scan(&p.Name, &p.Tier, &p.Currency, &p.MaxWithdrawal, &p.DailyWithdrawal, &p.MonthlyWithdrawal, &p.MaxBalance, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// PutLimitPolicy creates the policy or replaces the one with the same name.
+// A tier can only have one policy per currency; claiming a taken tier fails
+// with ErrLimitTierTaken. Changing the currency of a policy assigned to
+// wallets fails with ErrLimitCurrencyMismatch.
+func (r *walletRepo) PutLimitPolicy(ctx context.Context, policy LimitPolicy) (_ *LimitPolicy, err error) {
+	ctx, span := tracer.Start(ctx, "repository.PutLimitPolicy")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Insert("limit_policies").
+		Columns("name", "tier", "currency", "max_withdrawal", "daily_withdrawal", "monthly_withdrawal", "max_balance").
+		Values(policy.Name, nullString(policy.Tier), policy.Currency, policy.MaxWithdrawal, policy.DailyWithdrawal, policy.MonthlyWithdrawal, policy.MaxBalance).
+		Suffix("ON CONFLICT (name) DO UPDATE SET " +
+			"tier = EXCLUDED.tier, " +
+			"currency = EXCLUDED.currency, " +
+			"max_withdrawal = EXCLUDED.max_withdrawal, " +
+			"daily_withdrawal = EXCLUDED.daily_withdrawal, " +
+			"monthly_withdrawal = EXCLUDED.monthly_withdrawal, " +
+			"max_balance = EXCLUDED.max_balance, " +
+			"updated_at = NOW() " +
+			"RETURNING " + strings.Join(limitPolicyColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SQL query: %w", err)
+	}
+
+	saved, err := scanLimitPolicy(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return nil, ErrLimitTierTaken
+			case "23503":
+				// The policy is assigned to wallets of its old currency.
+				return nil, ErrLimitCurrencyMismatch
+			}
+		}
+		return nil, fmt.Errorf("failed to save limit policy: %w", err)
+	}
+
+	r.log.Debug("limit policy saved", slog.String("policy", saved.Name), slog.String("tier", saved.Tier))
+	return saved, nil
+}
+
+func (r *walletRepo) ListLimitPolicies(ctx context.Context) (_ []LimitPolicy, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ListLimitPolicies")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select(limitPolicyColumns...).
+		From("limit_policies").
+		OrderBy("name").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list limit policies: %w", err)
+	}
+	policies, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (LimitPolicy, error) {
+		p, err := scanLimitPolicy(row)
+		if err != nil {
+			return LimitPolicy{}, err
+		}
+		return *p, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list limit policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// SetWalletLimits assigns a tier and a limit policy to a wallet. Operations
+// already running keep the limits they started with.
+func (r *walletRepo) SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.SetWalletLimits", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Update("wallets").
+		Set("tier", nullString(limits.Tier)).
+		Set("limit_policy", nullString(limits.Policy)).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": walletID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			// The policy is missing or in another currency.
+			return r.limitPolicyMismatch(ctx, limits.Policy)
+		}
+		return fmt.Errorf("failed to set wallet limits: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWalletNotFound
+	}
+
+	r.log.Debug("wallet limits set",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("tier", limits.Tier),
+		slog.String("policy", limits.Policy),
+	)
+	return nil
+}
+
+// limitPolicyMismatch explains why a wallet could not reference policy:
+// ErrLimitCurrencyMismatch if it exists, ErrLimitPolicyNotFound otherwise.
+func (r *walletRepo) limitPolicyMismatch(ctx context.Context, policy string) error {
+	sql, args, err := squirrel.Select("EXISTS(SELECT 1 FROM limit_policies WHERE name = ?)").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build check SQL: %w", err)
+	}
+
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, append(args, policy)...).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check limit policy: %w", err)
+	}
+	if exists {
+		return ErrLimitCurrencyMismatch
+	}
+	return ErrLimitPolicyNotFound
+}
@@ -0,0 +1,880 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// memoryRepo keeps wallets, holds and operations in process memory with the
+// semantics of walletRepo. A single mutex stands in for the database
+// transactions. No outbox events are written, so nothing is delivered to
+// webhooks.
+type memoryRepo struct {
+	log *slog.Logger
+
+	mu            sync.RWMutex
+	wallets       map[uuid.UUID]*Wallet
+	holds         map[uuid.UUID]*Hold
+	operations    map[uuid.UUID][]Operation
+	statusChanges map[uuid.UUID][]StatusChange
+	limitPolicies map[string]*LimitPolicy
+	ledger        map[ledgerAccount]decimal.Decimal
+}
+
+// ledgerAccount identifies the account of a wallet, or a system account by
+// code and currency.
+type ledgerAccount struct {
+	walletID uuid.UUID
+	code     string
+	currency string
+}
+
+// NewMemory returns a Repository that lives and dies with the process. It is
+// meant for local development and tests.
+func NewMemory(log *slog.Logger) Repository {
+	return &memoryRepo{
+		log:           log.With(slog.String("component", "repository/memory")),
+		wallets:       make(map[uuid.UUID]*Wallet),
+		holds:         make(map[uuid.UUID]*Hold),
+		operations:    make(map[uuid.UUID][]Operation),
+		statusChanges: make(map[uuid.UUID][]StatusChange),
+		limitPolicies: make(map[string]*LimitPolicy),
+		ledger:        make(map[ledgerAccount]decimal.Decimal),
+	}
+}
+
+// memoryNow matches the microsecond precision of Postgres timestamps, so cursors
+// built from returned operations compare the same way.
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func (r *memoryRepo) Create(_ context.Context, walletID uuid.UUID, currency string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.wallets[walletID]; exists {
+		return fmt.Errorf("wallet already exists: %s", walletID)
+	}
+
+	ts := memoryNow()
+	r.wallets[walletID] = &Wallet{
+		ID:        walletID,
+		Currency:  currency,
+		Status:    WalletActive,
+		CreatedAt: ts,
+		UpdatedAt: ts,
+	}
+	r.ledger[ledgerAccount{walletID: walletID, currency: currency}] = decimal.Zero
+	for _, code := range systemAccounts {
+		account := ledgerAccount{code: code, currency: currency}
+		if _, exists := r.ledger[account]; !exists {
+			r.ledger[account] = decimal.Zero
+		}
+	}
+
+	r.log.Debug("wallet created", slog.String("wallet_id", walletID.String()), slog.String("currency", currency))
+	return nil
+}
+
+func (r *memoryRepo) GetByID(_ context.Context, walletID uuid.UUID) (*Wallet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return nil, ErrWalletNotFound
+	}
+	wallet := *w
+	return &wallet, nil
+}
+
+func (r *memoryRepo) ApplyOperation(_ context.Context, walletID uuid.UUID, opType string, amount decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delta := amount
+	if opType == OperationWithdraw {
+		delta = amount.Neg()
+	}
+
+	ts := memoryNow()
+	balance, err := r.applyDelta(walletID, delta, ts)
+	if err != nil {
+		return err
+	}
+	ops := r.insertOperations(ts, Operation{WalletID: walletID, Type: opType, Amount: amount, BalanceAfter: balance})
+	return r.postEntries(operationEntry(&ops[0], delta, cashAccount(opType)))
+}
+
+func (r *memoryRepo) ApplyOperations(_ context.Context, walletID uuid.UUID, ops []BatchOperation) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.wallets[walletID]; !exists {
+		return nil, ErrWalletNotFound
+	}
+
+	ts := memoryNow()
+	results := make([]error, len(ops))
+	for i, op := range ops {
+		delta := op.Amount
+		if op.Type == OperationWithdraw {
+			delta = op.Amount.Neg()
+		}
+		balance, err := r.applyDelta(walletID, delta, ts)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		applied := r.insertOperations(ts, Operation{WalletID: walletID, Type: op.Type, Amount: op.Amount, BalanceAfter: balance})
+		if err = r.postEntries(operationEntry(&applied[0], delta, cashAccount(op.Type))); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (r *memoryRepo) Transfer(_ context.Context, fromID, toID uuid.UUID, amount decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check both legs before touching either wallet, in the order the
+	// transaction would fail and roll back.
+	from, exists := r.wallets[fromID]
+	if !exists {
+		return ErrWalletNotFound
+	}
+	if err := statusError(from.Status, from.DepositsBlocked, false); err != nil {
+		return err
+	}
+	if from.Balance.Sub(amount).LessThan(from.Held) {
+		return ErrInsufficientFunds
+	}
+	ts := memoryNow()
+	if err := r.enforceLimits(from, amount.Neg(), from.Balance.Sub(amount), ts); err != nil {
+		return err
+	}
+	to, exists := r.wallets[toID]
+	if !exists {
+		return ErrWalletNotFound
+	}
+	if err := statusError(to.Status, to.DepositsBlocked, true); err != nil {
+		return err
+	}
+	if err := r.enforceLimits(to, amount, to.Balance.Add(amount), ts); err != nil {
+		return err
+	}
+
+	fromBalance, err := r.applyDelta(fromID, amount.Neg(), ts)
+	if err != nil {
+		return err
+	}
+	toBalance, err := r.applyDelta(toID, amount, ts)
+	if err != nil {
+		return err
+	}
+
+	transferID := uuid.New()
+	ops := r.insertOperations(ts,
+		Operation{WalletID: fromID, Type: OperationTransferOut, Amount: amount, BalanceAfter: fromBalance, TransferID: &transferID},
+		Operation{WalletID: toID, Type: OperationTransferIn, Amount: amount, BalanceAfter: toBalance, TransferID: &transferID},
+	)
+	return r.postEntries(transferEntry(&ops[0], &ops[1], amount))
+}
+
+func (r *memoryRepo) ListOperations(_ context.Context, walletID uuid.UUID, filter OperationFilter) ([]Operation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ops []Operation
+	for _, op := range r.operations[walletID] {
+		if len(filter.Types) > 0 && !slices.Contains(filter.Types, op.Type) {
+			continue
+		}
+		if !filter.From.IsZero() && op.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !op.CreatedAt.Before(filter.To) {
+			continue
+		}
+		if filter.After != nil && compareOperation(op, filter.After.CreatedAt, filter.After.ID) >= 0 {
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	slices.SortFunc(ops, func(a, b Operation) int {
+		return compareOperation(b, a.CreatedAt, a.ID)
+	})
+	if filter.Limit > 0 && len(ops) > filter.Limit {
+		ops = ops[:filter.Limit]
+	}
+
+	if len(ops) == 0 {
+		if _, exists := r.wallets[walletID]; !exists {
+			return nil, ErrWalletNotFound
+		}
+	}
+
+	// Reversals always live on the wallet of the operation they reverse and
+	// are stored oldest first.
+	for i := range ops {
+		for _, op := range r.operations[walletID] {
+			if op.ReversalOf != nil && *op.ReversalOf == ops[i].ID {
+				ops[i].ReversedBy = append(ops[i].ReversedBy, op.ID)
+			}
+		}
+	}
+	return ops, nil
+}
+
+func (r *memoryRepo) Statement(_ context.Context, walletID uuid.UUID, from, to time.Time, w StatementWriter) error {
+	r.mu.RLock()
+	if _, exists := r.wallets[walletID]; !exists {
+		r.mu.RUnlock()
+		return ErrWalletNotFound
+	}
+	opening := decimal.Zero
+	var ops []Operation
+	for _, op := range r.operations[walletID] {
+		if op.CreatedAt.Before(from) {
+			opening = op.BalanceAfter
+		} else if op.CreatedAt.Before(to) {
+			ops = append(ops, op)
+		}
+	}
+	// Release the lock before writing, which may wait on a slow client.
+	r.mu.RUnlock()
+
+	if err := w.Opening(opening); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		if err := w.Operation(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepo) BalanceAt(_ context.Context, walletID uuid.UUID, at time.Time) (decimal.Decimal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.wallets[walletID]; !exists {
+		return decimal.Zero, ErrWalletNotFound
+	}
+
+	var last *Operation
+	for i, op := range r.operations[walletID] {
+		if op.CreatedAt.After(at) {
+			continue
+		}
+		if last == nil || compareOperation(op, last.CreatedAt, last.ID) > 0 {
+			last = &r.operations[walletID][i]
+		}
+	}
+	if last == nil {
+		return decimal.Zero, nil
+	}
+	return last.BalanceAfter, nil
+}
+
+func (r *memoryRepo) CreateHold(_ context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := memoryNow()
+	if err := r.applyHeldDelta(walletID, amount, ts); err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{
+		ID:        uuid.New(),
+		WalletID:  walletID,
+		Amount:    amount,
+		Status:    HoldActive,
+		ExpiresAt: ts.Add(ttl),
+		CreatedAt: ts,
+		UpdatedAt: ts,
+	}
+	r.holds[hold.ID] = hold
+
+	r.log.Debug("hold created",
+		slog.String("hold_id", hold.ID.String()),
+		slog.String("wallet_id", walletID.String()),
+		slog.String("amount", amount.String()),
+	)
+
+	h := *hold
+	return &h, nil
+}
+
+func (r *memoryRepo) GetHold(_ context.Context, holdID uuid.UUID) (*Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hold, exists := r.holds[holdID]
+	if !exists {
+		return nil, ErrHoldNotFound
+	}
+	h := *hold
+	return &h, nil
+}
+
+func (r *memoryRepo) CaptureHold(_ context.Context, holdID uuid.UUID, amount decimal.Decimal) (*Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.finishHold(holdID, HoldCaptured, amount)
+}
+
+func (r *memoryRepo) ReleaseHold(_ context.Context, holdID uuid.UUID) (*Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.finishHold(holdID, HoldReleased, decimal.Zero)
+}
+
+func (r *memoryRepo) ExpireHolds(_ context.Context, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := memoryNow()
+	var due []*Hold
+	for _, hold := range r.holds {
+		if hold.Status == HoldActive && !hold.ExpiresAt.After(ts) {
+			due = append(due, hold)
+		}
+	}
+	slices.SortFunc(due, func(a, b *Hold) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+	if limit >= 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	expired := 0
+	for _, hold := range due {
+		if _, err := r.finishHold(hold.ID, HoldExpired, decimal.Zero); err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (r *memoryRepo) SetStatus(_ context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return nil, ErrWalletNotFound
+	}
+	if err := checkTransition(w.Status, w.DepositsBlocked, w.Balance, update); err != nil {
+		return nil, err
+	}
+
+	ts := memoryNow()
+	change := StatusChange{
+		ID:              uuid.New(),
+		WalletID:        walletID,
+		FromStatus:      w.Status,
+		ToStatus:        update.Status,
+		DepositsBlocked: update.DepositsBlocked,
+		Reason:          update.Reason,
+		Actor:           update.Actor,
+		CreatedAt:       ts,
+	}
+	r.statusChanges[walletID] = append(r.statusChanges[walletID], change)
+
+	w.Status = update.Status
+	w.DepositsBlocked = update.DepositsBlocked
+	w.UpdatedAt = ts
+
+	r.log.Debug("wallet status changed",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("from_status", change.FromStatus),
+		slog.String("to_status", update.Status),
+		slog.Bool("deposits_blocked", update.DepositsBlocked),
+	)
+
+	return &change, nil
+}
+
+func (r *memoryRepo) PutLimitPolicy(_ context.Context, policy LimitPolicy) (*LimitPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if policy.Tier != "" {
+		for _, p := range r.limitPolicies {
+			if p.Tier == policy.Tier && p.Currency == policy.Currency && p.Name != policy.Name {
+				return nil, ErrLimitTierTaken
+			}
+		}
+	}
+
+	ts := memoryNow()
+	policy.CreatedAt = ts
+	if existing, exists := r.limitPolicies[policy.Name]; exists {
+		if existing.Currency != policy.Currency {
+			for _, w := range r.wallets {
+				if w.LimitPolicy == policy.Name {
+					return nil, ErrLimitCurrencyMismatch
+				}
+			}
+		}
+		policy.CreatedAt = existing.CreatedAt
+	}
+	policy.UpdatedAt = ts
+	r.limitPolicies[policy.Name] = &policy
+
+	r.log.Debug("limit policy saved", slog.String("policy", policy.Name), slog.String("tier", policy.Tier))
+
+	saved := policy
+	return &saved, nil
+}
+
+func (r *memoryRepo) ListLimitPolicies(_ context.Context) ([]LimitPolicy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policies := make([]LimitPolicy, 0, len(r.limitPolicies))
+	for _, p := range r.limitPolicies {
+		policies = append(policies, *p)
+	}
+	slices.SortFunc(policies, func(a, b LimitPolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return policies, nil
+}
+
+func (r *memoryRepo) SetWalletLimits(_ context.Context, walletID uuid.UUID, limits WalletLimits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return ErrWalletNotFound
+	}
+	if limits.Policy != "" {
+		policy, exists := r.limitPolicies[limits.Policy]
+		if !exists {
+			return ErrLimitPolicyNotFound
+		}
+		if policy.Currency != w.Currency {
+			return ErrLimitCurrencyMismatch
+		}
+	}
+
+	w.Tier = limits.Tier
+	w.LimitPolicy = limits.Policy
+	w.UpdatedAt = memoryNow()
+
+	r.log.Debug("wallet limits set",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("tier", limits.Tier),
+		slog.String("policy", limits.Policy),
+	)
+	return nil
+}
+
+func (r *memoryRepo) ReverseOperation(_ context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal) (*Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	original := r.findOperation(walletID, func(op Operation) bool { return op.ID == operationID })
+	if original == nil {
+		return nil, ErrOperationNotFound
+	}
+	if _, ok := reversalDelta(original.Type, amount); !ok {
+		return nil, ErrOperationNotReversible
+	}
+
+	originals := []*Operation{original}
+	if original.TransferID != nil {
+		var other *Operation
+		for id := range r.operations {
+			other = r.findOperation(id, func(op Operation) bool {
+				return op.TransferID != nil && *op.TransferID == *original.TransferID && op.ID != original.ID
+			})
+			if other != nil {
+				break
+			}
+		}
+		if other == nil {
+			return nil, ErrOperationNotFound
+		}
+		originals = append(originals, other)
+	}
+
+	reversed := decimal.Zero
+	for _, op := range r.operations[walletID] {
+		if op.ReversalOf != nil && *op.ReversalOf == original.ID {
+			reversed = reversed.Add(op.Amount)
+		}
+	}
+	if err := checkReversal(original, reversed, amount); err != nil {
+		return nil, err
+	}
+
+	// Check every leg before touching any wallet. Limits do not apply to
+	// reversals, so balances are changed here rather than by applyDelta.
+	for _, op := range originals {
+		w := r.wallets[op.WalletID]
+		delta, _ := reversalDelta(op.Type, amount)
+		if err := statusError(w.Status, w.DepositsBlocked, delta.IsPositive()); err != nil {
+			return nil, err
+		}
+		if w.Balance.Add(delta).LessThan(w.Held) {
+			return nil, ErrInsufficientFunds
+		}
+	}
+
+	ts := memoryNow()
+	var transferID *uuid.UUID
+	if len(originals) > 1 {
+		id := uuid.New()
+		transferID = &id
+	}
+	reversals := make([]Operation, 0, len(originals))
+	deltas := make([]decimal.Decimal, 0, len(originals))
+	for _, op := range originals {
+		w := r.wallets[op.WalletID]
+		delta, _ := reversalDelta(op.Type, amount)
+		w.Balance = w.Balance.Add(delta)
+		w.UpdatedAt = ts
+		reversalOf := op.ID
+		reversals = append(reversals, Operation{
+			WalletID:     op.WalletID,
+			Type:         OperationReversal,
+			Amount:       amount,
+			BalanceAfter: w.Balance,
+			TransferID:   transferID,
+			ReversalOf:   &reversalOf,
+		})
+		deltas = append(deltas, delta)
+	}
+	reversals = r.insertOperations(ts, reversals...)
+
+	entry := operationEntry(&reversals[0], deltas[0], cashAccount(original.Type))
+	if len(reversals) > 1 {
+		entry = journalEntry{entryType: OperationReversal, postings: []posting{
+			walletPosting(&reversals[0], deltas[0]),
+			walletPosting(&reversals[1], deltas[1]),
+		}}
+	}
+	if err := r.postEntries(entry); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("operation reversed",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("operation_id", operationID.String()),
+		slog.String("amount", amount.String()),
+	)
+
+	reversal := reversals[0]
+	return &reversal, nil
+}
+
+// findOperation returns a copy of the first operation of the wallet matching
+// match, or nil. r.mu must be held.
+func (r *memoryRepo) findOperation(walletID uuid.UUID, match func(Operation) bool) *Operation {
+	for _, op := range r.operations[walletID] {
+		if match(op) {
+			return &op
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepo) Reconcile(_ context.Context, walletID uuid.UUID) (*Reconciliation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return nil, ErrWalletNotFound
+	}
+
+	ops := r.operations[walletID]
+	rc := &Reconciliation{WalletID: walletID, Balance: w.Balance, Operations: len(ops)}
+	types := make(map[uuid.UUID]string, len(ops))
+	for _, op := range ops {
+		types[op.ID] = op.Type
+	}
+	for _, op := range ops {
+		var reversedType string
+		if op.ReversalOf != nil {
+			reversedType = types[*op.ReversalOf]
+		}
+		rc.Computed = rc.Computed.Add(signedAmount(op.Type, reversedType, op.Amount))
+	}
+	if len(ops) > 0 {
+		rc.LastBalanceAfter = ops[len(ops)-1].BalanceAfter
+	}
+	return rc, nil
+}
+
+func (r *memoryRepo) ListWalletIDs(_ context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uuid.UUID
+	for id := range r.wallets {
+		if bytes.Compare(id[:], after[:]) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *memoryRepo) TrialBalance(_ context.Context) (*TrialBalance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type lineKey struct{ currency, account string }
+	lines := make(map[lineKey]*TrialBalanceLine)
+	for account, balance := range r.ledger {
+		key := lineKey{account.currency, account.code}
+		if account.code == "" {
+			key.account = TrialBalanceWallets
+		}
+		line, exists := lines[key]
+		if !exists {
+			line = &TrialBalanceLine{Currency: key.currency, Account: key.account}
+			lines[key] = line
+		}
+		line.Accounts++
+		line.Balance = line.Balance.Add(balance)
+	}
+
+	tb := &TrialBalance{}
+	for _, line := range lines {
+		tb.Lines = append(tb.Lines, *line)
+	}
+	sortTrialBalance(tb.Lines)
+
+	for _, w := range r.wallets {
+		ledger := r.ledger[ledgerAccount{walletID: w.ID, currency: w.Currency}]
+		if !ledger.Equal(w.Balance) {
+			tb.Mismatches = append(tb.Mismatches, BalanceMismatch{WalletID: w.ID, Cached: w.Balance, Ledger: ledger})
+		}
+	}
+	slices.SortFunc(tb.Mismatches, func(a, b BalanceMismatch) int {
+		return bytes.Compare(a.WalletID[:], b.WalletID[:])
+	})
+	if len(tb.Mismatches) > maxBalanceMismatches {
+		tb.Mismatches = tb.Mismatches[:maxBalanceMismatches]
+	}
+	return tb, nil
+}
+
+func (r *memoryRepo) finishHold(holdID uuid.UUID, status string, captured decimal.Decimal) (*Hold, error) {
+	hold, exists := r.holds[holdID]
+	if !exists {
+		return nil, ErrHoldNotFound
+	}
+
+	ts := memoryNow()
+	if hold.Status != HoldActive {
+		return nil, ErrHoldNotActive
+	}
+	if status == HoldCaptured {
+		if !hold.ExpiresAt.After(ts) {
+			return nil, ErrHoldNotActive
+		}
+		if captured.GreaterThan(hold.Amount) {
+			return nil, ErrCaptureExceedsHold
+		}
+	}
+
+	// Nothing is rolled back here, so the capture must be known to succeed
+	// before the hold is released.
+	if captured.IsPositive() {
+		w := r.wallets[hold.WalletID]
+		if err := statusError(w.Status, w.DepositsBlocked, false); err != nil {
+			return nil, err
+		}
+		if err := r.enforceLimits(w, captured.Neg(), w.Balance.Sub(captured), ts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.applyHeldDelta(hold.WalletID, hold.Amount.Neg(), ts); err != nil {
+		return nil, err
+	}
+	if captured.IsPositive() {
+		balance, err := r.applyDelta(hold.WalletID, captured.Neg(), ts)
+		if err != nil {
+			return nil, err
+		}
+		id := holdID
+		ops := r.insertOperations(ts, Operation{
+			WalletID:     hold.WalletID,
+			Type:         OperationWithdraw,
+			Amount:       captured,
+			BalanceAfter: balance,
+			HoldID:       &id,
+		})
+		if err := r.postEntries(operationEntry(&ops[0], captured.Neg(), AccountCashOut)); err != nil {
+			return nil, err
+		}
+	}
+
+	hold.Status = status
+	hold.CapturedAmount = captured
+	hold.UpdatedAt = ts
+
+	r.log.Debug("hold finished",
+		slog.String("hold_id", holdID.String()),
+		slog.String("wallet_id", hold.WalletID.String()),
+		slog.String("status", status),
+		slog.String("captured_amount", captured.String()),
+	)
+
+	h := *hold
+	return &h, nil
+}
+
+// applyDelta mirrors the Postgres helper of the same name. r.mu must be held.
+func (r *memoryRepo) applyDelta(walletID uuid.UUID, delta decimal.Decimal, ts time.Time) (decimal.Decimal, error) {
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return decimal.Zero, ErrWalletNotFound
+	}
+	if err := statusError(w.Status, w.DepositsBlocked, delta.IsPositive()); err != nil {
+		return decimal.Zero, err
+	}
+	balance := w.Balance.Add(delta)
+	if balance.LessThan(w.Held) {
+		return decimal.Zero, ErrInsufficientFunds
+	}
+	if err := r.enforceLimits(w, delta, balance, ts); err != nil {
+		return decimal.Zero, err
+	}
+	w.Balance = balance
+	w.UpdatedAt = ts
+	return balance, nil
+}
+
+// enforceLimits mirrors the Postgres helper of the same name, with days and
+// months in UTC. r.mu must be held.
+func (r *memoryRepo) enforceLimits(w *Wallet, delta, balance decimal.Decimal, ts time.Time) error {
+	policy := r.limitPolicies[w.LimitPolicy]
+	if w.LimitPolicy == "" && w.Tier != "" {
+		for _, p := range r.limitPolicies {
+			if p.Tier == w.Tier && p.Currency == w.Currency {
+				policy = p
+				break
+			}
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+
+	reversed := make(map[uuid.UUID]decimal.Decimal)
+	for _, op := range r.operations[w.ID] {
+		if op.ReversalOf != nil {
+			reversed[*op.ReversalOf] = reversed[*op.ReversalOf].Add(op.Amount)
+		}
+	}
+
+	var usage limitUsage
+	day := ts.Truncate(24 * time.Hour)
+	month := time.Date(ts.Year(), ts.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, op := range r.operations[w.ID] {
+		if !slices.Contains(outgoingOperations, op.Type) || op.CreatedAt.Before(month) {
+			continue
+		}
+		amount := op.Amount.Sub(reversed[op.ID])
+		usage.month = usage.month.Add(amount)
+		if !op.CreatedAt.Before(day) {
+			usage.day = usage.day.Add(amount)
+		}
+	}
+	return checkLimits(policy, usage, delta, balance)
+}
+
+// applyHeldDelta mirrors the Postgres helper of the same name. r.mu must be
+// held.
+func (r *memoryRepo) applyHeldDelta(walletID uuid.UUID, delta decimal.Decimal, ts time.Time) error {
+	w, exists := r.wallets[walletID]
+	if !exists {
+		return ErrWalletNotFound
+	}
+	if delta.IsPositive() && w.Status != WalletActive {
+		return statusError(w.Status, w.DepositsBlocked, false)
+	}
+	held := w.Held.Add(delta)
+	if held.GreaterThan(w.Balance) {
+		return ErrInsufficientFunds
+	}
+	w.Held = held
+	w.UpdatedAt = ts
+	return nil
+}
+
+// insertOperations records ops as created at ts and returns them with their
+// IDs and timestamps. r.mu must be held.
+func (r *memoryRepo) insertOperations(ts time.Time, ops ...Operation) []Operation {
+	for i := range ops {
+		ops[i].ID = uuid.Must(uuid.NewV7())
+		ops[i].CreatedAt = ts
+		r.operations[ops[i].WalletID] = append(r.operations[ops[i].WalletID], ops[i])
+	}
+	return ops
+}
+
+// postEntries mirrors the Postgres helper of the same name. Only account
+// balances are kept. r.mu must be held.
+func (r *memoryRepo) postEntries(entries ...journalEntry) error {
+	for _, entry := range entries {
+		sum := decimal.Zero
+		for _, p := range entry.postings {
+			sum = sum.Add(p.amount)
+		}
+		if !sum.IsZero() {
+			return fmt.Errorf("journal entry %s does not balance", entry.entryType)
+		}
+	}
+
+	for _, entry := range entries {
+		for _, p := range entry.postings {
+			account := ledgerAccount{walletID: p.walletID, currency: r.wallets[p.walletID].Currency}
+			if p.system != "" {
+				account = ledgerAccount{code: p.system, currency: account.currency}
+			}
+			r.ledger[account] = r.ledger[account].Add(p.amount)
+		}
+	}
+	return nil
+}
+
+// compareOperation orders op against the (createdAt, id) position the way
+// ListOperations does in SQL.
+func compareOperation(op Operation, createdAt time.Time, id uuid.UUID) int {
+	if c := op.CreatedAt.Compare(createdAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(op.ID[:], id[:])
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryIdempotencyRepo is the in-memory counterpart of idempotencyRepo.
+type memoryIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewMemoryIdempotency() IdempotencyRepository {
+	return &memoryIdempotencyRepo{
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+func (r *memoryIdempotencyRepo) Reserve(_ context.Context, key, requestHash string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := memoryNow()
+	if rec, exists := r.records[key]; exists && !rec.ExpiresAt.Before(ts) {
+		return false, nil
+	}
+
+	r.records[key] = IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   ts,
+		ExpiresAt:   ts.Add(ttl),
+	}
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepo) Get(_ context.Context, key string) (*IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, exists := r.records[key]
+	if !exists {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	return &rec, nil
+}
+
+func (r *memoryIdempotencyRepo) Complete(_ context.Context, key string, statusCode int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, exists := r.records[key]; exists {
+		rec.StatusCode = statusCode
+		rec.ResponseBody = body
+		r.records[key] = rec
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, key)
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) DeleteExpired(_ context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := memoryNow()
+	var deleted int64
+	for key, rec := range r.records {
+		if rec.ExpiresAt.Before(ts) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,19 @@
+package repository_test
+
+import (
+	"testing"
+
+	"ITK/internal/repository"
+	"ITK/internal/repository/repositorytest"
+	"ITK/pkg/logger/handlers/slogdiscard"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	suite.Run(t, &repositorytest.Suite{
+		NewRepository: func() repository.Repository {
+			return repository.NewMemory(slogdiscard.NewDiscardLogger())
+		},
+	})
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Operation struct {
+	ID           uuid.UUID
+	WalletID     uuid.UUID
+	Type         string
+	Amount       decimal.Decimal
+	BalanceAfter decimal.Decimal
+	TransferID   *uuid.UUID
+	HoldID       *uuid.UUID
+	ReversalOf   *uuid.UUID
+	CreatedAt    time.Time
+
+	// ReversedBy lists the reversals of the operation, oldest first. It is
+	// only filled in by ListOperations.
+	ReversedBy []uuid.UUID
+}
+
+var operationColumns = []string{
+	"id", "wallet_id", "operation_type", "amount", "balance_after", "transfer_id", "hold_id", "reversal_of", "created_at",
+}
+
+func scanOperation(row pgx.Row) (*Operation, error) {
+	var op Operation
+	err := row.Scan(&op.ID, &op.WalletID, &op.Type, &op.Amount, &op.BalanceAfter, &op.TransferID, &op.HoldID, &op.ReversalOf, &op.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// OperationCursor points at the last operation of a previous page.
+type OperationCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// OperationFilter narrows ListOperations. Zero values mean "no restriction";
+// From is inclusive and To is exclusive.
+type OperationFilter struct {
+	Types []string
+	From  time.Time
+	To    time.Time
+	After *OperationCursor
+	Limit int
+}
+
+// ListOperations returns operations of a wallet ordered from newest to oldest
+// by (created_at, id), starting right after filter.After.
+func (r *walletRepo) ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) (_ []Operation, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ListOperations", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	query := squirrel.Select(operationColumns...).
+		From("operations").
+		Where(squirrel.Eq{"wallet_id": walletID}).
+		OrderBy("created_at DESC", "id DESC").
+		PlaceholderFormat(squirrel.Dollar)
+
+	if len(filter.Types) > 0 {
+		query = query.Where(squirrel.Eq{"operation_type": filter.Types})
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(squirrel.GtOrEq{"created_at": filter.From})
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(squirrel.Lt{"created_at": filter.To})
+	}
+	if filter.After != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(uint64(filter.Limit))
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		r.log.Error("failed to list operations", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
+	var ops []Operation
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		ops = append(ops, *op)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	if len(ops) == 0 {
+		if _, err = r.GetByID(ctx, walletID); err != nil {
+			return nil, err
+		}
+		return ops, nil
+	}
+
+	if err = r.loadReversals(ctx, ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// BalanceAt returns the balance of a wallet right after its last operation
+// created at or before at, or zero if it had none by then.
+func (r *walletRepo) BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (_ decimal.Decimal, err error) {
+	ctx, span := tracer.Start(ctx, "repository.BalanceAt", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select("balance_after").
+		From("operations").
+		Where(squirrel.Eq{"wallet_id": walletID}).
+		Where(squirrel.LtOrEq{"created_at": at}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(1).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var balance decimal.Decimal
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err = r.GetByID(ctx, walletID); err != nil {
+			return decimal.Zero, err
+		}
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return balance, nil
+}
+
+// loadReversals fills in ReversedBy of ops.
+func (r *walletRepo) loadReversals(ctx context.Context, ops []Operation) error {
+	byID := make(map[uuid.UUID]*Operation, len(ops))
+	ids := make([]uuid.UUID, 0, len(ops))
+	for i := range ops {
+		byID[ops[i].ID] = &ops[i]
+		ids = append(ids, ops[i].ID)
+	}
+
+	sql, args, err := squirrel.Select("reversal_of", "id").
+		From("operations").
+		Where("reversal_of = ANY(?)", ids).
+		OrderBy("created_at", "id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list reversals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reversalOf, id uuid.UUID
+		if err = rows.Scan(&reversalOf, &id); err != nil {
+			return fmt.Errorf("failed to scan reversal: %w", err)
+		}
+		op := byID[reversalOf]
+		op.ReversedBy = append(op.ReversedBy, id)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to list reversals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// EventOperationCreated is published for every row written to operations.
+const EventOperationCreated = "operation.created"
+
+// OperationEvent is the payload of an EventOperationCreated outbox event.
+type OperationEvent struct {
+	OperationID   uuid.UUID       `json:"operationId"`
+	WalletID      uuid.UUID       `json:"walletId"`
+	OperationType string          `json:"operationType"`
+	Amount        decimal.Decimal `json:"amount"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter"`
+	TransferID    *uuid.UUID      `json:"transferId,omitempty"`
+	HoldID        *uuid.UUID      `json:"holdId,omitempty"`
+	ReversalOf    *uuid.UUID      `json:"reversalOf,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// insertOperations records ops and queues an outbox event for each of them in
+// the same transaction, so an event exists if and only if the operation was
+// committed. Operation IDs are generated here and creation timestamps
+// assigned by the database are written back into ops.
+//
+// The caller must hold the locks of the wallets of ops. created_at is then
+// the start of this statement rather than the NOW() default, which is the
+// start of the transaction and may predate operations committed while it
+// waited for the lock; stamped after the lock, created_at follows the order
+// in which operations were applied to a wallet.
+func insertOperations(ctx context.Context, tx pgx.Tx, ops ...*Operation) error {
+	byID := make(map[uuid.UUID]*Operation, len(ops))
+	insert := squirrel.Insert("operations").
+		Columns("id", "wallet_id", "operation_type", "amount", "balance_after", "transfer_id", "hold_id", "reversal_of", "created_at")
+	for _, op := range ops {
+		// Operations written by one statement share created_at; time
+		// ordered IDs keep them listed in the order they were applied.
+		op.ID = uuid.Must(uuid.NewV7())
+		byID[op.ID] = op
+		insert = insert.Values(op.ID, op.WalletID, op.Type, op.Amount, op.BalanceAfter, op.TransferID, op.HoldID, op.ReversalOf, squirrel.Expr("statement_timestamp()"))
+	}
+
+	insertSQL, insertArgs, err := insert.
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert SQL: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, insertSQL, insertArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to insert operation records: %w", err)
+	}
+	for rows.Next() {
+		var (
+			id        uuid.UUID
+			createdAt time.Time
+		)
+		if err = rows.Scan(&id, &createdAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan operation record: %w", err)
+		}
+		if op, ok := byID[id]; ok {
+			op.CreatedAt = createdAt
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to insert operation records: %w", err)
+	}
+
+	events := squirrel.Insert("outbox_events").
+		Columns("event_type", "wallet_id", "payload", "created_at")
+	for _, op := range ops {
+		payload, err := json.Marshal(OperationEvent{
+			OperationID:   op.ID,
+			WalletID:      op.WalletID,
+			OperationType: op.Type,
+			Amount:        op.Amount,
+			BalanceAfter:  op.BalanceAfter,
+			TransferID:    op.TransferID,
+			HoldID:        op.HoldID,
+			ReversalOf:    op.ReversalOf,
+			CreatedAt:     op.CreatedAt.UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode operation event: %w", err)
+		}
+		events = events.Values(EventOperationCreated, op.WalletID, payload, op.CreatedAt)
+	}
+
+	eventsSQL, eventsArgs, err := events.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build outbox SQL: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, eventsSQL, eventsArgs...); err != nil {
+		return fmt.Errorf("failed to insert outbox events: %w", err)
+	}
+	return nil
+}
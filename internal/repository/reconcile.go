@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// incomingOperations are the operation types that add their amount to the
+// balance.
+var incomingOperations = []string{OperationDeposit, OperationTransferIn}
+
+// Reconciliation compares the cached balance of a wallet with the balance
+// recomputed from its operations.
+type Reconciliation struct {
+	WalletID uuid.UUID
+	// Balance is wallets.balance.
+	Balance decimal.Decimal
+	// Computed is the sum of the signed amounts of all operations.
+	Computed decimal.Decimal
+	// LastBalanceAfter is the balance_after of the latest operation, or zero
+	// if there is none.
+	LastBalanceAfter decimal.Decimal
+	Operations       int
+}
+
+// Drift is how much Balance exceeds Computed.
+func (rc *Reconciliation) Drift() decimal.Decimal {
+	return rc.Balance.Sub(rc.Computed)
+}
+
+// Consistent reports whether the three balances agree.
+func (rc *Reconciliation) Consistent() bool {
+	return rc.Balance.Equal(rc.Computed) && rc.Balance.Equal(rc.LastBalanceAfter)
+}
+
+// signedAmount returns the change of the balance made by an operation.
+// reversedType is the type of the operation a reversal reverses.
+func signedAmount(opType, reversedType string, amount decimal.Decimal) decimal.Decimal {
+	switch opType {
+	case OperationDeposit, OperationTransferIn:
+		return amount
+	case OperationWithdraw, OperationTransferOut:
+		return amount.Neg()
+	case OperationReversal:
+		delta, _ := reversalDelta(reversedType, amount)
+		return delta
+	}
+	return decimal.Zero
+}
+
+// Reconcile recomputes the balance of a wallet from its operations. It reads
+// a snapshot, so it takes no wallet lock and runs next to live traffic.
+func (r *walletRepo) Reconcile(ctx context.Context, walletID uuid.UUID) (_ *Reconciliation, err error) {
+	ctx, span := tracer.Start(ctx, "repository.Reconcile", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	// A reversal has the opposite sign of the operation it reverses, which
+	// always lives on the same wallet.
+	sql, args, err := squirrel.Select("w.balance").
+		Column(squirrel.Expr(`COALESCE(SUM(CASE
+				WHEN o.operation_type = ANY(?) THEN o.amount
+				WHEN o.operation_type = ANY(?) THEN -o.amount
+				WHEN o.operation_type = ? AND orig.operation_type = ANY(?) THEN -o.amount
+				WHEN o.operation_type = ? AND orig.operation_type = ANY(?) THEN o.amount
+			END), 0)`,
+			incomingOperations, outgoingOperations,
+			OperationReversal, incomingOperations,
+			OperationReversal, outgoingOperations,
+		)).
+		Column("COUNT(o.id)").
+		Column(`COALESCE((
+			SELECT l.balance_after FROM operations l
+			WHERE l.wallet_id = w.id
+			ORDER BY l.created_at DESC, l.id DESC
+			LIMIT 1
+		), 0)`).
+		From("wallets w").
+		LeftJoin("operations o ON o.wallet_id = w.id").
+		LeftJoin("operations orig ON orig.id = o.reversal_of").
+		Where(squirrel.Eq{"w.id": walletID}).
+		GroupBy("w.id", "w.balance").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rc := &Reconciliation{WalletID: walletID}
+	err = tx.QueryRow(ctx, sql, args...).Scan(&rc.Balance, &rc.Computed, &rc.Operations, &rc.LastBalanceAfter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile wallet: %w", err)
+	}
+	return rc, nil
+}
+
+// ListWalletIDs returns up to limit wallet IDs greater than after, in order.
+func (r *walletRepo) ListWalletIDs(ctx context.Context, after uuid.UUID, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ListWalletIDs")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select("id").
+		From("wallets").
+		Where(squirrel.Gt{"id": after}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package repositorytest
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// trialBalance returns the lines of the trial balance by currency and
+// account, checking that every currency sums to zero.
+func (s *Suite) trialBalance() (map[string]map[string]decimal.Decimal, []repository.BalanceMismatch) {
+	tb, err := s.repo.TrialBalance(s.ctx)
+	s.Require().NoError(err)
+	for currency, total := range tb.Totals() {
+		s.True(total.IsZero(), "%s does not balance: %s", currency, total)
+	}
+
+	lines := make(map[string]map[string]decimal.Decimal)
+	for _, line := range tb.Lines {
+		if lines[line.Currency] == nil {
+			lines[line.Currency] = make(map[string]decimal.Decimal)
+		}
+		lines[line.Currency][line.Account] = line.Balance
+	}
+	return lines, tb.Mismatches
+}
+
+func (s *Suite) TestLedgerBalances() {
+	fromID := s.createWallet("0")
+	before, _ := s.trialBalance()
+
+	toID := s.createWallet("0")
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, fromID, repository.OperationDeposit, amount("100")))
+	deposit := s.lastOperation(fromID)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, fromID, repository.OperationWithdraw, amount("30")))
+	s.Require().NoError(s.repo.Transfer(s.ctx, fromID, toID, amount("20")))
+	in := s.lastOperation(toID)
+	_, err := s.repo.ReverseOperation(s.ctx, toID, in.ID, amount("5"))
+	s.Require().NoError(err)
+	_, err = s.repo.ReverseOperation(s.ctx, fromID, deposit.ID, amount("10"))
+	s.Require().NoError(err)
+	results, err := s.repo.ApplyOperations(s.ctx, toID, []repository.BatchOperation{
+		{Type: repository.OperationDeposit, Amount: amount("7")},
+		{Type: repository.OperationWithdraw, Amount: amount("2")},
+	})
+	s.Require().NoError(err)
+	s.Equal([]error{nil, nil}, results)
+	hold, err := s.repo.CreateHold(s.ctx, toID, amount("4"), time.Hour)
+	s.Require().NoError(err)
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("3"))
+	s.Require().NoError(err)
+
+	s.requireBalance(fromID, "45", "0")
+	s.requireBalance(toID, "17", "0")
+
+	after, mismatches := s.trialBalance()
+	delta := func(account string) decimal.Decimal {
+		return after["RUB"][account].Sub(before["RUB"][account])
+	}
+	s.requireAmount("-97", delta(repository.AccountCashIn), "cash in")
+	s.requireAmount("35", delta(repository.AccountCashOut), "cash out")
+	s.requireAmount("62", delta(repository.TrialBalanceWallets), "wallets")
+
+	for _, m := range mismatches {
+		s.NotContains([]uuid.UUID{fromID, toID}, m.WalletID)
+	}
+}
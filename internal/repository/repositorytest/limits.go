@@ -0,0 +1,270 @@
+package repositorytest
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// putPolicy saves a policy under a name no other test uses and returns it.
+// The policy is in the currency of createWallet unless it names another.
+func (s *Suite) putPolicy(policy repository.LimitPolicy) *repository.LimitPolicy {
+	policy.Name = "repositorytest-" + uuid.NewString()
+	if policy.Currency == "" {
+		policy.Currency = "RUB"
+	}
+	saved, err := s.repo.PutLimitPolicy(s.ctx, policy)
+	s.Require().NoError(err)
+	return saved
+}
+
+func (s *Suite) setLimits(walletID uuid.UUID, tier, policy string) {
+	s.Require().NoError(s.repo.SetWalletLimits(s.ctx, walletID, repository.WalletLimits{Tier: tier, Policy: policy}))
+}
+
+func (s *Suite) requireLimitExceeded(err error, policy, limit string) {
+	var exceeded *repository.LimitExceededError
+	s.Require().ErrorAs(err, &exceeded)
+	s.ErrorIs(err, repository.ErrLimitExceeded)
+	s.Equal(policy, exceeded.Policy)
+	s.Equal(limit, exceeded.Limit)
+}
+
+func limit(value string) decimal.NullDecimal {
+	return decimal.NewNullDecimal(amount(value))
+}
+
+func (s *Suite) TestPutLimitPolicy() {
+	policy := s.putPolicy(repository.LimitPolicy{MaxWithdrawal: limit("100")})
+	s.True(policy.MaxWithdrawal.Valid)
+	s.requireAmount("100", policy.MaxWithdrawal.Decimal)
+	s.False(policy.DailyWithdrawal.Valid)
+	s.Equal("RUB", policy.Currency)
+	s.False(policy.CreatedAt.IsZero())
+
+	policy.MaxWithdrawal = decimal.NullDecimal{}
+	policy.MaxBalance = limit("1000")
+	replaced, err := s.repo.PutLimitPolicy(s.ctx, *policy)
+	s.Require().NoError(err)
+	s.False(replaced.MaxWithdrawal.Valid)
+	s.requireAmount("1000", replaced.MaxBalance.Decimal)
+	s.True(replaced.CreatedAt.Equal(policy.CreatedAt))
+
+	policies, err := s.repo.ListLimitPolicies(s.ctx)
+	s.Require().NoError(err)
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	s.Contains(names, policy.Name)
+}
+
+func (s *Suite) TestLimitPolicyKeepsMinorUnits() {
+	policy := s.putPolicy(repository.LimitPolicy{Currency: "KWD", MaxWithdrawal: limit("100.125")})
+	s.requireAmount("100.125", policy.MaxWithdrawal.Decimal)
+
+	policies, err := s.repo.ListLimitPolicies(s.ctx)
+	s.Require().NoError(err)
+	for _, p := range policies {
+		if p.Name == policy.Name {
+			s.Equal("KWD", p.Currency)
+			s.requireAmount("100.125", p.MaxWithdrawal.Decimal)
+		}
+	}
+}
+
+func (s *Suite) TestTierHasOnePolicyPerCurrency() {
+	tier := "tier-" + uuid.NewString()
+	s.putPolicy(repository.LimitPolicy{Tier: tier})
+
+	_, err := s.repo.PutLimitPolicy(s.ctx, repository.LimitPolicy{Name: "repositorytest-" + uuid.NewString(), Tier: tier, Currency: "RUB"})
+	s.ErrorIs(err, repository.ErrLimitTierTaken)
+
+	s.putPolicy(repository.LimitPolicy{Tier: tier, Currency: "USD"})
+}
+
+func (s *Suite) TestLimitPolicyCurrencyMatchesWallet() {
+	walletID := s.createWallet("500")
+	usd := s.putPolicy(repository.LimitPolicy{Currency: "USD", MaxWithdrawal: limit("10")})
+
+	err := s.repo.SetWalletLimits(s.ctx, walletID, repository.WalletLimits{Policy: usd.Name})
+	s.ErrorIs(err, repository.ErrLimitCurrencyMismatch)
+
+	// A policy of the wallet's tier in another currency does not apply.
+	tier := "tier-" + uuid.NewString()
+	s.putPolicy(repository.LimitPolicy{Tier: tier, Currency: "USD", MaxWithdrawal: limit("10")})
+	s.setLimits(walletID, tier, "")
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("20")))
+
+	// Nor can an assigned policy move to another currency.
+	rub := s.putPolicy(repository.LimitPolicy{MaxWithdrawal: limit("10")})
+	s.setLimits(walletID, "", rub.Name)
+	rub.Currency = "USD"
+	_, err = s.repo.PutLimitPolicy(s.ctx, *rub)
+	s.ErrorIs(err, repository.ErrLimitCurrencyMismatch)
+
+	err = s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("20"))
+	s.requireLimitExceeded(err, rub.Name, repository.LimitMaxWithdrawal)
+}
+
+func (s *Suite) TestSetWalletLimits() {
+	walletID := s.createWallet("0")
+	policy := s.putPolicy(repository.LimitPolicy{})
+
+	s.setLimits(walletID, "unverified", policy.Name)
+	wallet, err := s.repo.GetByID(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.Equal("unverified", wallet.Tier)
+	s.Equal(policy.Name, wallet.LimitPolicy)
+
+	s.setLimits(walletID, "", "")
+	wallet, err = s.repo.GetByID(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.Empty(wallet.Tier)
+	s.Empty(wallet.LimitPolicy)
+
+	err = s.repo.SetWalletLimits(s.ctx, walletID, repository.WalletLimits{Policy: "missing-" + uuid.NewString()})
+	s.ErrorIs(err, repository.ErrLimitPolicyNotFound)
+	err = s.repo.SetWalletLimits(s.ctx, uuid.New(), repository.WalletLimits{})
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestMaxWithdrawal() {
+	walletID := s.createWallet("500")
+	policy := s.putPolicy(repository.LimitPolicy{MaxWithdrawal: limit("100")})
+	s.setLimits(walletID, "", policy.Name)
+
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("100.01"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitMaxWithdrawal)
+	s.requireBalance(walletID, "500", "0")
+
+	// A withdrawal that does not fit the balance is reported as such first.
+	err = s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("600"))
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("100")))
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("1000")))
+	s.requireBalance(walletID, "1400", "0")
+}
+
+func (s *Suite) TestDailyWithdrawalCountsEveryOutflow() {
+	walletID := s.createWallet("500")
+	otherID := s.createWallet("0")
+	policy := s.putPolicy(repository.LimitPolicy{DailyWithdrawal: limit("100")})
+	s.setLimits(walletID, "", policy.Name)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("40")))
+	s.Require().NoError(s.repo.Transfer(s.ctx, walletID, otherID, amount("30")))
+
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("50"), time.Hour)
+	s.Require().NoError(err)
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("31"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitDailyWithdrawal)
+	s.requireBalance(walletID, "430", "50")
+
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("20"))
+	s.Require().NoError(err)
+
+	err = s.repo.Transfer(s.ctx, walletID, otherID, amount("10.01"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitDailyWithdrawal)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("10")))
+	s.requireBalance(walletID, "400", "0")
+	s.requireBalance(otherID, "30", "0")
+}
+
+func (s *Suite) TestWithdrawalTotalsNetReversals() {
+	walletID := s.createWallet("500")
+	otherID := s.createWallet("0")
+	policy := s.putPolicy(repository.LimitPolicy{DailyWithdrawal: limit("100"), MonthlyWithdrawal: limit("100")})
+	s.setLimits(walletID, "", policy.Name)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("60")))
+	withdraw := s.lastOperation(walletID)
+	s.Require().NoError(s.repo.Transfer(s.ctx, walletID, otherID, amount("40")))
+	transferOut := s.lastOperation(walletID)
+
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("1"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitDailyWithdrawal)
+
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, withdraw.ID, amount("25"))
+	s.Require().NoError(err)
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, transferOut.ID, amount("15"))
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("40")))
+	err = s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("0.01"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitDailyWithdrawal)
+	s.requireBalance(walletID, "400", "0")
+	s.requireBalance(otherID, "25", "0")
+}
+
+func (s *Suite) TestMonthlyWithdrawal() {
+	walletID := s.createWallet("500")
+	policy := s.putPolicy(repository.LimitPolicy{MonthlyWithdrawal: limit("150")})
+	s.setLimits(walletID, "", policy.Name)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("100")))
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("60"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitMonthlyWithdrawal)
+	s.requireBalance(walletID, "400", "0")
+}
+
+func (s *Suite) TestMaxBalance() {
+	walletID := s.createWallet("50")
+	otherID := s.createWallet("100")
+	policy := s.putPolicy(repository.LimitPolicy{MaxBalance: limit("100")})
+	s.setLimits(walletID, "", policy.Name)
+
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("51"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitMaxBalance)
+
+	err = s.repo.Transfer(s.ctx, otherID, walletID, amount("60"))
+	s.requireLimitExceeded(err, policy.Name, repository.LimitMaxBalance)
+	s.requireBalance(walletID, "50", "0")
+	s.requireBalance(otherID, "100", "0")
+
+	s.Require().NoError(s.repo.Transfer(s.ctx, otherID, walletID, amount("50")))
+	s.requireBalance(walletID, "100", "0")
+}
+
+func (s *Suite) TestTierPolicy() {
+	walletID := s.createWallet("500")
+	tier := "tier-" + uuid.NewString()
+	tierPolicy := s.putPolicy(repository.LimitPolicy{Tier: tier, MaxWithdrawal: limit("10")})
+	s.setLimits(walletID, tier, "")
+
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("20"))
+	s.requireLimitExceeded(err, tierPolicy.Name, repository.LimitMaxWithdrawal)
+
+	// A policy of the wallet's own takes precedence over its tier.
+	own := s.putPolicy(repository.LimitPolicy{MaxWithdrawal: limit("50")})
+	s.setLimits(walletID, tier, own.Name)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("20")))
+	err = s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("60"))
+	s.requireLimitExceeded(err, own.Name, repository.LimitMaxWithdrawal)
+}
+
+func (s *Suite) TestApplyOperationsEnforcesLimits() {
+	walletID := s.createWallet("500")
+	policy := s.putPolicy(repository.LimitPolicy{DailyWithdrawal: limit("100"), MaxBalance: limit("520")})
+	s.setLimits(walletID, "", policy.Name)
+
+	results, err := s.repo.ApplyOperations(s.ctx, walletID, []repository.BatchOperation{
+		{Type: repository.OperationWithdraw, Amount: amount("60")},
+		{Type: repository.OperationWithdraw, Amount: amount("60")},
+		{Type: repository.OperationWithdraw, Amount: amount("40")},
+		{Type: repository.OperationDeposit, Amount: amount("130")},
+		{Type: repository.OperationDeposit, Amount: amount("20")},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(results, 5)
+	s.NoError(results[0])
+	s.requireLimitExceeded(results[1], policy.Name, repository.LimitDailyWithdrawal)
+	s.NoError(results[2])
+	s.requireLimitExceeded(results[3], policy.Name, repository.LimitMaxBalance)
+	s.NoError(results[4])
+	s.requireBalance(walletID, "420", "0")
+}
@@ -0,0 +1,75 @@
+package repositorytest
+
+import (
+	"bytes"
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func (s *Suite) TestReconcile() {
+	walletID := s.createWallet("100")
+	otherID := s.createWallet("50")
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("30")))
+	withdrawal := s.lastOperation(walletID)
+	s.Require().NoError(s.repo.Transfer(s.ctx, walletID, otherID, amount("20")))
+	s.Require().NoError(s.repo.Transfer(s.ctx, otherID, walletID, amount("15")))
+	transferIn := s.lastOperation(walletID)
+	_, err := s.repo.ReverseOperation(s.ctx, walletID, withdrawal.ID, amount("10"))
+	s.Require().NoError(err)
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, transferIn.ID, amount("5"))
+	s.Require().NoError(err)
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("8"), time.Hour)
+	s.Require().NoError(err)
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("6"))
+	s.Require().NoError(err)
+	_, err = s.repo.CreateHold(s.ctx, walletID, amount("4"), time.Hour)
+	s.Require().NoError(err)
+
+	// 100 - 30 - 20 + 15 + 10 - 5 - 6
+	s.requireBalance(walletID, "64", "4")
+	rc, err := s.repo.Reconcile(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.Equal(walletID, rc.WalletID)
+	s.requireAmount("64", rc.Balance, "balance")
+	s.requireAmount("64", rc.Computed, "computed")
+	s.requireAmount("64", rc.LastBalanceAfter, "last balance after")
+	s.Equal(7, rc.Operations)
+	s.True(rc.Consistent())
+	s.True(rc.Drift().IsZero())
+
+	rc, err = s.repo.Reconcile(s.ctx, s.createWallet("0"))
+	s.Require().NoError(err)
+	s.True(rc.Consistent())
+	s.Zero(rc.Operations)
+
+	_, err = s.repo.Reconcile(s.ctx, uuid.New())
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestListWalletIDs() {
+	created := []uuid.UUID{s.createWallet("0"), s.createWallet("0"), s.createWallet("0")}
+
+	var listed []uuid.UUID
+	after := uuid.Nil
+	for {
+		ids, err := s.repo.ListWalletIDs(s.ctx, after, 2)
+		s.Require().NoError(err)
+		s.Require().LessOrEqual(len(ids), 2)
+		if len(ids) == 0 {
+			break
+		}
+		listed = append(listed, ids...)
+		after = ids[len(ids)-1]
+	}
+
+	for i := 1; i < len(listed); i++ {
+		s.Require().Negative(bytes.Compare(listed[i-1][:], listed[i][:]), "not in order")
+	}
+	for _, id := range created {
+		s.Contains(listed, id)
+	}
+}
@@ -0,0 +1,118 @@
+package repositorytest
+
+import (
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// lastOperation returns the newest operation of the wallet.
+func (s *Suite) lastOperation(walletID uuid.UUID) repository.Operation {
+	ops := s.operations(walletID, repository.OperationFilter{Limit: 1})
+	s.Require().Len(ops, 1)
+	return ops[0]
+}
+
+func (s *Suite) TestReverseDepositPartially() {
+	walletID := s.createWallet("100")
+	deposit := s.lastOperation(walletID)
+
+	first, err := s.repo.ReverseOperation(s.ctx, walletID, deposit.ID, amount("30"))
+	s.Require().NoError(err)
+	s.Equal(repository.OperationReversal, first.Type)
+	s.Require().NotNil(first.ReversalOf)
+	s.Equal(deposit.ID, *first.ReversalOf)
+	s.requireAmount("70", first.BalanceAfter)
+	s.False(first.CreatedAt.IsZero())
+
+	second, err := s.repo.ReverseOperation(s.ctx, walletID, deposit.ID, amount("70"))
+	s.Require().NoError(err)
+	s.requireBalance(walletID, "0", "0")
+
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, deposit.ID, amount("1"))
+	s.ErrorIs(err, repository.ErrAlreadyReversed)
+
+	ops := s.operations(walletID, repository.OperationFilter{})
+	s.Require().Len(ops, 3)
+	s.Equal(deposit.ID, ops[2].ID)
+	s.Equal([]uuid.UUID{first.ID, second.ID}, ops[2].ReversedBy)
+	s.Empty(ops[0].ReversedBy)
+}
+
+func (s *Suite) TestReverseWithdraw() {
+	walletID := s.createWallet("100")
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("40")))
+	withdraw := s.lastOperation(walletID)
+
+	_, err := s.repo.ReverseOperation(s.ctx, walletID, withdraw.ID, amount("41"))
+	s.ErrorIs(err, repository.ErrReversalExceedsOriginal)
+
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, withdraw.ID, amount("40"))
+	s.Require().NoError(err)
+	s.requireBalance(walletID, "100", "0")
+}
+
+func (s *Suite) TestReverseTransfer() {
+	fromID := s.createWallet("100")
+	toID := s.createWallet("0")
+	s.Require().NoError(s.repo.Transfer(s.ctx, fromID, toID, amount("40")))
+	in := s.lastOperation(toID)
+
+	reversal, err := s.repo.ReverseOperation(s.ctx, toID, in.ID, amount("15"))
+	s.Require().NoError(err)
+	s.Equal(toID, reversal.WalletID)
+	s.requireBalance(fromID, "75", "0")
+	s.requireBalance(toID, "25", "0")
+
+	out := s.lastOperation(fromID)
+	s.Equal(repository.OperationReversal, out.Type)
+	s.Require().NotNil(out.TransferID)
+	s.Equal(reversal.TransferID, out.TransferID)
+	s.NotEqual(in.TransferID, out.TransferID)
+
+	transferOut := s.operations(fromID, repository.OperationFilter{Types: []string{repository.OperationTransferOut}})
+	s.Require().Len(transferOut, 1)
+	s.Equal([]uuid.UUID{out.ID}, transferOut[0].ReversedBy)
+
+	// Both legs were reversed, so the other leg has 25 left as well.
+	_, err = s.repo.ReverseOperation(s.ctx, fromID, transferOut[0].ID, amount("26"))
+	s.ErrorIs(err, repository.ErrReversalExceedsOriginal)
+}
+
+func (s *Suite) TestReverseInsufficientFunds() {
+	walletID := s.createWallet("100")
+	deposit := s.lastOperation(walletID)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("80")))
+
+	_, err := s.repo.ReverseOperation(s.ctx, walletID, deposit.ID, amount("50"))
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+	s.requireBalance(walletID, "20", "0")
+}
+
+func (s *Suite) TestReverseIgnoresLimits() {
+	walletID := s.createWallet("100")
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("50")))
+	withdraw := s.lastOperation(walletID)
+	policy := s.putPolicy(repository.LimitPolicy{MaxBalance: limit("60")})
+	s.setLimits(walletID, "", policy.Name)
+
+	_, err := s.repo.ReverseOperation(s.ctx, walletID, withdraw.ID, amount("50"))
+	s.Require().NoError(err)
+	s.requireBalance(walletID, "100", "0")
+}
+
+func (s *Suite) TestReverseInvalidOperation() {
+	walletID := s.createWallet("100")
+	deposit := s.lastOperation(walletID)
+
+	_, err := s.repo.ReverseOperation(s.ctx, walletID, uuid.New(), amount("1"))
+	s.ErrorIs(err, repository.ErrOperationNotFound)
+
+	_, err = s.repo.ReverseOperation(s.ctx, s.createWallet("0"), deposit.ID, amount("1"))
+	s.ErrorIs(err, repository.ErrOperationNotFound)
+
+	reversal, err := s.repo.ReverseOperation(s.ctx, walletID, deposit.ID, amount("1"))
+	s.Require().NoError(err)
+	_, err = s.repo.ReverseOperation(s.ctx, walletID, reversal.ID, amount("1"))
+	s.ErrorIs(err, repository.ErrOperationNotReversible)
+}
@@ -0,0 +1,81 @@
+package repositorytest
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// statement records what Statement passes to its writer.
+type statement struct {
+	opening    *decimal.Decimal
+	operations []repository.Operation
+}
+
+func (st *statement) Opening(balance decimal.Decimal) error {
+	st.opening = &balance
+	return nil
+}
+
+func (st *statement) Operation(op repository.Operation) error {
+	st.operations = append(st.operations, op)
+	return nil
+}
+
+func (s *Suite) statement(walletID uuid.UUID, from, to time.Time) *statement {
+	st := &statement{}
+	s.Require().NoError(s.repo.Statement(s.ctx, walletID, from, to, st))
+	s.Require().NotNil(st.opening)
+	return st
+}
+
+func (s *Suite) TestStatementPeriod() {
+	walletID := s.createWallet("100")
+	// Operations committed within the same microsecond share created_at.
+	time.Sleep(2 * time.Millisecond)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("30")))
+	withdrawal := s.lastOperation(walletID)
+	time.Sleep(2 * time.Millisecond)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("5")))
+	last := s.lastOperation(walletID)
+
+	st := s.statement(walletID, withdrawal.CreatedAt, last.CreatedAt)
+	s.requireAmount("100", *st.opening, "opening")
+	s.Require().Len(st.operations, 1)
+	s.Equal(withdrawal.ID, st.operations[0].ID)
+	s.requireAmount("70", st.operations[0].BalanceAfter)
+
+	st = s.statement(walletID, time.Time{}, time.Now().UTC().Add(time.Hour))
+	s.requireAmount("0", *st.opening, "opening")
+	s.Require().Len(st.operations, 3)
+	s.requireAmount("100", st.operations[0].BalanceAfter)
+	s.Equal(last.ID, st.operations[2].ID)
+
+	st = s.statement(walletID, time.Now().UTC().Add(time.Hour), time.Now().UTC().Add(2*time.Hour))
+	s.requireAmount("75", *st.opening, "opening")
+	s.Empty(st.operations)
+}
+
+func (s *Suite) TestStatementLongHistory() {
+	walletID := s.createWallet("0")
+	ops := make([]repository.BatchOperation, 1200)
+	for i := range ops {
+		ops[i] = repository.BatchOperation{Type: repository.OperationDeposit, Amount: amount("1")}
+	}
+	_, err := s.repo.ApplyOperations(s.ctx, walletID, ops)
+	s.Require().NoError(err)
+
+	st := s.statement(walletID, time.Time{}, time.Now().UTC().Add(time.Hour))
+	s.Require().Len(st.operations, len(ops))
+	for i, op := range st.operations {
+		s.requireAmount(decimal.NewFromInt(int64(i+1)).String(), op.BalanceAfter, i)
+	}
+}
+
+func (s *Suite) TestStatementMissingWallet() {
+	err := s.repo.Statement(s.ctx, uuid.New(), time.Time{}, time.Now().UTC(), &statement{})
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
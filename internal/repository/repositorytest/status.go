@@ -0,0 +1,148 @@
+package repositorytest
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func (s *Suite) setStatus(walletID uuid.UUID, status string, depositsBlocked bool) *repository.StatusChange {
+	change, err := s.repo.SetStatus(s.ctx, walletID, repository.StatusUpdate{
+		Status:          status,
+		DepositsBlocked: depositsBlocked,
+		Reason:          "test",
+		Actor:           "repositorytest",
+	})
+	s.Require().NoError(err)
+	return change
+}
+
+func (s *Suite) TestSetStatusRecordsChange() {
+	walletID := s.createWallet("10")
+
+	change := s.setStatus(walletID, repository.WalletFrozen, true)
+	s.NotEqual(uuid.Nil, change.ID)
+	s.Equal(walletID, change.WalletID)
+	s.Equal(repository.WalletActive, change.FromStatus)
+	s.Equal(repository.WalletFrozen, change.ToStatus)
+	s.True(change.DepositsBlocked)
+	s.Equal("test", change.Reason)
+	s.Equal("repositorytest", change.Actor)
+	s.False(change.CreatedAt.IsZero())
+
+	wallet, err := s.repo.GetByID(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.Equal(repository.WalletFrozen, wallet.Status)
+	s.True(wallet.DepositsBlocked)
+}
+
+func (s *Suite) TestStatusTransitions() {
+	walletID := s.createWallet("0")
+	update := repository.StatusUpdate{Reason: "test", Actor: "repositorytest"}
+
+	update.Status = repository.WalletActive
+	_, err := s.repo.SetStatus(s.ctx, walletID, update)
+	s.ErrorIs(err, repository.ErrInvalidStatusTransition)
+
+	s.setStatus(walletID, repository.WalletFrozen, false)
+	update.Status = repository.WalletFrozen
+	_, err = s.repo.SetStatus(s.ctx, walletID, update)
+	s.ErrorIs(err, repository.ErrInvalidStatusTransition)
+	s.setStatus(walletID, repository.WalletFrozen, true)
+	s.setStatus(walletID, repository.WalletActive, false)
+
+	s.setStatus(walletID, repository.WalletClosed, false)
+	for _, status := range []string{repository.WalletActive, repository.WalletFrozen, repository.WalletClosed} {
+		update.Status = status
+		_, err = s.repo.SetStatus(s.ctx, walletID, update)
+		s.ErrorIs(err, repository.ErrInvalidStatusTransition, status)
+	}
+
+	update.Status = repository.WalletFrozen
+	_, err = s.repo.SetStatus(s.ctx, uuid.New(), update)
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestCloseRequiresZeroBalance() {
+	walletID := s.createWallet("10")
+
+	_, err := s.repo.SetStatus(s.ctx, walletID, repository.StatusUpdate{
+		Status: repository.WalletClosed,
+		Reason: "test",
+		Actor:  "repositorytest",
+	})
+	s.ErrorIs(err, repository.ErrWalletNotEmpty)
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("10")))
+	s.setStatus(walletID, repository.WalletClosed, false)
+}
+
+func (s *Suite) TestFrozenWallet() {
+	walletID := s.createWallet("100")
+	otherID := s.createWallet("100")
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("10"), time.Hour)
+	s.Require().NoError(err)
+	s.setStatus(walletID, repository.WalletFrozen, false)
+
+	s.ErrorIs(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("1")), repository.ErrWalletFrozen)
+	s.ErrorIs(s.repo.Transfer(s.ctx, walletID, otherID, amount("1")), repository.ErrWalletFrozen)
+	_, err = s.repo.CreateHold(s.ctx, walletID, amount("1"), time.Hour)
+	s.ErrorIs(err, repository.ErrWalletFrozen)
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("5"))
+	s.ErrorIs(err, repository.ErrWalletFrozen)
+
+	// Money can still come in unless deposits are blocked, and holds can be
+	// released.
+	s.NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("5")))
+	s.NoError(s.repo.Transfer(s.ctx, otherID, walletID, amount("5")))
+	_, err = s.repo.ReleaseHold(s.ctx, hold.ID)
+	s.NoError(err)
+	s.requireBalance(walletID, "110", "0")
+
+	s.setStatus(walletID, repository.WalletFrozen, true)
+	s.ErrorIs(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("5")), repository.ErrWalletFrozen)
+	s.ErrorIs(s.repo.Transfer(s.ctx, otherID, walletID, amount("5")), repository.ErrWalletFrozen)
+	s.requireBalance(walletID, "110", "0")
+	s.requireBalance(otherID, "95", "0")
+
+	s.setStatus(walletID, repository.WalletActive, false)
+	s.NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("10")))
+	s.requireBalance(walletID, "100", "0")
+}
+
+func (s *Suite) TestFrozenWalletInBatch() {
+	walletID := s.createWallet("100")
+	s.setStatus(walletID, repository.WalletFrozen, false)
+
+	results, err := s.repo.ApplyOperations(s.ctx, walletID, []repository.BatchOperation{
+		{Type: repository.OperationDeposit, Amount: amount("10")},
+		{Type: repository.OperationWithdraw, Amount: amount("5")},
+	})
+	s.Require().NoError(err)
+	s.NoError(results[0])
+	s.ErrorIs(results[1], repository.ErrWalletFrozen)
+	s.requireBalance(walletID, "110", "0")
+}
+
+func (s *Suite) TestClosedWallet() {
+	walletID := s.createWallet("0")
+	otherID := s.createWallet("100")
+	s.setStatus(walletID, repository.WalletClosed, false)
+
+	s.ErrorIs(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("1")), repository.ErrWalletClosed)
+	s.ErrorIs(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("1")), repository.ErrWalletClosed)
+	s.ErrorIs(s.repo.Transfer(s.ctx, otherID, walletID, amount("1")), repository.ErrWalletClosed)
+	_, err := s.repo.CreateHold(s.ctx, walletID, amount("1"), time.Hour)
+	s.ErrorIs(err, repository.ErrWalletClosed)
+
+	results, err := s.repo.ApplyOperations(s.ctx, walletID, []repository.BatchOperation{
+		{Type: repository.OperationDeposit, Amount: amount("1")},
+	})
+	s.Require().NoError(err)
+	s.ErrorIs(results[0], repository.ErrWalletClosed)
+
+	s.requireBalance(walletID, "0", "0")
+	s.requireBalance(otherID, "100", "0")
+}
@@ -0,0 +1,373 @@
+// Package repositorytest holds the behaviour every repository.Repository
+// implementation must share, so that the in-memory and Postgres repositories
+// cannot drift apart.
+package repositorytest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+)
+
+// Suite runs against the repository returned by NewRepository, which is
+// called before every test. Tests only touch wallets they create, so a shared
+// database does not need to be cleaned between them.
+type Suite struct {
+	suite.Suite
+
+	NewRepository func() repository.Repository
+
+	repo repository.Repository
+	ctx  context.Context
+}
+
+func (s *Suite) SetupTest() {
+	if s.NewRepository == nil {
+		s.FailNow("NewRepository is not set")
+	}
+
+	s.repo = s.NewRepository()
+	s.ctx = context.Background()
+}
+
+func (s *Suite) createWallet(balance string) uuid.UUID {
+	walletID := uuid.New()
+	s.Require().NoError(s.repo.Create(s.ctx, walletID, "RUB"))
+	if amount := decimal.RequireFromString(balance); amount.IsPositive() {
+		s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount))
+	}
+	return walletID
+}
+
+func (s *Suite) requireBalance(walletID uuid.UUID, balance, held string) {
+	wallet, err := s.repo.GetByID(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.requireAmount(balance, wallet.Balance, "balance")
+	s.requireAmount(held, wallet.Held, "held")
+}
+
+func (s *Suite) requireAmount(want string, got decimal.Decimal, msgAndArgs ...any) {
+	s.Require().True(decimal.RequireFromString(want).Equal(got), "want %s, got %s: %v", want, got, msgAndArgs)
+}
+
+func (s *Suite) operations(walletID uuid.UUID, filter repository.OperationFilter) []repository.Operation {
+	ops, err := s.repo.ListOperations(s.ctx, walletID, filter)
+	s.Require().NoError(err)
+	return ops
+}
+
+func amount(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func (s *Suite) TestCreateAndGet() {
+	walletID := uuid.New()
+	s.Require().NoError(s.repo.Create(s.ctx, walletID, "USD"))
+
+	wallet, err := s.repo.GetByID(s.ctx, walletID)
+	s.Require().NoError(err)
+	s.Equal(walletID, wallet.ID)
+	s.Equal("USD", wallet.Currency)
+	s.True(wallet.Balance.IsZero())
+	s.True(wallet.Held.IsZero())
+	s.Equal(repository.WalletActive, wallet.Status)
+	s.False(wallet.DepositsBlocked)
+	s.False(wallet.CreatedAt.IsZero())
+}
+
+func (s *Suite) TestCreateDuplicate() {
+	walletID := s.createWallet("0")
+
+	s.Error(s.repo.Create(s.ctx, walletID, "RUB"))
+}
+
+func (s *Suite) TestGetMissingWallet() {
+	_, err := s.repo.GetByID(s.ctx, uuid.New())
+
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestDepositAndWithdraw() {
+	walletID := s.createWallet("100.50")
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("30.25")))
+
+	s.requireBalance(walletID, "70.25", "0")
+	ops := s.operations(walletID, repository.OperationFilter{})
+	s.Require().Len(ops, 2)
+	s.Equal(repository.OperationWithdraw, ops[0].Type)
+	s.requireAmount("30.25", ops[0].Amount)
+	s.requireAmount("70.25", ops[0].BalanceAfter)
+	s.Equal(repository.OperationDeposit, ops[1].Type)
+	s.requireAmount("100.50", ops[1].BalanceAfter)
+}
+
+func (s *Suite) TestWithdrawInsufficientFunds() {
+	walletID := s.createWallet("10")
+
+	err := s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("10.01"))
+
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+	s.requireBalance(walletID, "10", "0")
+	s.Len(s.operations(walletID, repository.OperationFilter{}), 1)
+}
+
+func (s *Suite) TestApplyOperationMissingWallet() {
+	err := s.repo.ApplyOperation(s.ctx, uuid.New(), repository.OperationDeposit, amount("1"))
+
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestApplyOperations() {
+	walletID := s.createWallet("0")
+
+	results, err := s.repo.ApplyOperations(s.ctx, walletID, []repository.BatchOperation{
+		{Type: repository.OperationDeposit, Amount: amount("100")},
+		{Type: repository.OperationWithdraw, Amount: amount("150")},
+		{Type: repository.OperationWithdraw, Amount: amount("60")},
+		{Type: repository.OperationDeposit, Amount: amount("10")},
+	})
+
+	s.Require().NoError(err)
+	s.Require().Len(results, 4)
+	s.NoError(results[0])
+	s.ErrorIs(results[1], repository.ErrInsufficientFunds)
+	s.NoError(results[2])
+	s.NoError(results[3])
+	s.requireBalance(walletID, "50", "0")
+
+	ops := s.operations(walletID, repository.OperationFilter{})
+	s.Require().Len(ops, 3)
+	s.requireAmount("50", ops[0].BalanceAfter)
+	s.requireAmount("40", ops[1].BalanceAfter)
+	s.requireAmount("100", ops[2].BalanceAfter)
+}
+
+func (s *Suite) TestApplyOperationsMissingWallet() {
+	_, err := s.repo.ApplyOperations(s.ctx, uuid.New(), []repository.BatchOperation{
+		{Type: repository.OperationDeposit, Amount: amount("1")},
+	})
+
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestConcurrentDeposits() {
+	const n = 20
+	walletID := s.createWallet("0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("1")))
+		}()
+	}
+	wg.Wait()
+
+	s.requireBalance(walletID, "20", "0")
+	s.Len(s.operations(walletID, repository.OperationFilter{}), n)
+}
+
+func (s *Suite) TestTransfer() {
+	fromID := s.createWallet("100")
+	toID := s.createWallet("5")
+
+	s.Require().NoError(s.repo.Transfer(s.ctx, fromID, toID, amount("40")))
+
+	s.requireBalance(fromID, "60", "0")
+	s.requireBalance(toID, "45", "0")
+
+	out := s.operations(fromID, repository.OperationFilter{Types: []string{repository.OperationTransferOut}})
+	in := s.operations(toID, repository.OperationFilter{Types: []string{repository.OperationTransferIn}})
+	s.Require().Len(out, 1)
+	s.Require().Len(in, 1)
+	s.Require().NotNil(out[0].TransferID)
+	s.Equal(out[0].TransferID, in[0].TransferID)
+	s.requireAmount("60", out[0].BalanceAfter)
+	s.requireAmount("45", in[0].BalanceAfter)
+}
+
+func (s *Suite) TestTransferInsufficientFunds() {
+	fromID := s.createWallet("10")
+	toID := s.createWallet("0")
+
+	err := s.repo.Transfer(s.ctx, fromID, toID, amount("11"))
+
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+	s.requireBalance(fromID, "10", "0")
+	s.requireBalance(toID, "0", "0")
+}
+
+func (s *Suite) TestTransferMissingWallet() {
+	fromID := s.createWallet("10")
+
+	s.ErrorIs(s.repo.Transfer(s.ctx, fromID, uuid.New(), amount("1")), repository.ErrWalletNotFound)
+	s.ErrorIs(s.repo.Transfer(s.ctx, uuid.New(), fromID, amount("1")), repository.ErrWalletNotFound)
+	s.requireBalance(fromID, "10", "0")
+}
+
+func (s *Suite) TestListOperationsFilterAndCursor() {
+	walletID := s.createWallet("0")
+	for _, op := range []struct {
+		typ    string
+		amount string
+	}{
+		{repository.OperationDeposit, "10"},
+		{repository.OperationDeposit, "20"},
+		{repository.OperationWithdraw, "5"},
+		{repository.OperationDeposit, "30"},
+	} {
+		s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, op.typ, amount(op.amount)))
+	}
+
+	deposits := s.operations(walletID, repository.OperationFilter{Types: []string{repository.OperationDeposit}})
+	s.Require().Len(deposits, 3)
+	s.requireAmount("30", deposits[0].Amount)
+	s.requireAmount("10", deposits[2].Amount)
+
+	first := s.operations(walletID, repository.OperationFilter{Limit: 2})
+	s.Require().Len(first, 2)
+	s.requireAmount("30", first[0].Amount)
+	s.requireAmount("5", first[1].Amount)
+
+	last := first[len(first)-1]
+	rest := s.operations(walletID, repository.OperationFilter{
+		After: &repository.OperationCursor{CreatedAt: last.CreatedAt, ID: last.ID},
+	})
+	s.Require().Len(rest, 2)
+	s.requireAmount("20", rest[0].Amount)
+	s.requireAmount("10", rest[1].Amount)
+
+	s.Empty(s.operations(walletID, repository.OperationFilter{From: time.Now().UTC().Add(time.Hour)}))
+	s.Len(s.operations(walletID, repository.OperationFilter{To: time.Now().UTC().Add(time.Hour)}), 4)
+}
+
+func (s *Suite) TestListOperationsOfEmptyAndMissingWallet() {
+	s.Empty(s.operations(s.createWallet("0"), repository.OperationFilter{}))
+
+	_, err := s.repo.ListOperations(s.ctx, uuid.New(), repository.OperationFilter{})
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestBalanceAt() {
+	walletID := s.createWallet("0")
+	balanceAt := func(at time.Time) decimal.Decimal {
+		balance, err := s.repo.BalanceAt(s.ctx, walletID, at)
+		s.Require().NoError(err)
+		return balance
+	}
+
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationDeposit, amount("100")))
+	first := s.lastOperation(walletID)
+	// Operations committed within the same microsecond share created_at.
+	time.Sleep(2 * time.Millisecond)
+	s.Require().NoError(s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("30")))
+	second := s.lastOperation(walletID)
+
+	s.requireAmount("0", balanceAt(first.CreatedAt.Add(-time.Microsecond)), "before first")
+	s.requireAmount("100", balanceAt(first.CreatedAt), "at first")
+	s.requireAmount("100", balanceAt(second.CreatedAt.Add(-time.Microsecond)), "before second")
+	s.requireAmount("70", balanceAt(second.CreatedAt), "at second")
+	s.requireAmount("70", balanceAt(time.Now().UTC().Add(time.Hour)), "now")
+
+	_, err := s.repo.BalanceAt(s.ctx, uuid.New(), time.Now().UTC())
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestHoldCapture() {
+	walletID := s.createWallet("100")
+
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("40"), time.Hour)
+	s.Require().NoError(err)
+	s.Equal(repository.HoldActive, hold.Status)
+	s.Equal(walletID, hold.WalletID)
+	s.requireBalance(walletID, "100", "40")
+
+	// Held funds cannot be withdrawn.
+	err = s.repo.ApplyOperation(s.ctx, walletID, repository.OperationWithdraw, amount("70"))
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("41"))
+	s.ErrorIs(err, repository.ErrCaptureExceedsHold)
+
+	captured, err := s.repo.CaptureHold(s.ctx, hold.ID, amount("30"))
+	s.Require().NoError(err)
+	s.Equal(repository.HoldCaptured, captured.Status)
+	s.requireAmount("30", captured.CapturedAmount)
+	s.requireBalance(walletID, "70", "0")
+
+	ops := s.operations(walletID, repository.OperationFilter{})
+	s.Require().Len(ops, 2)
+	s.Equal(repository.OperationWithdraw, ops[0].Type)
+	s.Require().NotNil(ops[0].HoldID)
+	s.Equal(hold.ID, *ops[0].HoldID)
+	s.requireAmount("70", ops[0].BalanceAfter)
+
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("1"))
+	s.ErrorIs(err, repository.ErrHoldNotActive)
+}
+
+func (s *Suite) TestHoldRelease() {
+	walletID := s.createWallet("100")
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("40"), time.Hour)
+	s.Require().NoError(err)
+
+	released, err := s.repo.ReleaseHold(s.ctx, hold.ID)
+	s.Require().NoError(err)
+	s.Equal(repository.HoldReleased, released.Status)
+	s.requireBalance(walletID, "100", "0")
+	s.Len(s.operations(walletID, repository.OperationFilter{}), 1)
+
+	_, err = s.repo.ReleaseHold(s.ctx, hold.ID)
+	s.ErrorIs(err, repository.ErrHoldNotActive)
+}
+
+func (s *Suite) TestHoldExceedingAvailableBalance() {
+	walletID := s.createWallet("100")
+	_, err := s.repo.CreateHold(s.ctx, walletID, amount("60"), time.Hour)
+	s.Require().NoError(err)
+
+	_, err = s.repo.CreateHold(s.ctx, walletID, amount("41"), time.Hour)
+	s.ErrorIs(err, repository.ErrInsufficientFunds)
+
+	_, err = s.repo.CreateHold(s.ctx, uuid.New(), amount("1"), time.Hour)
+	s.ErrorIs(err, repository.ErrWalletNotFound)
+}
+
+func (s *Suite) TestExpireHolds() {
+	walletID := s.createWallet("100")
+	hold, err := s.repo.CreateHold(s.ctx, walletID, amount("40"), time.Millisecond)
+	s.Require().NoError(err)
+	time.Sleep(10 * time.Millisecond)
+
+	// An expired hold cannot be captured even before the sweeper runs.
+	_, err = s.repo.CaptureHold(s.ctx, hold.ID, amount("10"))
+	s.ErrorIs(err, repository.ErrHoldNotActive)
+
+	expired, err := s.repo.ExpireHolds(s.ctx, 1000)
+	s.Require().NoError(err)
+	s.Positive(expired)
+
+	got, err := s.repo.GetHold(s.ctx, hold.ID)
+	s.Require().NoError(err)
+	s.Equal(repository.HoldExpired, got.Status)
+	s.requireBalance(walletID, "100", "0")
+}
+
+func (s *Suite) TestMissingHold() {
+	_, err := s.repo.GetHold(s.ctx, uuid.New())
+	s.ErrorIs(err, repository.ErrHoldNotFound)
+
+	_, err = s.repo.CaptureHold(s.ctx, uuid.New(), amount("1"))
+	s.ErrorIs(err, repository.ErrHoldNotFound)
+
+	_, err = s.repo.ReleaseHold(s.ctx, uuid.New())
+	s.ErrorIs(err, repository.ErrHoldNotFound)
+}
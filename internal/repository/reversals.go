@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrOperationNotFound       = errors.New("operation not found")
+	ErrOperationNotReversible  = errors.New("operation cannot be reversed")
+	ErrAlreadyReversed         = errors.New("operation is already fully reversed")
+	ErrReversalExceedsOriginal = errors.New("reversal amount exceeds the amount not yet reversed")
+)
+
+// reversalDelta returns the balance change reversing amount of an operation
+// of type opType, or false if such operations cannot be reversed.
+func reversalDelta(opType string, amount decimal.Decimal) (decimal.Decimal, bool) {
+	switch opType {
+	case OperationDeposit, OperationTransferIn:
+		return amount.Neg(), true
+	case OperationWithdraw, OperationTransferOut:
+		return amount, true
+	}
+	return decimal.Zero, false
+}
+
+// checkReversal validates reversing amount of original, of which reversed has
+// already been reversed.
+func checkReversal(original *Operation, reversed, amount decimal.Decimal) error {
+	remaining := original.Amount.Sub(reversed)
+	switch {
+	case !remaining.IsPositive():
+		return ErrAlreadyReversed
+	case amount.GreaterThan(remaining):
+		return ErrReversalExceedsOriginal
+	}
+	return nil
+}
+
+// ReverseOperation records a REVERSAL of amount against an operation of the
+// wallet and applies the opposite of its balance change. Partial reversals
+// may follow each other until the original amount is used up. Reversing
+// either leg of a transfer reverses both legs, linked by a new transfer ID.
+// Reversals are not checked against limit policies. The REVERSAL recorded on
+// walletID is returned.
+func (r *walletRepo) ReverseOperation(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal) (_ *Operation, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReverseOperation", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	var reversal *Operation
+	err = r.withRetry(ctx, walletID, func(ctx context.Context) error {
+		var err error
+		reversal, err = r.executeReverse(ctx, walletID, operationID, amount)
+		return err
+	})
+	return reversal, err
+}
+
+func (r *walletRepo) executeReverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal) (*Operation, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	original, err := getOperation(ctx, tx, squirrel.Eq{"id": operationID, "wallet_id": walletID})
+	if err != nil {
+		return nil, err
+	}
+	delta, ok := reversalDelta(original.Type, amount)
+	if !ok {
+		return nil, ErrOperationNotReversible
+	}
+
+	// The other leg of a transfer is reversed along with this one.
+	originals := []*Operation{original}
+	if original.TransferID != nil {
+		other, err := getOperation(ctx, tx, squirrel.And{
+			squirrel.Eq{"transfer_id": *original.TransferID},
+			squirrel.NotEq{"id": original.ID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		originals = append(originals, other)
+	}
+
+	walletIDs := make([]uuid.UUID, 0, len(originals))
+	for _, op := range originals {
+		walletIDs = append(walletIDs, op.WalletID)
+	}
+	for _, id := range OrderWalletIDs(walletIDs...) {
+		if err = lockWallet(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	reversed, err := reversedAmount(ctx, tx, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkReversal(original, reversed, amount); err != nil {
+		return nil, err
+	}
+
+	var transferID *uuid.UUID
+	if len(originals) > 1 {
+		id := uuid.New()
+		transferID = &id
+	}
+	reversals := make([]*Operation, 0, len(originals))
+	entry := journalEntry{entryType: OperationReversal}
+	for _, op := range originals {
+		opDelta, _ := reversalDelta(op.Type, amount)
+		balance, err := applyDelta(ctx, tx, op.WalletID, opDelta)
+		if err != nil {
+			return nil, err
+		}
+		reversalOf := op.ID
+		reversal := &Operation{
+			WalletID:     op.WalletID,
+			Type:         OperationReversal,
+			Amount:       amount,
+			BalanceAfter: balance,
+			TransferID:   transferID,
+			ReversalOf:   &reversalOf,
+		}
+		reversals = append(reversals, reversal)
+		entry.postings = append(entry.postings, walletPosting(reversal, opDelta))
+	}
+	if len(originals) == 1 {
+		entry = operationEntry(reversals[0], delta, cashAccount(original.Type))
+	}
+
+	if err = insertOperations(ctx, tx, reversals...); err != nil {
+		return nil, err
+	}
+	if err = postEntries(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("operation reversed",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("operation_id", operationID.String()),
+		slog.String("amount", amount.String()),
+		slog.String("delta", delta.String()),
+	)
+
+	return reversals[0], nil
+}
+
+func getOperation(ctx context.Context, tx pgx.Tx, where squirrel.Sqlizer) (*Operation, error) {
+	sql, args, err := squirrel.Select(operationColumns...).
+		From("operations").
+		Where(where).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	op, err := scanOperation(tx.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOperationNotFound
+		}
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+	return op, nil
+}
+
+func reversedAmount(ctx context.Context, tx pgx.Tx, operationID uuid.UUID) (decimal.Decimal, error) {
+	sql, args, err := squirrel.Select("COALESCE(SUM(amount), 0)").
+		From("operations").
+		Where(squirrel.Eq{"reversal_of": operationID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var reversed decimal.Decimal
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&reversed); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum reversals: %w", err)
+	}
+	return reversed, nil
+}
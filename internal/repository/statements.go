@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statementFetchSize is the number of operations fetched from the statement
+// cursor at a time.
+const statementFetchSize = 500
+
+// StatementWriter receives a statement as Statement reads it.
+type StatementWriter interface {
+	// Opening is called once, before any operation, with the balance at the
+	// start of the period.
+	Opening(balance decimal.Decimal) error
+	// Operation is called for every operation of the period, oldest first.
+	Operation(op Operation) error
+}
+
+// Statement passes the operations of a wallet created in [from, to) to w,
+// oldest first, preceded by the balance the wallet had at from. A zero from
+// starts at the first operation. The operations are read from a snapshot
+// through a server-side cursor, so that long histories are never held in
+// memory.
+func (r *walletRepo) Statement(ctx context.Context, walletID uuid.UUID, from, to time.Time, w StatementWriter) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.Statement", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM wallets WHERE id = $1)", walletID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get wallet: %w", err)
+	}
+	if !exists {
+		return ErrWalletNotFound
+	}
+
+	opening := decimal.Zero
+	if !from.IsZero() {
+		sql, args, err := squirrel.Select("balance_after").
+			From("operations").
+			Where(squirrel.Eq{"wallet_id": walletID}).
+			Where(squirrel.Lt{"created_at": from}).
+			OrderBy("created_at DESC", "id DESC").
+			Limit(1).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		err = tx.QueryRow(ctx, sql, args...).Scan(&opening)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get opening balance: %w", err)
+		}
+	}
+	if err = w.Opening(opening); err != nil {
+		return err
+	}
+
+	query := squirrel.Select(operationColumns...).
+		From("operations").
+		Where(squirrel.Eq{"wallet_id": walletID}).
+		Where(squirrel.Lt{"created_at": to}).
+		OrderBy("created_at", "id")
+	if !from.IsZero() {
+		query = query.Where(squirrel.GtOrEq{"created_at": from})
+	}
+	sql, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+	if _, err = tx.Exec(ctx, "DECLARE statement NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		return fmt.Errorf("failed to open statement cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM statement", statementFetchSize)
+	for {
+		n, err := fetchStatement(ctx, tx, fetch, w)
+		if err != nil {
+			return err
+		}
+		if n < statementFetchSize {
+			return nil
+		}
+	}
+}
+
+// fetchStatement passes the next rows of the statement cursor to w and
+// returns how many there were.
+func fetchStatement(ctx context.Context, tx pgx.Tx, fetch string, w StatementWriter) (int, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch operations: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return n, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		if err = w.Operation(*op); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		return n, fmt.Errorf("failed to fetch operations: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrWalletFrozen            = errors.New("wallet is frozen")
+	ErrWalletClosed            = errors.New("wallet is closed")
+	ErrInvalidStatusTransition = errors.New("invalid wallet status transition")
+	ErrWalletNotEmpty          = errors.New("wallet balance must be zero to close it")
+)
+
+const (
+	WalletActive = "ACTIVE"
+	WalletFrozen = "FROZEN"
+	WalletClosed = "CLOSED"
+)
+
+// StatusUpdate asks to move a wallet to Status. DepositsBlocked only applies
+// to WalletFrozen: a frozen wallet always rejects outgoing money and rejects
+// deposits too when it is set.
+type StatusUpdate struct {
+	Status          string
+	DepositsBlocked bool
+	Reason          string
+	Actor           string
+}
+
+// StatusChange is the audit record of a wallet status transition.
+type StatusChange struct {
+	ID              uuid.UUID
+	WalletID        uuid.UUID
+	FromStatus      string
+	ToStatus        string
+	DepositsBlocked bool
+	Reason          string
+	Actor           string
+	CreatedAt       time.Time
+}
+
+// checkTransition validates a move from the current state of a wallet.
+// ACTIVE and FROZEN wallets can move to each other or to CLOSED, a frozen
+// wallet can change whether deposits are blocked, and CLOSED is final. Only
+// an empty wallet can be closed.
+func checkTransition(status string, depositsBlocked bool, balance decimal.Decimal, update StatusUpdate) error {
+	switch {
+	case status == WalletClosed:
+		return ErrInvalidStatusTransition
+	case status == update.Status && (status != WalletFrozen || depositsBlocked == update.DepositsBlocked):
+		return ErrInvalidStatusTransition
+	case update.Status == WalletClosed && !balance.IsZero():
+		return ErrWalletNotEmpty
+	}
+	return nil
+}
+
+// statusError reports whether a wallet in the given state accepts money
+// coming in (credit) or going out.
+func statusError(status string, depositsBlocked, credit bool) error {
+	switch status {
+	case WalletClosed:
+		return ErrWalletClosed
+	case WalletFrozen:
+		if !credit || depositsBlocked {
+			return ErrWalletFrozen
+		}
+	}
+	return nil
+}
+
+// acceptsDelta restricts a wallets update to rows whose status lets the
+// balance move in the direction of delta. It must match statusError.
+func acceptsDelta(delta decimal.Decimal) squirrel.Sqlizer {
+	if delta.IsPositive() {
+		return squirrel.Or{
+			squirrel.Eq{"status": WalletActive},
+			squirrel.Eq{"status": WalletFrozen, "deposits_blocked": false},
+		}
+	}
+	return squirrel.Eq{"status": WalletActive}
+}
+
+// rejectedDelta explains why a wallets update guarded by acceptsDelta and the
+// held amount matched no row.
+func rejectedDelta(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, credit bool) error {
+	selectSQL, selectArgs, err := squirrel.Select("status", "deposits_blocked").
+		From("wallets").
+		Where(squirrel.Eq{"id": walletID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var (
+		status          string
+		depositsBlocked bool
+	)
+	if err = tx.QueryRow(ctx, selectSQL, selectArgs...).Scan(&status, &depositsBlocked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWalletNotFound
+		}
+		return fmt.Errorf("failed to get wallet status: %w", err)
+	}
+	if err = statusError(status, depositsBlocked, credit); err != nil {
+		return err
+	}
+	return ErrInsufficientFunds
+}
+
+// SetStatus moves a wallet to another status and records the change with its
+// reason and actor in wallet_status_changes.
+func (r *walletRepo) SetStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (_ *StatusChange, err error) {
+	ctx, span := tracer.Start(ctx, "repository.SetStatus", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	var change *StatusChange
+	err = r.withRetry(ctx, walletID, func(ctx context.Context) error {
+		var err error
+		change, err = r.executeSetStatus(ctx, walletID, update)
+		return err
+	})
+	return change, err
+}
+
+func (r *walletRepo) executeSetStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockWallet(ctx, tx, walletID); err != nil {
+		return nil, err
+	}
+
+	selectSQL, selectArgs, err := squirrel.Select("status", "deposits_blocked", "balance").
+		From("wallets").
+		Where(squirrel.Eq{"id": walletID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var (
+		status          string
+		depositsBlocked bool
+		balance         decimal.Decimal
+	)
+	if err = tx.QueryRow(ctx, selectSQL, selectArgs...).Scan(&status, &depositsBlocked, &balance); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	if err = checkTransition(status, depositsBlocked, balance, update); err != nil {
+		return nil, err
+	}
+
+	updateSQL, updateArgs, err := squirrel.Update("wallets").
+		Set("status", update.Status).
+		Set("deposits_blocked", update.DepositsBlocked).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": walletID}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+	if _, err = tx.Exec(ctx, updateSQL, updateArgs...); err != nil {
+		return nil, fmt.Errorf("failed to update wallet status: %w", err)
+	}
+
+	change := &StatusChange{
+		WalletID:        walletID,
+		FromStatus:      status,
+		ToStatus:        update.Status,
+		DepositsBlocked: update.DepositsBlocked,
+		Reason:          update.Reason,
+		Actor:           update.Actor,
+	}
+	insertSQL, insertArgs, err := squirrel.Insert("wallet_status_changes").
+		Columns("wallet_id", "from_status", "to_status", "deposits_blocked", "reason", "actor").
+		Values(change.WalletID, change.FromStatus, change.ToStatus, change.DepositsBlocked, change.Reason, change.Actor).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build insert SQL: %w", err)
+	}
+	if err = tx.QueryRow(ctx, insertSQL, insertArgs...).Scan(&change.ID, &change.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to insert status change: %w", err)
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("wallet status changed",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("from_status", status),
+		slog.String("to_status", update.Status),
+		slog.Bool("deposits_blocked", update.DepositsBlocked),
+	)
+
+	return change, nil
+}
@@ -1,72 +1,149 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
+	"sort"
 	"time"
 
+	"ITK/internal/metrics"
+	"ITK/internal/tracing"
+	"ITK/pkg/postgres"
+	"ITK/pkg/retry"
+
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("ITK/internal/repository")
+
 var (
 	ErrWalletNotFound    = errors.New("wallet not found")
 	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrTooManyRetries    = errors.New("too many retries")
+	ErrTooManyRetries    = retry.ErrExhausted
+
+	// ErrCommitUnknown marks a COMMIT that lost its connection: the
+	// transaction may or may not have been applied, so neither the repository
+	// nor its callers may retry it blindly.
+	ErrCommitUnknown = errors.New("commit outcome unknown")
+)
+
+// TooManyRetriesError carries the number of attempts and the last failure
+// of a transaction that ran out of retries. It matches ErrTooManyRetries.
+type TooManyRetriesError = retry.ExhaustedError
+
+const (
+	OperationDeposit     = "DEPOSIT"
+	OperationWithdraw    = "WITHDRAW"
+	OperationTransferIn  = "TRANSFER_IN"
+	OperationTransferOut = "TRANSFER_OUT"
+	OperationReversal    = "REVERSAL"
 )
 
 type Wallet struct {
-	ID        uuid.UUID
-	Balance   decimal.Decimal
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID              uuid.UUID
+	Currency        string
+	Balance         decimal.Decimal
+	Held            decimal.Decimal
+	Status          string
+	DepositsBlocked bool
+	Tier            string
+	LimitPolicy     string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 //go:generate go run go.uber.org/mock/mockgen@latest -destination=wallet_mock.go -source=wallet.go -package=repository
 type Repository interface {
-	Create(ctx context.Context, walletID uuid.UUID) error
+	Create(ctx context.Context, walletID uuid.UUID, currency string) error
 	GetByID(ctx context.Context, walletID uuid.UUID) (*Wallet, error)
 	ApplyOperation(ctx context.Context, walletID uuid.UUID, opType string, amount decimal.Decimal) error
+	ApplyOperations(ctx context.Context, walletID uuid.UUID, ops []BatchOperation) ([]error, error)
+	Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal) error
+	ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) ([]Operation, error)
+	BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (decimal.Decimal, error)
+	Statement(ctx context.Context, walletID uuid.UUID, from, to time.Time, w StatementWriter) error
+	CreateHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error)
+	GetHold(ctx context.Context, holdID uuid.UUID) (*Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*Hold, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) (*Hold, error)
+	ExpireHolds(ctx context.Context, limit int) (int, error)
+	SetStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error)
+	PutLimitPolicy(ctx context.Context, policy LimitPolicy) (*LimitPolicy, error)
+	ListLimitPolicies(ctx context.Context) ([]LimitPolicy, error)
+	SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) error
+	ReverseOperation(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal) (*Operation, error)
+	TrialBalance(ctx context.Context) (*TrialBalance, error)
+	Reconcile(ctx context.Context, walletID uuid.UUID) (*Reconciliation, error)
+	ListWalletIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+}
+
+// BatchOperation is one deposit or withdrawal applied by ApplyOperations.
+type BatchOperation struct {
+	Type   string
+	Amount decimal.Decimal
 }
 
 type walletRepo struct {
-	pool         *pgxpool.Pool
-	log          *slog.Logger
-	maxRetries   int
-	baseDelayMS  int
+	pool  *pgxpool.Pool
+	log   *slog.Logger
+	retry retry.Policy
 }
 
 type Config struct {
-	MaxRetries  int
-	BaseDelayMS int
+	// Retry reruns transactions that failed with a retryable error. A nil
+	// Retryable defaults to postgres.Retryable(nil).
+	Retry retry.Policy
 }
 
 func New(pool *pgxpool.Pool, log *slog.Logger, cfg Config) Repository {
+	policy := cfg.Retry
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = postgres.Retryable(nil)
+	}
+	policy.Retryable = func(err error) bool {
+		return !errors.Is(err, ErrCommitUnknown) && retryable(err)
+	}
+
 	return &walletRepo{
-		pool:        pool,
-		log:         log.With(slog.String("component", "repository/wallet")),
-		maxRetries:  cfg.MaxRetries,
-		baseDelayMS: cfg.BaseDelayMS,
+		pool:  pool,
+		log:   log.With(slog.String("component", "repository/wallet")),
+		retry: policy,
 	}
 }
 
-func (r *walletRepo) Create(ctx context.Context, walletID uuid.UUID) error {
+func (r *walletRepo) Create(ctx context.Context, walletID uuid.UUID, currency string) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.Create", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	sql, args, err := squirrel.Insert("wallets").
-		Columns("id", "balance", "created_at", "updated_at").
-		Values(walletID, 0, squirrel.Expr("NOW()"), squirrel.Expr("NOW()")).
+		Columns("id", "currency", "balance", "created_at", "updated_at").
+		Values(walletID, currency, 0, squirrel.Expr("NOW()"), squirrel.Expr("NOW()")).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
 		return fmt.Errorf("failed to build SQL query: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx, sql, args...)
+	_, err = tx.Exec(ctx, sql, args...)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -76,12 +153,38 @@ func (r *walletRepo) Create(ctx context.Context, walletID uuid.UUID) error {
 		return fmt.Errorf("failed to create wallet: %w", err)
 	}
 
-	r.log.Debug("wallet created", slog.String("wallet_id", walletID.String()))
+	// The wallet's ledger account shares its ID.
+	accountSQL, accountArgs, err := squirrel.Insert("ledger_accounts").
+		Columns("id", "account_type", "currency").
+		Values(walletID, "WALLET", currency).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL query: %w", err)
+	}
+	if _, err = tx.Exec(ctx, accountSQL, accountArgs...); err != nil {
+		return fmt.Errorf("failed to create ledger account: %w", err)
+	}
+	if err = ensureSystemAccounts(ctx, tx, currency); err != nil {
+		return err
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return err
+	}
+
+	r.log.Debug("wallet created", slog.String("wallet_id", walletID.String()), slog.String("currency", currency))
 	return nil
 }
 
-func (r *walletRepo) GetByID(ctx context.Context, walletID uuid.UUID) (*Wallet, error) {
-	sql, args, err := squirrel.Select("id", "balance", "created_at", "updated_at").
+func (r *walletRepo) GetByID(ctx context.Context, walletID uuid.UUID) (_ *Wallet, err error) {
+	ctx, span := tracer.Start(ctx, "repository.GetByID", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select(
+		"id", "currency", "balance", "held", "status", "deposits_blocked",
+		"COALESCE(tier, '')", "COALESCE(limit_policy, '')", "created_at", "updated_at",
+	).
 		From("wallets").
 		Where(squirrel.Eq{"id": walletID}).
 		PlaceholderFormat(squirrel.Dollar).
@@ -91,7 +194,10 @@ func (r *walletRepo) GetByID(ctx context.Context, walletID uuid.UUID) (*Wallet,
 	}
 
 	var w Wallet
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&w.ID, &w.Balance, &w.CreatedAt, &w.UpdatedAt)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&w.ID, &w.Currency, &w.Balance, &w.Held, &w.Status, &w.DepositsBlocked,
+		&w.Tier, &w.LimitPolicy, &w.CreatedAt, &w.UpdatedAt,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrWalletNotFound
@@ -103,27 +209,99 @@ func (r *walletRepo) GetByID(ctx context.Context, walletID uuid.UUID) (*Wallet,
 	return &w, nil
 }
 
-func (r *walletRepo) ApplyOperation(ctx context.Context, walletID uuid.UUID, opType string, amount decimal.Decimal) error {
-	for attempt := 0; attempt < r.maxRetries; attempt++ {
-		err := r.executeOperation(ctx, walletID, opType, amount)
-		if err == nil {
-			return nil
-		}
+func (r *walletRepo) ApplyOperation(ctx context.Context, walletID uuid.UUID, opType string, amount decimal.Decimal) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.ApplyOperation", trace.WithAttributes(
+		tracing.WalletID(walletID),
+		tracing.OperationType(opType),
+	))
+	defer func() { tracing.End(span, err) }()
 
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "40001" {
-			backoff := time.Duration(1<<attempt) * time.Duration(r.baseDelayMS) * time.Millisecond
-			r.log.Warn("serialization failure, retrying",
-				slog.String("wallet_id", walletID.String()),
-				slog.Int("attempt", attempt+1),
-				slog.Duration("backoff", backoff),
-			)
-			time.Sleep(backoff)
-			continue
-		}
+	return r.withRetry(ctx, walletID, func(ctx context.Context) error {
+		return r.executeOperation(ctx, walletID, opType, amount)
+	})
+}
+
+// ApplyOperations applies ops to a wallet in order within a single
+// transaction. An operation that would leave less than the held amount, that
+// the wallet status forbids or that breaks a limit is skipped with its error
+// in its slot of the returned slice and does not affect the operations after
+// it; every other slot is nil. The returned error is set when nothing was
+// applied.
+func (r *walletRepo) ApplyOperations(ctx context.Context, walletID uuid.UUID, ops []BatchOperation) (results []error, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ApplyOperations", trace.WithAttributes(
+		tracing.WalletID(walletID),
+		attribute.Int("batch.size", len(ops)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	err = r.withRetry(ctx, walletID, func(ctx context.Context) error {
+		var err error
+		results, err = r.executeOperations(ctx, walletID, ops)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Transfer moves amount from one wallet to another in a single transaction.
+// Both legs are recorded in operations under a shared transfer_id.
+func (r *walletRepo) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.Transfer", trace.WithAttributes(
+		tracing.FromWalletID(fromID),
+		tracing.ToWalletID(toID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return r.withRetry(ctx, fromID, func(ctx context.Context) error {
+		return r.executeTransfer(ctx, fromID, toID, amount)
+	})
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error, or
+// the retry policy gives up. Every attempt gets its own span so queueing on a
+// hot wallet shows up in traces.
+func (r *walletRepo) withRetry(ctx context.Context, walletID uuid.UUID, fn func(ctx context.Context) error) error {
+	policy := r.retry
+	policy.OnRetry = func(attempt int, delay time.Duration, err error) {
+		r.log.Warn("transaction failed, retrying",
+			slog.String("wallet_id", walletID.String()),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", delay),
+			slog.String("error", err.Error()),
+		)
+		metrics.SerializationRetries.Inc()
+		metrics.SerializationBackoff.Add(delay.Seconds())
+	}
+
+	err := policy.Do(ctx, func(ctx context.Context, attempt int) error {
+		ctx, span := tracer.Start(ctx, "repository.attempt", trace.WithAttributes(
+			attribute.Int("retry.attempt", attempt),
+		))
+		err := fn(ctx)
+		tracing.End(span, err)
 		return err
+	})
+	if errors.Is(err, ErrTooManyRetries) {
+		metrics.SerializationRetriesExhausted.Inc()
 	}
-	return ErrTooManyRetries
+	return err
+}
+
+// commit commits tx. A commit that failed without the server answering is
+// marked with ErrCommitUnknown so that withRetry does not apply it twice.
+func commit(ctx context.Context, tx pgx.Tx) error {
+	err := tx.Commit(ctx)
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) && !pgconn.SafeToRetry(err) {
+		return fmt.Errorf("failed to commit transaction: %w: %w", ErrCommitUnknown, err)
+	}
+	return fmt.Errorf("failed to commit transaction: %w", err)
 }
 
 func (r *walletRepo) executeOperation(ctx context.Context, walletID uuid.UUID, opType string, amount decimal.Decimal) error {
@@ -133,94 +311,284 @@ func (r *walletRepo) executeOperation(ctx context.Context, walletID uuid.UUID, o
 	}
 	defer tx.Rollback(ctx)
 
-	lockKey := uuidToInt64(walletID)
-	lockSQL, lockArgs, err := squirrel.Select("pg_advisory_xact_lock(?)").
-		PlaceholderFormat(squirrel.Dollar).
-		ToSql()
+	if err = lockWallet(ctx, tx, walletID); err != nil {
+		return err
+	}
+
+	delta := amount
+	if opType == OperationWithdraw {
+		delta = amount.Neg()
+	}
+
+	newBalance, err := applyDelta(ctx, tx, walletID, delta)
 	if err != nil {
-		return fmt.Errorf("failed to build lock SQL: %w", err)
+		return err
+	}
+	if err = enforceLimits(ctx, tx, walletID, delta, newBalance); err != nil {
+		return err
+	}
+
+	op := &Operation{
+		WalletID:     walletID,
+		Type:         opType,
+		Amount:       amount,
+		BalanceAfter: newBalance,
+	}
+	if err = insertOperations(ctx, tx, op); err != nil {
+		return err
+	}
+	if err = postEntries(ctx, tx, operationEntry(op, delta, cashAccount(opType))); err != nil {
+		return err
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return err
 	}
-	_, err = tx.Exec(ctx, lockSQL, append(lockArgs, lockKey)...)
+
+	r.log.Debug("operation applied",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("operation_type", opType),
+		slog.String("amount", amount.String()),
+		slog.String("new_balance", newBalance.String()),
+	)
+
+	return nil
+}
+
+func (r *walletRepo) executeOperations(ctx context.Context, walletID uuid.UUID, ops []BatchOperation) ([]error, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
 	if err != nil {
-		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	delta := amount
-	if opType == "WITHDRAW" {
-		delta = amount.Neg()
+	if err = lockWallet(ctx, tx, walletID); err != nil {
+		return nil, err
 	}
 
-	updateSQL, updateArgs, err := squirrel.Update("wallets").
-		Set("balance", squirrel.Expr("balance + ?", delta)).
-		Set("updated_at", squirrel.Expr("NOW()")).
-		Where("id = ? AND (balance + ?) >= 0", walletID, delta).
-		Suffix("RETURNING balance").
+	selectSQL, selectArgs, err := squirrel.Select("balance", "held", "status", "deposits_blocked").
+		From("wallets").
+		Where(squirrel.Eq{"id": walletID}).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to build update SQL: %w", err)
+		return nil, fmt.Errorf("failed to build select SQL: %w", err)
 	}
 
-	var newBalance decimal.Decimal
-	err = tx.QueryRow(ctx, updateSQL, updateArgs...).Scan(&newBalance)
+	var (
+		balance, held   decimal.Decimal
+		status          string
+		depositsBlocked bool
+	)
+	if err = tx.QueryRow(ctx, selectSQL, selectArgs...).Scan(&balance, &held, &status, &depositsBlocked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
 
+	limits, err := loadLimits(ctx, tx, walletID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			checkSQL, _, buildErr := squirrel.Select("EXISTS(SELECT 1 FROM wallets WHERE id = ?)").
-				PlaceholderFormat(squirrel.Dollar).
-				ToSql()
-			if buildErr != nil {
-				return fmt.Errorf("failed to build check SQL: %w", buildErr)
-			}
+		return nil, err
+	}
 
-			var exists bool
-			checkErr := tx.QueryRow(ctx, checkSQL, walletID).Scan(&exists)
-			if checkErr != nil {
-				return fmt.Errorf("failed to check wallet existence: %w", checkErr)
-			}
-			if !exists {
-				return ErrWalletNotFound
+	results := make([]error, len(ops))
+	applied := make([]*Operation, 0, len(ops))
+	for i, op := range ops {
+		if err := statusError(status, depositsBlocked, op.Type != OperationWithdraw); err != nil {
+			results[i] = err
+			continue
+		}
+		delta := op.Amount
+		if op.Type == OperationWithdraw {
+			delta = op.Amount.Neg()
+		}
+		next := balance.Add(delta)
+		if next.LessThan(held) {
+			results[i] = ErrInsufficientFunds
+			continue
+		}
+		if err := limits.check(delta, next); err != nil {
+			results[i] = err
+			continue
+		}
+		balance = next
+		applied = append(applied, &Operation{
+			WalletID:     walletID,
+			Type:         op.Type,
+			Amount:       op.Amount,
+			BalanceAfter: balance,
+		})
+	}
+
+	if len(applied) > 0 {
+		updateSQL, updateArgs, err := squirrel.Update("wallets").
+			Set("balance", balance).
+			Set("updated_at", squirrel.Expr("NOW()")).
+			Where(squirrel.Eq{"id": walletID}).
+			PlaceholderFormat(squirrel.Dollar).
+			ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build update SQL: %w", err)
+		}
+		if _, err = tx.Exec(ctx, updateSQL, updateArgs...); err != nil {
+			return nil, fmt.Errorf("failed to update balance: %w", err)
+		}
+
+		if err = insertOperations(ctx, tx, applied...); err != nil {
+			return nil, err
+		}
+
+		entries := make([]journalEntry, 0, len(applied))
+		for _, op := range applied {
+			delta := op.Amount
+			if op.Type == OperationWithdraw {
+				delta = op.Amount.Neg()
 			}
-			return ErrInsufficientFunds
+			entries = append(entries, operationEntry(op, delta, cashAccount(op.Type)))
+		}
+		if err = postEntries(ctx, tx, entries...); err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("failed to update balance: %w", err)
 	}
 
-	insertSQL, insertArgs, err := squirrel.Insert("operations").
-		Columns("wallet_id", "operation_type", "amount", "balance_after").
-		Values(walletID, opType, amount, newBalance).
-		PlaceholderFormat(squirrel.Dollar).
-		ToSql()
+	if err = commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	r.log.Debug("operations applied",
+		slog.String("wallet_id", walletID.String()),
+		slog.Int("applied", len(applied)),
+		slog.Int("rejected", len(ops)-len(applied)),
+		slog.String("new_balance", balance.String()),
+	)
+
+	return results, nil
+}
+
+func (r *walletRepo) executeTransfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
 	if err != nil {
-		return fmt.Errorf("failed to build insert SQL: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locks are always taken in the same order so that concurrent A->B and
+	// B->A transfers cannot deadlock on each other.
+	for _, id := range OrderWalletIDs(fromID, toID) {
+		if err = lockWallet(ctx, tx, id); err != nil {
+			return err
+		}
 	}
 
-	_, err = tx.Exec(ctx, insertSQL, insertArgs...)
+	fromBalance, err := applyDelta(ctx, tx, fromID, amount.Neg())
 	if err != nil {
-		return fmt.Errorf("failed to insert operation record: %w", err)
+		return err
+	}
+	if err = enforceLimits(ctx, tx, fromID, amount.Neg(), fromBalance); err != nil {
+		return err
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	toBalance, err := applyDelta(ctx, tx, toID, amount)
+	if err != nil {
+		return err
+	}
+	if err = enforceLimits(ctx, tx, toID, amount, toBalance); err != nil {
+		return err
 	}
 
-	r.log.Debug("operation applied",
-		slog.String("wallet_id", walletID.String()),
-		slog.String("operation_type", opType),
+	transferID := uuid.New()
+	out := &Operation{WalletID: fromID, Type: OperationTransferOut, Amount: amount, BalanceAfter: fromBalance, TransferID: &transferID}
+	in := &Operation{WalletID: toID, Type: OperationTransferIn, Amount: amount, BalanceAfter: toBalance, TransferID: &transferID}
+	if err = insertOperations(ctx, tx, out, in); err != nil {
+		return err
+	}
+	if err = postEntries(ctx, tx, transferEntry(out, in, amount)); err != nil {
+		return err
+	}
+
+	if err = commit(ctx, tx); err != nil {
+		return err
+	}
+
+	r.log.Debug("transfer applied",
+		slog.String("transfer_id", transferID.String()),
+		slog.String("from_wallet_id", fromID.String()),
+		slog.String("to_wallet_id", toID.String()),
 		slog.String("amount", amount.String()),
-		slog.String("new_balance", newBalance.String()),
 	)
 
 	return nil
 }
 
-func uuidToInt64(u uuid.UUID) int64 {
-	bytes := [16]byte(u)
-	var result int64
-	for i := 0; i < 8; i++ {
-		result ^= int64(bytes[i]) << (i * 8)
-		result ^= int64(bytes[i+8]) << (i * 8)
+func lockWallet(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) error {
+	lockSQL, lockArgs, err := squirrel.Select("pg_advisory_xact_lock(?)").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build lock SQL: %w", err)
+	}
+	_, err = tx.Exec(ctx, lockSQL, append(lockArgs, WalletLockKey(walletID))...)
+	if err != nil {
+		return fmt.Errorf("failed to acquire advisory lock: %w", err)
 	}
-	return result
+	return nil
 }
 
+// applyDelta adds delta to the wallet balance and returns the new balance.
+// It fails with ErrInsufficientFunds if the balance would drop below the
+// amount reserved by active holds, and with ErrWalletFrozen or
+// ErrWalletClosed if the wallet status does not allow the movement.
+func applyDelta(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, delta decimal.Decimal) (decimal.Decimal, error) {
+	updateSQL, updateArgs, err := squirrel.Update("wallets").
+		Set("balance", squirrel.Expr("balance + ?", delta)).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where("id = ? AND (balance + ?) >= held", walletID, delta).
+		Where(acceptsDelta(delta)).
+		Suffix("RETURNING balance").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	var newBalance decimal.Decimal
+	err = tx.QueryRow(ctx, updateSQL, updateArgs...).Scan(&newBalance)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return decimal.Zero, rejectedDelta(ctx, tx, walletID, delta.IsPositive())
+		}
+		return decimal.Zero, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	return newBalance, nil
+}
+
+// OrderWalletIDs returns the given wallet IDs sorted by their byte
+// representation. Every lock acquisition over several wallets must follow
+// this order.
+func OrderWalletIDs(ids ...uuid.UUID) []uuid.UUID {
+	ordered := make([]uuid.UUID, len(ids))
+	copy(ordered, ids)
+	sort.Slice(ordered, func(i, j int) bool {
+		return bytes.Compare(ordered[i][:], ordered[j][:]) < 0
+	})
+	return ordered
+}
+
+// WalletLockClass namespaces wallet locks in the single bigint advisory
+// lock space: it is the top byte of every key returned by WalletLockKey.
+// Other subsystems taking bigint advisory locks must pick another class, as
+// pkgsync.PGLocker does with pkgsync.DefaultLockClass.
+const WalletLockClass = 0x57 // 'W'
+
+// WalletLockKey returns the pg_advisory_xact_lock key of a wallet: the lock
+// class followed by 56 bits of the FNV-1a hash of the UUID. Unlike folding
+// the UUID halves together, hashing leaves no structured collisions between
+// wallets; random ones need hundreds of millions of wallets to become likely.
+func WalletLockKey(walletID uuid.UUID) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write(walletID[:])
+	return int64(WalletLockClass<<56 | h.Sum64()&(1<<56-1))
+}
@@ -12,6 +12,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	decimal "github.com/shopspring/decimal"
@@ -56,18 +57,93 @@ func (mr *MockRepositoryMockRecorder) ApplyOperation(ctx, walletID, opType, amou
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyOperation", reflect.TypeOf((*MockRepository)(nil).ApplyOperation), ctx, walletID, opType, amount)
 }
 
+// ApplyOperations mocks base method.
+func (m *MockRepository) ApplyOperations(ctx context.Context, walletID uuid.UUID, ops []BatchOperation) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyOperations", ctx, walletID, ops)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyOperations indicates an expected call of ApplyOperations.
+func (mr *MockRepositoryMockRecorder) ApplyOperations(ctx, walletID, ops any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyOperations", reflect.TypeOf((*MockRepository)(nil).ApplyOperations), ctx, walletID, ops)
+}
+
+// BalanceAt mocks base method.
+func (m *MockRepository) BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (decimal.Decimal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BalanceAt", ctx, walletID, at)
+	ret0, _ := ret[0].(decimal.Decimal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceAt indicates an expected call of BalanceAt.
+func (mr *MockRepositoryMockRecorder) BalanceAt(ctx, walletID, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceAt", reflect.TypeOf((*MockRepository)(nil).BalanceAt), ctx, walletID, at)
+}
+
+// CaptureHold mocks base method.
+func (m *MockRepository) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, holdID, amount)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockRepositoryMockRecorder) CaptureHold(ctx, holdID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockRepository)(nil).CaptureHold), ctx, holdID, amount)
+}
+
 // Create mocks base method.
-func (m *MockRepository) Create(ctx context.Context, walletID uuid.UUID) error {
+func (m *MockRepository) Create(ctx context.Context, walletID uuid.UUID, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, walletID)
+	ret := m.ctrl.Call(m, "Create", ctx, walletID, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(ctx, walletID any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, walletID, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, walletID, currency)
+}
+
+// CreateHold mocks base method.
+func (m *MockRepository) CreateHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, walletID, amount, ttl)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockRepositoryMockRecorder) CreateHold(ctx, walletID, amount, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockRepository)(nil).CreateHold), ctx, walletID, amount, ttl)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockRepository) ExpireHolds(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockRepositoryMockRecorder) ExpireHolds(ctx, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, walletID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockRepository)(nil).ExpireHolds), ctx, limit)
 }
 
 // GetByID mocks base method.
@@ -84,3 +160,195 @@ func (mr *MockRepositoryMockRecorder) GetByID(ctx, walletID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, walletID)
 }
+
+// GetHold mocks base method.
+func (m *MockRepository) GetHold(ctx context.Context, holdID uuid.UUID) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockRepositoryMockRecorder) GetHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockRepository)(nil).GetHold), ctx, holdID)
+}
+
+// ListLimitPolicies mocks base method.
+func (m *MockRepository) ListLimitPolicies(ctx context.Context) ([]LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLimitPolicies", ctx)
+	ret0, _ := ret[0].([]LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLimitPolicies indicates an expected call of ListLimitPolicies.
+func (mr *MockRepositoryMockRecorder) ListLimitPolicies(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLimitPolicies", reflect.TypeOf((*MockRepository)(nil).ListLimitPolicies), ctx)
+}
+
+// ListOperations mocks base method.
+func (m *MockRepository) ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) ([]Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOperations", ctx, walletID, filter)
+	ret0, _ := ret[0].([]Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOperations indicates an expected call of ListOperations.
+func (mr *MockRepositoryMockRecorder) ListOperations(ctx, walletID, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOperations", reflect.TypeOf((*MockRepository)(nil).ListOperations), ctx, walletID, filter)
+}
+
+// ListWalletIDs mocks base method.
+func (m *MockRepository) ListWalletIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWalletIDs", ctx, after, limit)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWalletIDs indicates an expected call of ListWalletIDs.
+func (mr *MockRepositoryMockRecorder) ListWalletIDs(ctx, after, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWalletIDs", reflect.TypeOf((*MockRepository)(nil).ListWalletIDs), ctx, after, limit)
+}
+
+// PutLimitPolicy mocks base method.
+func (m *MockRepository) PutLimitPolicy(ctx context.Context, policy LimitPolicy) (*LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutLimitPolicy", ctx, policy)
+	ret0, _ := ret[0].(*LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutLimitPolicy indicates an expected call of PutLimitPolicy.
+func (mr *MockRepositoryMockRecorder) PutLimitPolicy(ctx, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutLimitPolicy", reflect.TypeOf((*MockRepository)(nil).PutLimitPolicy), ctx, policy)
+}
+
+// Reconcile mocks base method.
+func (m *MockRepository) Reconcile(ctx context.Context, walletID uuid.UUID) (*Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile", ctx, walletID)
+	ret0, _ := ret[0].(*Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockRepositoryMockRecorder) Reconcile(ctx, walletID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockRepository)(nil).Reconcile), ctx, walletID)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockRepository) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockRepositoryMockRecorder) ReleaseHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockRepository)(nil).ReleaseHold), ctx, holdID)
+}
+
+// ReverseOperation mocks base method.
+func (m *MockRepository) ReverseOperation(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal) (*Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseOperation", ctx, walletID, operationID, amount)
+	ret0, _ := ret[0].(*Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseOperation indicates an expected call of ReverseOperation.
+func (mr *MockRepositoryMockRecorder) ReverseOperation(ctx, walletID, operationID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseOperation", reflect.TypeOf((*MockRepository)(nil).ReverseOperation), ctx, walletID, operationID, amount)
+}
+
+// SetStatus mocks base method.
+func (m *MockRepository) SetStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStatus", ctx, walletID, update)
+	ret0, _ := ret[0].(*StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetStatus indicates an expected call of SetStatus.
+func (mr *MockRepositoryMockRecorder) SetStatus(ctx, walletID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockRepository)(nil).SetStatus), ctx, walletID, update)
+}
+
+// SetWalletLimits mocks base method.
+func (m *MockRepository) SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWalletLimits", ctx, walletID, limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWalletLimits indicates an expected call of SetWalletLimits.
+func (mr *MockRepositoryMockRecorder) SetWalletLimits(ctx, walletID, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWalletLimits", reflect.TypeOf((*MockRepository)(nil).SetWalletLimits), ctx, walletID, limits)
+}
+
+// Statement mocks base method.
+func (m *MockRepository) Statement(ctx context.Context, walletID uuid.UUID, from, to time.Time, w StatementWriter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Statement", ctx, walletID, from, to, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Statement indicates an expected call of Statement.
+func (mr *MockRepositoryMockRecorder) Statement(ctx, walletID, from, to, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Statement", reflect.TypeOf((*MockRepository)(nil).Statement), ctx, walletID, from, to, w)
+}
+
+// Transfer mocks base method.
+func (m *MockRepository) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, fromID, toID, amount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockRepositoryMockRecorder) Transfer(ctx, fromID, toID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockRepository)(nil).Transfer), ctx, fromID, toID, amount)
+}
+
+// TrialBalance mocks base method.
+func (m *MockRepository) TrialBalance(ctx context.Context) (*TrialBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrialBalance", ctx)
+	ret0, _ := ret[0].(*TrialBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrialBalance indicates an expected call of TrialBalance.
+func (mr *MockRepositoryMockRecorder) TrialBalance(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrialBalance", reflect.TypeOf((*MockRepository)(nil).TrialBalance), ctx)
+}
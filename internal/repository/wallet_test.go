@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockKeySuite struct {
+	suite.Suite
+}
+
+func TestLockKey(t *testing.T) {
+	suite.Run(t, &LockKeySuite{})
+}
+
+// xorFold is the key derivation used before WalletLockKey: it XORs the two
+// halves of the UUID together.
+func xorFold(u uuid.UUID) int64 {
+	var result int64
+	for i := 0; i < 8; i++ {
+		result ^= int64(u[i]) << (i * 8)
+		result ^= int64(u[i+8]) << (i * 8)
+	}
+	return result
+}
+
+// swapHalves returns a different wallet ID with the same XOR of halves.
+func swapHalves(u uuid.UUID) uuid.UUID {
+	var swapped uuid.UUID
+	copy(swapped[:8], u[8:])
+	copy(swapped[8:], u[:8])
+	return swapped
+}
+
+func (s *LockKeySuite) TestXorFoldCollides() {
+	a := uuid.New()
+	b := swapHalves(a)
+
+	s.NotEqual(a, b)
+	s.Equal(xorFold(a), xorFold(b))
+	// Any UUID with equal halves maps to the same key as the zero UUID.
+	s.Equal(xorFold(uuid.Nil), xorFold(uuid.MustParse("0123456789abcdef0123456789abcdef")))
+}
+
+func (s *LockKeySuite) TestDistinctWalletsGetDistinctKeys() {
+	a := uuid.New()
+	s.NotEqual(WalletLockKey(a), WalletLockKey(swapHalves(a)))
+	s.NotEqual(WalletLockKey(uuid.Nil), WalletLockKey(uuid.MustParse("0123456789abcdef0123456789abcdef")))
+
+	const wallets = 100_000
+	seen := make(map[int64]uuid.UUID, wallets)
+	for i := 0; i < wallets; i++ {
+		id := uuid.New()
+		key := WalletLockKey(id)
+		prev, exists := seen[key]
+		s.Require().False(exists, "%s and %s share lock key %d", prev, id, key)
+		seen[key] = id
+	}
+}
+
+func (s *LockKeySuite) TestKeysCarryWalletClass() {
+	for i := 0; i < 1000; i++ {
+		s.Equal(int64(WalletLockClass), WalletLockKey(uuid.New())>>56)
+	}
+}
+
+// Replicas running different versions must agree on keys, so the derivation
+// must not change silently.
+func (s *LockKeySuite) TestKeyIsStable() {
+	id := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	s.Equal(int64(6318007255881658802), WalletLockKey(id))
+}
@@ -0,0 +1,464 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"ITK/internal/tracing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrWebhookDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryDead      = "DEAD"
+)
+
+// WebhookSubscription receives outbox events of the listed types. An empty
+// EventTypes subscribes to every event. Secret is only loaded when the
+// subscription is created and when its deliveries are claimed.
+type WebhookSubscription struct {
+	ID         uuid.UUID
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+// WebhookDelivery tracks sending one event to one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	EventID        uuid.UUID
+	SubscriptionID uuid.UUID
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// PendingDelivery is a delivery claimed by the dispatcher together with
+// everything needed to send it.
+type PendingDelivery struct {
+	WebhookDelivery
+	URL            string
+	Secret         string
+	EventType      string
+	Payload        []byte
+	EventCreatedAt time.Time
+}
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=webhooks_mock.go -source=webhooks.go -package=repository
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (*WebhookSubscription, error)
+	// ListSubscriptions returns the subscriptions without their secrets.
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id uuid.UUID) (*WebhookDelivery, error)
+	ReplayDeadDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+
+	FanOutEvents(ctx context.Context, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID) error
+	RetryDelivery(ctx context.Context, id uuid.UUID, lastError string, delay time.Duration) error
+	MarkDeliveryDead(ctx context.Context, id uuid.UUID, lastError string) error
+}
+
+type webhookRepo struct {
+	pool *pgxpool.Pool
+	log  *slog.Logger
+}
+
+func NewWebhooks(pool *pgxpool.Pool, log *slog.Logger) WebhookRepository {
+	return &webhookRepo{
+		pool: pool,
+		log:  log.With(slog.String("component", "repository/webhooks")),
+	}
+}
+
+var deliveryColumns = []string{
+	"d.id", "d.event_id", "d.subscription_id", "d.status", "d.attempts",
+	"d.next_attempt_at", "COALESCE(d.last_error, '')", "d.created_at", "d.updated_at",
+}
+
+func (r *webhookRepo) CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (_ *WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "repository.CreateSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	sql, args, err := squirrel.Insert("webhook_subscriptions").
+		Columns("url", "secret", "event_types").
+		Values(url, secret, eventTypes).
+		Suffix("RETURNING id, url, secret, event_types, created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SQL query: %w", err)
+	}
+
+	var sub WebhookSubscription
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.EventTypes, &sub.CreatedAt)
+	if err != nil {
+		r.log.Error("failed to create webhook subscription", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *webhookRepo) ListSubscriptions(ctx context.Context) (_ []WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ListSubscriptions")
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Select("id", "url", "event_types", "created_at").
+		From("webhook_subscriptions").
+		OrderBy("created_at").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		var sub WebhookSubscription
+		if err = rows.Scan(&sub.ID, &sub.URL, &sub.EventTypes, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// DeleteSubscription removes the subscription together with its deliveries.
+func (r *webhookRepo) DeleteSubscription(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.DeleteSubscription", trace.WithAttributes(tracing.SubscriptionID(id)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Delete("webhook_subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete SQL: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookSubscriptionNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns the newest deliveries of a subscription. An empty
+// status lists deliveries in any state.
+func (r *webhookRepo) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) (_ []WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ListDeliveries", trace.WithAttributes(tracing.SubscriptionID(subscriptionID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := r.subscriptionExists(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	query := squirrel.Select(deliveryColumns...).
+		From("webhook_deliveries d").
+		Where(squirrel.Eq{"d.subscription_id": subscriptionID}).
+		OrderBy("d.created_at DESC", "d.id DESC").
+		Limit(uint64(limit))
+	if status != "" {
+		query = query.Where(squirrel.Eq{"d.status": status})
+	}
+
+	sql, args, err := query.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0, limit)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ReplayDelivery schedules a delivery to be sent again right away with a
+// fresh attempt budget.
+func (r *webhookRepo) ReplayDelivery(ctx context.Context, id uuid.UUID) (_ *WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReplayDelivery", trace.WithAttributes(tracing.DeliveryID(id)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := replayQuery().
+		Where(squirrel.Eq{"d.id": id}).
+		Suffix("RETURNING " + strings.Join(deliveryColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	d, err := scanDelivery(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// ReplayDeadDeliveries reschedules every dead delivery of a subscription.
+func (r *webhookRepo) ReplayDeadDeliveries(ctx context.Context, subscriptionID uuid.UUID) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ReplayDeadDeliveries", trace.WithAttributes(tracing.SubscriptionID(subscriptionID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := r.subscriptionExists(ctx, subscriptionID); err != nil {
+		return 0, err
+	}
+
+	sql, args, err := replayQuery().
+		Where(squirrel.Eq{"d.subscription_id": subscriptionID, "d.status": DeliveryDead}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FanOutEvents turns up to limit undispatched outbox events into one delivery
+// per matching subscription and marks the events dispatched. Events that
+// match no subscription are marked dispatched as well.
+func (r *webhookRepo) FanOutEvents(ctx context.Context, limit int) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "repository.FanOutEvents")
+	defer func() { tracing.End(span, err) }()
+
+	const fanOutSQL = `
+		WITH batch AS (
+			SELECT id, event_type
+			FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (event_id, subscription_id)
+			SELECT b.id, s.id
+			FROM batch b
+			JOIN webhook_subscriptions s
+				ON cardinality(s.event_types) = 0 OR b.event_type = ANY(s.event_types)
+			ON CONFLICT (event_id, subscription_id) DO NOTHING
+		)
+		UPDATE outbox_events
+		SET dispatched_at = NOW()
+		WHERE id IN (SELECT id FROM batch)`
+
+	tag, err := r.pool.Exec(ctx, fanOutSQL, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fan out outbox events: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDeliveries picks up to limit due deliveries and counts an attempt for
+// each of them. A claimed delivery is hidden from other dispatchers for the
+// lease duration, after which it becomes due again unless its outcome was
+// recorded.
+func (r *webhookRepo) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []PendingDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ClaimDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	due, dueArgs, err := squirrel.Select("id").
+		From("webhook_deliveries").
+		Where(squirrel.Eq{"status": DeliveryPending}).
+		Where("next_attempt_at <= NOW()").
+		OrderBy("next_attempt_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	columns := append(append([]string{}, deliveryColumns...), "s.url", "s.secret", "e.event_type", "e.payload", "e.created_at")
+	sql, args, err := squirrel.Update("webhook_deliveries d").
+		Set("attempts", squirrel.Expr("d.attempts + 1")).
+		Set("next_attempt_at", squirrel.Expr("NOW() + make_interval(secs => ?)", lease.Seconds())).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		From("outbox_events e, webhook_subscriptions s").
+		Where("d.id IN ("+due+")", dueArgs...).
+		Where("e.id = d.event_id AND s.id = d.subscription_id").
+		Suffix("RETURNING " + strings.Join(columns, ", ")).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]PendingDelivery, 0, limit)
+	for rows.Next() {
+		var p PendingDelivery
+		err = rows.Scan(
+			&p.ID, &p.EventID, &p.SubscriptionID, &p.Status, &p.Attempts,
+			&p.NextAttemptAt, &p.LastError, &p.CreatedAt, &p.UpdatedAt,
+			&p.URL, &p.Secret, &p.EventType, &p.Payload, &p.EventCreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return pending, nil
+}
+
+func (r *webhookRepo) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	return r.updateDelivery(ctx, id, map[string]any{
+		"status":     DeliveryDelivered,
+		"last_error": nil,
+	})
+}
+
+// RetryDelivery records a failed attempt and schedules the next one after
+// delay.
+func (r *webhookRepo) RetryDelivery(ctx context.Context, id uuid.UUID, lastError string, delay time.Duration) error {
+	return r.updateDelivery(ctx, id, map[string]any{
+		"last_error":      lastError,
+		"next_attempt_at": squirrel.Expr("NOW() + make_interval(secs => ?)", delay.Seconds()),
+	})
+}
+
+// MarkDeliveryDead records a failed attempt and stops retrying until the
+// delivery is replayed.
+func (r *webhookRepo) MarkDeliveryDead(ctx context.Context, id uuid.UUID, lastError string) error {
+	return r.updateDelivery(ctx, id, map[string]any{
+		"status":     DeliveryDead,
+		"last_error": lastError,
+	})
+}
+
+func (r *webhookRepo) updateDelivery(ctx context.Context, id uuid.UUID, set map[string]any) (err error) {
+	ctx, span := tracer.Start(ctx, "repository.updateDelivery", trace.WithAttributes(tracing.DeliveryID(id)))
+	defer func() { tracing.End(span, err) }()
+
+	sql, args, err := squirrel.Update("webhook_deliveries").
+		SetMap(set).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update SQL: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		r.log.Error("failed to update webhook delivery", slog.String("error", err.Error()), slog.String("delivery_id", id.String()))
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepo) subscriptionExists(ctx context.Context, id uuid.UUID) error {
+	sql, args, err := squirrel.Select("EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = ?)").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build check SQL: %w", err)
+	}
+
+	var exists bool
+	if err = r.pool.QueryRow(ctx, sql, append(args, id)...).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check webhook subscription: %w", err)
+	}
+	if !exists {
+		return ErrWebhookSubscriptionNotFound
+	}
+	return nil
+}
+
+func replayQuery() squirrel.UpdateBuilder {
+	return squirrel.Update("webhook_deliveries d").
+		Set("status", DeliveryPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()"))
+}
+
+func scanDelivery(row pgx.Row) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(
+		&d.ID, &d.EventID, &d.SubscriptionID, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"ITK/internal/metrics"
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultMaxBatch is the largest group commit used when Config.MaxBatch is
+// not positive.
+const DefaultMaxBatch = 100
+
+// batcher queues deposits and withdrawals per wallet for group commit. The
+// first caller on an idle wallet becomes the leader: it takes the wallet
+// lock, applies everything queued by then in one transaction, hands the
+// results out and passes leadership to the next queued caller, if any.
+// Callers never wait for work queued after their own, so one busy wallet
+// cannot keep a request in the leader role forever.
+type batcher struct {
+	mu       sync.Mutex
+	queues   map[uuid.UUID]*batchQueue
+	maxBatch int
+}
+
+type batchQueue struct {
+	pending []*pendingOp
+	// leading is set while some caller is leading the queue; pending is
+	// only non-empty while it is set.
+	leading bool
+}
+
+type pendingOp struct {
+	op   repository.BatchOperation
+	done chan error
+	lead chan struct{}
+}
+
+func newBatcher(maxBatch int) *batcher {
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+
+	return &batcher{
+		queues:   make(map[uuid.UUID]*batchQueue),
+		maxBatch: maxBatch,
+	}
+}
+
+// enqueue appends op to the wallet queue and reports whether the caller has
+// to lead it.
+func (b *batcher) enqueue(walletID uuid.UUID, op repository.BatchOperation) (*pendingOp, bool) {
+	p := &pendingOp{
+		op:   op,
+		done: make(chan error, 1),
+		lead: make(chan struct{}, 1),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, exists := b.queues[walletID]
+	if !exists {
+		q = &batchQueue{}
+		b.queues[walletID] = q
+	}
+	q.pending = append(q.pending, p)
+	if q.leading {
+		return p, false
+	}
+	q.leading = true
+	return p, true
+}
+
+// take removes up to maxBatch operations from the head of the wallet queue.
+// Only the leader calls it, and the leader's own operation is at the head.
+func (b *batcher) take(walletID uuid.UUID) []*pendingOp {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queues[walletID]
+	n := min(len(q.pending), b.maxBatch)
+	batch := slices.Clone(q.pending[:n])
+	q.pending = slices.Delete(q.pending, 0, n)
+	return batch
+}
+
+// cancel removes p from the wallet queue. It returns false if p was already
+// taken into a batch, in which case its result is on the way.
+func (b *batcher) cancel(walletID uuid.UUID, p *pendingOp) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queues[walletID]
+	if q == nil {
+		return false
+	}
+	i := slices.Index(q.pending, p)
+	if i < 0 {
+		return false
+	}
+	q.pending = slices.Delete(q.pending, i, i+1)
+	if i == 0 && len(p.lead) > 0 {
+		// p had just been made the leader.
+		b.handOffLocked(walletID, q)
+	}
+	return true
+}
+
+// leave removes the leader's own operation from the wallet queue and passes
+// leadership on.
+func (b *batcher) leave(walletID uuid.UUID, p *pendingOp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queues[walletID]
+	q.pending = slices.DeleteFunc(q.pending, func(op *pendingOp) bool { return op == p })
+	b.handOffLocked(walletID, q)
+}
+
+// handOff passes leadership of the wallet queue to the next queued caller.
+func (b *batcher) handOff(walletID uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handOffLocked(walletID, b.queues[walletID])
+}
+
+func (b *batcher) handOffLocked(walletID uuid.UUID, q *batchQueue) {
+	if len(q.pending) == 0 {
+		delete(b.queues, walletID)
+		return
+	}
+	q.pending[0].lead <- struct{}{}
+}
+
+// applyBatched queues a deposit or withdrawal for group commit and waits for
+// its result. Waiting in the queue is bounded like waiting for the lock.
+func (s *walletService) applyBatched(ctx context.Context, walletID uuid.UUID, op repository.BatchOperation) error {
+	p, lead := s.batches.enqueue(walletID, op)
+
+	waitCtx := ctx
+	if s.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.lockTimeout)
+		defer cancel()
+	}
+
+	if !lead {
+		select {
+		case err := <-p.done:
+			return err
+		case <-p.lead:
+		case <-waitCtx.Done():
+			if !s.batches.cancel(walletID, p) {
+				return <-p.done
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: %w", ErrRequestAborted, ctx.Err())
+			}
+			return ErrWalletBusy
+		}
+	}
+
+	if err := s.runBatch(ctx, walletID, p); err != nil {
+		return err
+	}
+	return <-p.done
+}
+
+// runBatch applies the operations at the head of the wallet queue, the
+// leader's own first. It returns an error only if the lock could not be
+// taken; the leader then leaves the queue and the next caller tries.
+func (s *walletService) runBatch(ctx context.Context, walletID uuid.UUID, self *pendingOp) (err error) {
+	ctx, span := tracer.Start(ctx, "service.batch", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	// The other callers in the batch are waiting for it, so it must not be
+	// cut short when the leader's request goes away.
+	batchCtx, unlock, err := s.lockFor(ctx, context.WithoutCancel(ctx), walletID.String())
+	if err != nil {
+		s.batches.leave(walletID, self)
+		return err
+	}
+
+	batch := s.batches.take(walletID)
+	span.SetAttributes(attribute.Int("batch.size", len(batch)))
+	metrics.BatchSize.Observe(float64(len(batch)))
+
+	results := s.applyBatch(batchCtx, walletID, batch)
+	unlock()
+
+	for i, p := range batch {
+		p.done <- results[i]
+	}
+	s.batches.handOff(walletID)
+	return nil
+}
+
+// applyBatch returns the result of every operation in batch.
+func (s *walletService) applyBatch(ctx context.Context, walletID uuid.UUID, batch []*pendingOp) []error {
+	results := make([]error, len(batch))
+	if len(batch) == 1 {
+		results[0] = s.repo.ApplyOperation(ctx, walletID, batch[0].op.Type, batch[0].op.Amount)
+		return results
+	}
+
+	ops := make([]repository.BatchOperation, len(batch))
+	for i, p := range batch {
+		ops[i] = p.op
+	}
+	applied, err := s.repo.ApplyOperations(ctx, walletID, ops)
+	if err != nil {
+		for i := range results {
+			results[i] = err
+		}
+		return results
+	}
+	return applied
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"ITK/internal/repository"
+	pkgsync "ITK/pkg/sync"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type GroupCommitSuite struct {
+	suite.Suite
+
+	ctrl          *gomock.Controller
+	walletRepo    *repository.MockRepository
+	walletService *walletService
+	ctx           context.Context
+	walletID      uuid.UUID
+}
+
+func TestGroupCommit(t *testing.T) {
+	suite.Run(t, &GroupCommitSuite{})
+}
+
+func (s *GroupCommitSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.walletRepo = repository.NewMockRepository(s.ctrl)
+	s.ctx = context.Background()
+
+	s.walletService = &walletService{
+		repo:       s.walletRepo,
+		log:        slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		walletLock: pkgsync.NewKeyedMutex(),
+		currencies: newCurrencyCache(currencyCacheSize),
+		batches:    newBatcher(0),
+	}
+
+	s.walletID = uuid.New()
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), s.walletID).
+		Return(&repository.Wallet{ID: s.walletID, Currency: "RUB"}, nil).
+		AnyTimes()
+}
+
+func (s *GroupCommitSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+// queued returns the number of operations waiting for the wallet.
+func (s *GroupCommitSuite) queued() int {
+	b := s.walletService.batches
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q := b.queues[s.walletID]; q != nil {
+		return len(q.pending)
+	}
+	return 0
+}
+
+// holdWallet takes the wallet lock so that operations pile up behind it, and
+// returns a func releasing it once n operations are queued.
+func (s *GroupCommitSuite) holdWallet() func(n int) {
+	s.Require().NoError(s.walletService.walletLock.LockContext(s.ctx, s.walletID.String()))
+
+	return func(n int) {
+		s.Require().Eventually(func() bool { return s.queued() == n }, time.Second, time.Millisecond)
+		s.walletService.walletLock.Unlock(s.walletID.String())
+	}
+}
+
+func (s *GroupCommitSuite) TestSingleOperationIsNotBatched() {
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), s.walletID, repository.OperationDeposit, decimal.NewFromInt(100)).
+		Return(nil)
+
+	s.NoError(s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), ""))
+	s.Zero(s.queued())
+}
+
+func (s *GroupCommitSuite) TestConcurrentOperationsShareTransaction() {
+	const n = 10
+	release := s.holdWallet()
+
+	s.walletRepo.EXPECT().
+		ApplyOperations(gomock.Any(), s.walletID, gomock.Len(n)).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, ops []repository.BatchOperation) ([]error, error) {
+			// Withdrawals above 500 do not fit the balance.
+			results := make([]error, len(ops))
+			for i, op := range ops {
+				if op.Type == repository.OperationWithdraw && op.Amount.GreaterThan(decimal.NewFromInt(500)) {
+					results[i] = repository.ErrInsufficientFunds
+				}
+			}
+			return results, nil
+		})
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				errs[i] = s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), "")
+			} else {
+				errs[i] = s.walletService.Withdraw(s.ctx, s.walletID, decimal.NewFromInt(int64(i*100)), "")
+			}
+		}()
+	}
+
+	release(n)
+	wg.Wait()
+
+	for i, err := range errs {
+		if i%2 == 1 && i > 5 {
+			s.ErrorIs(err, ErrInsufficientFunds, i)
+		} else {
+			s.NoError(err, i)
+		}
+	}
+	s.Zero(s.queued())
+}
+
+func (s *GroupCommitSuite) TestBatchFailureReachesEveryCaller() {
+	const n = 3
+	release := s.holdWallet()
+
+	s.walletRepo.EXPECT().
+		ApplyOperations(gomock.Any(), s.walletID, gomock.Len(n)).
+		Return(nil, repository.ErrWalletNotFound)
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), "")
+		}()
+	}
+
+	release(n)
+	wg.Wait()
+
+	for _, err := range errs {
+		s.ErrorIs(err, ErrWalletNotFound)
+	}
+}
+
+func (s *GroupCommitSuite) TestBatchIsCapped() {
+	const n = 5
+	s.walletService.batches = newBatcher(2)
+	release := s.holdWallet()
+
+	var mu sync.Mutex
+	var sizes []int
+	record := func(size int) {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, size)
+	}
+	s.walletRepo.EXPECT().
+		ApplyOperations(gomock.Any(), s.walletID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, ops []repository.BatchOperation) ([]error, error) {
+			record(len(ops))
+			return make([]error, len(ops)), nil
+		}).
+		Times(2)
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), s.walletID, repository.OperationDeposit, gomock.Any()).
+		DoAndReturn(func(context.Context, uuid.UUID, string, decimal.Decimal) error {
+			record(1)
+			return nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.NoError(s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), ""))
+		}()
+	}
+
+	release(n)
+	wg.Wait()
+
+	s.ElementsMatch([]int{2, 2, 1}, sizes)
+}
+
+func (s *GroupCommitSuite) TestQueuedOperationCanBeAbandoned() {
+	release := s.holdWallet()
+
+	leaderDone := make(chan error)
+	go func() {
+		leaderDone <- s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), "")
+	}()
+	s.Require().Eventually(func() bool { return s.queued() == 1 }, time.Second, time.Millisecond)
+
+	// The second caller gives up while waiting behind the leader and must not
+	// reach the repository.
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Millisecond)
+	defer cancel()
+	err := s.walletService.Withdraw(ctx, s.walletID, decimal.NewFromInt(50), "")
+	s.ErrorIs(err, ErrRequestAborted)
+	s.ErrorIs(err, context.DeadlineExceeded)
+
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), s.walletID, repository.OperationDeposit, decimal.NewFromInt(100)).
+		Return(nil)
+	release(1)
+	s.NoError(<-leaderDone)
+}
+
+func (s *GroupCommitSuite) TestLeaderLockFailureHandsOff() {
+	s.walletService.lockTimeout = 20 * time.Millisecond
+	s.Require().NoError(s.walletService.walletLock.LockContext(s.ctx, s.walletID.String()))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), "")
+		}()
+	}
+	wg.Wait()
+	s.walletService.walletLock.Unlock(s.walletID.String())
+
+	for _, err := range errs {
+		s.ErrorIs(err, ErrWalletBusy)
+	}
+	s.Zero(s.queued())
+
+	// The wallet is usable again afterwards.
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), s.walletID, repository.OperationDeposit, decimal.NewFromInt(100)).
+		Return(nil)
+	s.NoError(s.walletService.Deposit(s.ctx, s.walletID, decimal.NewFromInt(100), ""))
+}
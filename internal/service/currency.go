@@ -0,0 +1,112 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"ITK/internal/repository"
+	"ITK/pkg/currency"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// currencyCacheSize bounds the number of wallets whose currency is cached.
+const currencyCacheSize = 100_000
+
+// walletCurrency returns the currency of a wallet. A wallet's currency never
+// changes, so it is loaded once and then served from the cache, which keeps
+// the amount checks of operations off the database.
+func (s *walletService) walletCurrency(ctx context.Context, walletID uuid.UUID) (currency.Currency, error) {
+	if cur, ok := s.currencies.get(walletID); ok {
+		return cur, nil
+	}
+
+	wallet, err := s.repo.GetByID(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return currency.Currency{}, ErrWalletNotFound
+		}
+		return currency.Currency{}, fmt.Errorf("failed to get wallet currency: %w", err)
+	}
+
+	cur, ok := currency.Lookup(wallet.Currency)
+	if !ok {
+		return currency.Currency{}, fmt.Errorf("wallet %s has unsupported currency %q", walletID, wallet.Currency)
+	}
+
+	s.currencies.add(walletID, cur)
+	return cur, nil
+}
+
+// checkAmount validates amount against the wallet currency and returns that
+// currency. requested is the currency named by the client; an empty value
+// means the wallet currency.
+func (s *walletService) checkAmount(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, requested string) (currency.Currency, error) {
+	cur, err := s.walletCurrency(ctx, walletID)
+	if err != nil {
+		return currency.Currency{}, err
+	}
+
+	if requested != "" && !strings.EqualFold(requested, cur.Code) {
+		return currency.Currency{}, ErrCurrencyMismatch
+	}
+	if !cur.Fits(amount) {
+		return currency.Currency{}, ErrInvalidAmountPrecision
+	}
+
+	return cur, nil
+}
+
+// currencyCache is a bounded LRU cache of wallet currencies.
+type currencyCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[uuid.UUID]*list.Element
+}
+
+type currencyEntry struct {
+	walletID uuid.UUID
+	currency currency.Currency
+}
+
+func newCurrencyCache(size int) *currencyCache {
+	return &currencyCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+func (c *currencyCache) get(walletID uuid.UUID) (currency.Currency, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[walletID]
+	if !ok {
+		return currency.Currency{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*currencyEntry).currency, true
+}
+
+func (c *currencyCache) add(walletID uuid.UUID, cur currency.Currency) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[walletID]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[walletID] = c.order.PushFront(&currencyEntry{walletID: walletID, currency: cur})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*currencyEntry).walletID)
+	}
+}
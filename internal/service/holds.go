@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrInvalidHoldTTL     = errors.New("hold ttl is out of range")
+	ErrHoldNotFound       = repository.ErrHoldNotFound
+	ErrHoldNotActive      = repository.ErrHoldNotActive
+	ErrCaptureExceedsHold = repository.ErrCaptureExceedsHold
+)
+
+type Hold = repository.Hold
+
+// PlaceHold reserves amount on the wallet until ttl elapses. A zero ttl uses
+// the configured default.
+func (s *walletService) PlaceHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "service.PlaceHold", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() {
+		recordOperation("hold_place", err)
+		tracing.End(span, err)
+	}()
+
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, ErrInvalidAmount
+	}
+	if ttl == 0 {
+		ttl = s.holdTTL
+	}
+	if ttl <= 0 || (s.holdMaxTTL > 0 && ttl > s.holdMaxTTL) {
+		return nil, ErrInvalidHoldTTL
+	}
+	if _, err = s.checkAmount(ctx, walletID, amount, ""); err != nil {
+		return nil, err
+	}
+
+	ctx, unlock, err := s.lock(ctx, walletID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	hold, err := s.repo.CreateHold(ctx, walletID, amount, ttl)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			return nil, ErrInsufficientFunds
+		}
+		if isStatusError(err) {
+			return nil, err
+		}
+		s.log.Error("failed to place hold", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to place hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+func (s *walletService) GetHold(ctx context.Context, holdID uuid.UUID) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "service.GetHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() { tracing.End(span, err) }()
+
+	hold, err := s.repo.GetHold(ctx, holdID)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		s.log.Error("failed to get hold", slog.String("error", err.Error()), slog.String("hold_id", holdID.String()))
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold withdraws amount from the held funds and releases the rest. A
+// zero amount captures the whole hold.
+func (s *walletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "service.CaptureHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() {
+		recordOperation("hold_capture", err)
+		tracing.End(span, err)
+	}()
+
+	if amount.IsNegative() {
+		return nil, ErrInvalidAmount
+	}
+
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsZero() {
+		amount = hold.Amount
+	} else if _, err = s.checkAmount(ctx, hold.WalletID, amount, ""); err != nil {
+		return nil, err
+	}
+
+	ctx, unlock, err := s.lock(ctx, hold.WalletID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	hold, err = s.repo.CaptureHold(ctx, holdID, amount)
+	if err != nil {
+		return nil, s.mapHoldError(err, holdID, "failed to capture hold")
+	}
+
+	return hold, nil
+}
+
+func (s *walletService) ReleaseHold(ctx context.Context, holdID uuid.UUID) (_ *Hold, err error) {
+	ctx, span := tracer.Start(ctx, "service.ReleaseHold", trace.WithAttributes(tracing.HoldID(holdID)))
+	defer func() {
+		recordOperation("hold_release", err)
+		tracing.End(span, err)
+	}()
+
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, unlock, err := s.lock(ctx, hold.WalletID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	hold, err = s.repo.ReleaseHold(ctx, holdID)
+	if err != nil {
+		return nil, s.mapHoldError(err, holdID, "failed to release hold")
+	}
+
+	return hold, nil
+}
+
+func (s *walletService) mapHoldError(err error, holdID uuid.UUID, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrHoldNotFound):
+		return ErrHoldNotFound
+	case errors.Is(err, repository.ErrHoldNotActive):
+		return ErrHoldNotActive
+	case errors.Is(err, repository.ErrCaptureExceedsHold):
+		return ErrCaptureExceedsHold
+	case isStatusError(err), errors.Is(err, ErrLimitExceeded):
+		return err
+	}
+	s.log.Error(msg, slog.String("error", err.Error()), slog.String("hold_id", holdID.String()))
+	return fmt.Errorf("%s: %w", msg, err)
+}
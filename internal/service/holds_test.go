@@ -0,0 +1,117 @@
+package service
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestPlaceHold_DefaultTTL() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(150)
+	hold := &repository.Hold{ID: uuid.New(), WalletID: walletID, Amount: amount, Status: repository.HoldActive}
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		CreateHold(gomock.Any(), walletID, amount, time.Hour).
+		Return(hold, nil)
+
+	result, err := s.walletService.PlaceHold(s.ctx, walletID, amount, 0)
+
+	s.NoError(err)
+	s.Equal(hold, result)
+}
+
+func (s *WalletServiceSuite) TestPlaceHold_TTLTooLong() {
+	_, err := s.walletService.PlaceHold(s.ctx, uuid.New(), decimal.NewFromFloat(150), 48*time.Hour)
+
+	s.ErrorIs(err, ErrInvalidHoldTTL)
+}
+
+func (s *WalletServiceSuite) TestPlaceHold_InvalidAmount() {
+	_, err := s.walletService.PlaceHold(s.ctx, uuid.New(), decimal.Zero, 0)
+
+	s.ErrorIs(err, ErrInvalidAmount)
+}
+
+func (s *WalletServiceSuite) TestPlaceHold_InsufficientFunds() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(150)
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		CreateHold(gomock.Any(), walletID, amount, 2*time.Hour).
+		Return(nil, repository.ErrInsufficientFunds)
+
+	_, err := s.walletService.PlaceHold(s.ctx, walletID, amount, 2*time.Hour)
+
+	s.ErrorIs(err, ErrInsufficientFunds)
+}
+
+func (s *WalletServiceSuite) TestCaptureHold_FullAmountByDefault() {
+	holdID := uuid.New()
+	hold := &repository.Hold{ID: holdID, WalletID: uuid.New(), Amount: decimal.NewFromFloat(150), Status: repository.HoldActive}
+	captured := &repository.Hold{ID: holdID, WalletID: hold.WalletID, Amount: hold.Amount, CapturedAmount: hold.Amount, Status: repository.HoldCaptured}
+
+	s.walletRepo.EXPECT().
+		GetHold(gomock.Any(), holdID).
+		Return(hold, nil)
+	s.walletRepo.EXPECT().
+		CaptureHold(gomock.Any(), holdID, hold.Amount).
+		Return(captured, nil)
+
+	result, err := s.walletService.CaptureHold(s.ctx, holdID, decimal.Zero)
+
+	s.NoError(err)
+	s.Equal(repository.HoldCaptured, result.Status)
+}
+
+func (s *WalletServiceSuite) TestCaptureHold_ExceedsHold() {
+	holdID := uuid.New()
+	hold := &repository.Hold{ID: holdID, WalletID: uuid.New(), Amount: decimal.NewFromFloat(150), Status: repository.HoldActive}
+	amount := decimal.NewFromFloat(200)
+
+	s.walletRepo.EXPECT().
+		GetHold(gomock.Any(), holdID).
+		Return(hold, nil)
+	s.expectWallet(hold.WalletID, "RUB")
+	s.walletRepo.EXPECT().
+		CaptureHold(gomock.Any(), holdID, amount).
+		Return(nil, repository.ErrCaptureExceedsHold)
+
+	_, err := s.walletService.CaptureHold(s.ctx, holdID, amount)
+
+	s.ErrorIs(err, ErrCaptureExceedsHold)
+}
+
+func (s *WalletServiceSuite) TestCaptureHold_NotFound() {
+	holdID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		GetHold(gomock.Any(), holdID).
+		Return(nil, repository.ErrHoldNotFound)
+
+	_, err := s.walletService.CaptureHold(s.ctx, holdID, decimal.Zero)
+
+	s.ErrorIs(err, ErrHoldNotFound)
+}
+
+func (s *WalletServiceSuite) TestReleaseHold_NotActive() {
+	holdID := uuid.New()
+	hold := &repository.Hold{ID: holdID, WalletID: uuid.New(), Amount: decimal.NewFromFloat(150), Status: repository.HoldReleased}
+
+	s.walletRepo.EXPECT().
+		GetHold(gomock.Any(), holdID).
+		Return(hold, nil)
+	s.walletRepo.EXPECT().
+		ReleaseHold(gomock.Any(), holdID).
+		Return(nil, repository.ErrHoldNotActive)
+
+	_, err := s.walletService.ReleaseHold(s.ctx, holdID)
+
+	s.ErrorIs(err, ErrHoldNotActive)
+}
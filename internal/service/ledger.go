@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+)
+
+type (
+	TrialBalance     = repository.TrialBalance
+	TrialBalanceLine = repository.TrialBalanceLine
+	BalanceMismatch  = repository.BalanceMismatch
+)
+
+// TrialBalance sums the double-entry ledger by currency and account. An
+// unbalanced result means the ledger or the cached wallet balances were
+// changed outside of the service and is logged as an error.
+func (s *walletService) TrialBalance(ctx context.Context) (_ *TrialBalance, err error) {
+	ctx, span := tracer.Start(ctx, "service.TrialBalance")
+	defer func() { tracing.End(span, err) }()
+
+	tb, err := s.repo.TrialBalance(ctx)
+	if err != nil {
+		s.log.Error("failed to build trial balance", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to build trial balance: %w", err)
+	}
+
+	if !tb.Balanced() {
+		attrs := []any{slog.Int("mismatches", len(tb.Mismatches))}
+		for currency, total := range tb.Totals() {
+			attrs = append(attrs, slog.String("total_"+currency, total.String()))
+		}
+		s.log.Error("ledger does not balance", attrs...)
+	}
+	return tb, nil
+}
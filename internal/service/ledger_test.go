@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestTrialBalance() {
+	tb := &repository.TrialBalance{
+		Lines: []repository.TrialBalanceLine{
+			{Currency: "RUB", Account: repository.AccountCashIn, Accounts: 1, Balance: decimal.NewFromInt(-100)},
+			{Currency: "RUB", Account: repository.TrialBalanceWallets, Accounts: 2, Balance: decimal.NewFromInt(100)},
+		},
+		Mismatches: []repository.BalanceMismatch{{WalletID: uuid.New(), Cached: decimal.NewFromInt(1)}},
+	}
+	s.walletRepo.EXPECT().TrialBalance(gomock.Any()).Return(tb, nil)
+
+	result, err := s.walletService.TrialBalance(s.ctx)
+
+	s.NoError(err)
+	s.Same(tb, result)
+	s.False(result.Balanced())
+	s.True(result.Totals()["RUB"].IsZero())
+}
+
+func (s *WalletServiceSuite) TestTrialBalance_RepositoryError() {
+	s.walletRepo.EXPECT().TrialBalance(gomock.Any()).Return(nil, errors.New("connection refused"))
+
+	_, err := s.walletService.TrialBalance(s.ctx)
+
+	s.Error(err)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+	"ITK/pkg/currency"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrInvalidLimitPolicy  = errors.New("invalid limit policy")
+	ErrInvalidWalletLimits = errors.New("invalid wallet limits")
+	ErrLimitExceeded       = repository.ErrLimitExceeded
+	ErrLimitPolicyNotFound = repository.ErrLimitPolicyNotFound
+	ErrLimitTierTaken      = repository.ErrLimitTierTaken
+	// ErrLimitCurrencyMismatch is returned when a wallet would be governed by
+	// a limit policy of another currency.
+	ErrLimitCurrencyMismatch = repository.ErrLimitCurrencyMismatch
+)
+
+type (
+	LimitPolicy        = repository.LimitPolicy
+	WalletLimits       = repository.WalletLimits
+	LimitExceededError = repository.LimitExceededError
+)
+
+// maxLimitNameLength bounds policy and tier names, as stored in Postgres.
+const maxLimitNameLength = 64
+
+// PutLimitPolicy creates or replaces a limit policy. Limits are amounts in
+// the policy currency, the configured default if empty, and must be positive
+// and fit its minor units; unset limits do not apply.
+func (s *walletService) PutLimitPolicy(ctx context.Context, policy LimitPolicy) (_ *LimitPolicy, err error) {
+	ctx, span := tracer.Start(ctx, "service.PutLimitPolicy")
+	defer func() { tracing.End(span, err) }()
+
+	policy.Name = strings.TrimSpace(policy.Name)
+	policy.Tier = strings.TrimSpace(policy.Tier)
+	if err = checkLimitName("name", policy.Name, true); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLimitPolicy, err)
+	}
+	if err = checkLimitName("tier", policy.Tier, false); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLimitPolicy, err)
+	}
+	if policy.Currency == "" {
+		policy.Currency = s.defaultCurrency
+	}
+	cur, ok := currency.Lookup(policy.Currency)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported currency %q", ErrInvalidLimitPolicy, policy.Currency)
+	}
+	policy.Currency = cur.Code
+	for limit, value := range map[string]decimal.NullDecimal{
+		repository.LimitMaxWithdrawal:     policy.MaxWithdrawal,
+		repository.LimitDailyWithdrawal:   policy.DailyWithdrawal,
+		repository.LimitMonthlyWithdrawal: policy.MonthlyWithdrawal,
+		repository.LimitMaxBalance:        policy.MaxBalance,
+	} {
+		if value.Valid && (!value.Decimal.IsPositive() || !cur.Fits(value.Decimal)) {
+			return nil, fmt.Errorf("%w: %s must be a positive amount with at most %d decimal places", ErrInvalidLimitPolicy, limit, cur.MinorUnits)
+		}
+	}
+
+	saved, err := s.repo.PutLimitPolicy(ctx, policy)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrLimitTierTaken):
+			return nil, ErrLimitTierTaken
+		case errors.Is(err, repository.ErrLimitCurrencyMismatch):
+			return nil, ErrLimitCurrencyMismatch
+		}
+		s.log.Error("failed to save limit policy", slog.String("error", err.Error()), slog.String("policy", policy.Name))
+		return nil, fmt.Errorf("failed to save limit policy: %w", err)
+	}
+
+	s.log.Info("limit policy saved",
+		slog.String("policy", saved.Name),
+		slog.String("tier", saved.Tier),
+		slog.String("currency", saved.Currency),
+	)
+	return saved, nil
+}
+
+func (s *walletService) ListLimitPolicies(ctx context.Context) (_ []LimitPolicy, err error) {
+	ctx, span := tracer.Start(ctx, "service.ListLimitPolicies")
+	defer func() { tracing.End(span, err) }()
+
+	policies, err := s.repo.ListLimitPolicies(ctx)
+	if err != nil {
+		s.log.Error("failed to list limit policies", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to list limit policies: %w", err)
+	}
+	return policies, nil
+}
+
+// SetWalletLimits assigns a tier and a limit policy to a wallet. The policy
+// must be in the wallet currency. The change waits for operations already
+// queued on the wallet.
+func (s *walletService) SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) (err error) {
+	ctx, span := tracer.Start(ctx, "service.SetWalletLimits", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	limits.Tier = strings.TrimSpace(limits.Tier)
+	limits.Policy = strings.TrimSpace(limits.Policy)
+	if err = checkLimitName("tier", limits.Tier, false); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWalletLimits, err)
+	}
+	if err = checkLimitName("policy", limits.Policy, false); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWalletLimits, err)
+	}
+
+	ctx, unlock, err := s.lock(ctx, walletID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = s.repo.SetWalletLimits(ctx, walletID, limits); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrWalletNotFound):
+			return ErrWalletNotFound
+		case errors.Is(err, repository.ErrLimitPolicyNotFound):
+			return ErrLimitPolicyNotFound
+		case errors.Is(err, repository.ErrLimitCurrencyMismatch):
+			return ErrLimitCurrencyMismatch
+		}
+		s.log.Error("failed to set wallet limits", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return fmt.Errorf("failed to set wallet limits: %w", err)
+	}
+
+	s.log.Info("wallet limits set",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("tier", limits.Tier),
+		slog.String("policy", limits.Policy),
+	)
+	return nil
+}
+
+func checkLimitName(field, name string, required bool) error {
+	switch {
+	case name == "" && required:
+		return fmt.Errorf("%s is required", field)
+	case len(name) > maxLimitNameLength:
+		return fmt.Errorf("%s must be at most %d bytes", field, maxLimitNameLength)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"strings"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestPutLimitPolicy_Success() {
+	policy := repository.LimitPolicy{
+		Name:            "unverified",
+		Tier:            "basic",
+		Currency:        "RUB",
+		DailyWithdrawal: decimal.NewNullDecimal(decimal.NewFromInt(15000)),
+	}
+
+	s.walletRepo.EXPECT().
+		PutLimitPolicy(gomock.Any(), policy).
+		Return(&policy, nil)
+
+	result, err := s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{
+		Name:            " unverified ",
+		Tier:            "basic ",
+		DailyWithdrawal: decimal.NewNullDecimal(decimal.NewFromInt(15000)),
+	})
+
+	s.NoError(err)
+	s.Equal(&policy, result)
+}
+
+func (s *WalletServiceSuite) TestPutLimitPolicy_CurrencyMinorUnits() {
+	policy := repository.LimitPolicy{
+		Name:          "p",
+		Currency:      "KWD",
+		MaxWithdrawal: decimal.NewNullDecimal(decimal.RequireFromString("10.125")),
+	}
+
+	s.walletRepo.EXPECT().
+		PutLimitPolicy(gomock.Any(), policy).
+		Return(&policy, nil)
+
+	_, err := s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{
+		Name:          "p",
+		Currency:      "kwd",
+		MaxWithdrawal: decimal.NewNullDecimal(decimal.RequireFromString("10.125")),
+	})
+
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestPutLimitPolicy_Validation() {
+	_, err := s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{Name: " "})
+	s.ErrorIs(err, ErrInvalidLimitPolicy)
+
+	_, err = s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{Name: strings.Repeat("a", 65)})
+	s.ErrorIs(err, ErrInvalidLimitPolicy)
+
+	_, err = s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{
+		Name:       "p",
+		MaxBalance: decimal.NewNullDecimal(decimal.Zero),
+	})
+	s.ErrorIs(err, ErrInvalidLimitPolicy)
+
+	_, err = s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{
+		Name:          "p",
+		MaxWithdrawal: decimal.NewNullDecimal(decimal.RequireFromString("10.001")),
+	})
+	s.ErrorIs(err, ErrInvalidLimitPolicy)
+
+	_, err = s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{Name: "p", Currency: "XXX"})
+	s.ErrorIs(err, ErrInvalidLimitPolicy)
+}
+
+func (s *WalletServiceSuite) TestPutLimitPolicy_TierTaken() {
+	s.walletRepo.EXPECT().
+		PutLimitPolicy(gomock.Any(), gomock.Any()).
+		Return(nil, repository.ErrLimitTierTaken)
+
+	_, err := s.walletService.PutLimitPolicy(s.ctx, LimitPolicy{Name: "p", Tier: "basic"})
+
+	s.ErrorIs(err, ErrLimitTierTaken)
+}
+
+func (s *WalletServiceSuite) TestSetWalletLimits_PolicyNotFound() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		SetWalletLimits(gomock.Any(), walletID, repository.WalletLimits{Tier: "basic", Policy: "missing"}).
+		Return(repository.ErrLimitPolicyNotFound)
+
+	err := s.walletService.SetWalletLimits(s.ctx, walletID, WalletLimits{Tier: " basic", Policy: "missing"})
+
+	s.ErrorIs(err, ErrLimitPolicyNotFound)
+}
+
+func (s *WalletServiceSuite) TestSetWalletLimits_CurrencyMismatch() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		SetWalletLimits(gomock.Any(), walletID, repository.WalletLimits{Policy: "usd"}).
+		Return(repository.ErrLimitCurrencyMismatch)
+
+	err := s.walletService.SetWalletLimits(s.ctx, walletID, WalletLimits{Policy: "usd"})
+
+	s.ErrorIs(err, ErrLimitCurrencyMismatch)
+}
+
+func (s *WalletServiceSuite) TestWithdraw_LimitExceeded() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(100)
+	exceeded := &repository.LimitExceededError{Policy: "unverified", Limit: repository.LimitDailyWithdrawal, Value: decimal.NewFromInt(50)}
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), walletID, repository.OperationWithdraw, amount).
+		Return(exceeded)
+
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
+
+	s.ErrorIs(err, ErrLimitExceeded)
+	var limitErr *LimitExceededError
+	s.Require().ErrorAs(err, &limitErr)
+	s.Equal(repository.LimitDailyWithdrawal, limitErr.Limit)
+}
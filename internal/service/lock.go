@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"ITK/internal/metrics"
+	"ITK/internal/tracing"
+	pkgsync "ITK/pkg/sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrWalletBusy     = errors.New("wallet is busy")
+	ErrRequestAborted = errors.New("request aborted while waiting for wallet")
+	ErrWalletNotOwned = pkgsync.ErrNotOwner
+
+	errLockFailed = errors.New("failed to lock wallet")
+)
+
+// NotOwnerError names the instance that owns a wallet when locks are routed
+// by consistent hashing.
+type NotOwnerError = pkgsync.NotOwnerError
+
+// lock takes the wallet locks for keys in the given order and returns a
+// context for the work they guard, derived from ctx, and a func releasing
+// them. The wait is traced, as queueing on a hot wallet is where most of the
+// tail latency comes from.
+//
+// Waiting stops when ctx is done, so a request the client or the router
+// timeout has given up on never reaches the database, or after the configured
+// lock timeout, which sheds load from a wallet with too long a queue.
+func (s *walletService) lock(ctx context.Context, keys ...string) (context.Context, func(), error) {
+	return s.lockFor(ctx, ctx, keys...)
+}
+
+// lockFor is like lock, but waits under ctx and derives the returned context
+// from work. If the locker can lose a held lock, that context is cancelled
+// with pkgsync.ErrLockLost when it does, so that the guarded work stops
+// rather than running unserialized.
+func (s *walletService) lockFor(ctx, work context.Context, keys ...string) (_ context.Context, unlock func(), err error) {
+	_, span := tracer.Start(ctx, "service.lock")
+	span.SetAttributes(attribute.StringSlice("lock.keys", keys))
+	defer func() { tracing.End(span, err) }()
+
+	lockCtx := ctx
+	if s.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, s.lockTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.LockWait.Observe(time.Since(start).Seconds())
+		metrics.LockKeys.Set(float64(s.walletLock.Len()))
+	}()
+
+	for i, key := range keys {
+		if err = s.walletLock.LockContext(lockCtx, key); err != nil {
+			for _, held := range keys[:i] {
+				s.walletLock.Unlock(held)
+			}
+			switch {
+			case ctx.Err() != nil:
+				return nil, nil, fmt.Errorf("%w: %w", ErrRequestAborted, ctx.Err())
+			case lockCtx.Err() != nil:
+				return nil, nil, ErrWalletBusy
+			case errors.Is(err, ErrWalletNotOwned):
+				return nil, nil, err
+			}
+			s.log.Error("failed to lock wallet", slog.String("error", err.Error()), slog.String("key", key))
+			return nil, nil, fmt.Errorf("%w: %w", errLockFailed, err)
+		}
+	}
+
+	release := func() {
+		for _, key := range keys {
+			s.walletLock.Unlock(key)
+		}
+	}
+
+	notifier, ok := s.walletLock.(pkgsync.LossNotifier)
+	if !ok {
+		return work, release, nil
+	}
+
+	guarded, cancel := context.WithCancelCause(work)
+	stops := make([]func() bool, 0, len(keys))
+	for _, key := range keys {
+		stops = append(stops, context.AfterFunc(notifier.HeldContext(key), func() {
+			s.log.Warn("wallet lock lost", slog.String("key", key))
+			cancel(pkgsync.ErrLockLost)
+		}))
+	}
+
+	return guarded, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel(nil)
+		release()
+	}, nil
+}
+
+// isLockError reports whether err comes from lock rather than from the
+// operation the lock guards. Such errors are already mapped and logged.
+func isLockError(err error) bool {
+	return errors.Is(err, ErrWalletBusy) ||
+		errors.Is(err, ErrRequestAborted) ||
+		errors.Is(err, ErrWalletNotOwned) ||
+		errors.Is(err, errLockFailed)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+
+	"ITK/internal/metrics"
+)
+
+const (
+	outcomeSuccess           = "success"
+	outcomeInvalid           = "invalid"
+	outcomeNotFound          = "not_found"
+	outcomeInsufficientFunds = "insufficient_funds"
+	outcomeLimitExceeded     = "limit_exceeded"
+	outcomeConflict          = "conflict"
+	outcomeBusy              = "busy"
+	outcomeAborted           = "aborted"
+	outcomeNotOwner          = "not_owner"
+	outcomeError             = "error"
+)
+
+// recordOperation counts a finished money-moving call by its outcome.
+func recordOperation(opType string, err error) {
+	metrics.Operations.WithLabelValues(opType, operationOutcome(err)).Inc()
+}
+
+func operationOutcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.Is(err, ErrWalletNotFound), errors.Is(err, ErrHoldNotFound), errors.Is(err, ErrOperationNotFound):
+		return outcomeNotFound
+	case errors.Is(err, ErrInsufficientFunds):
+		return outcomeInsufficientFunds
+	case errors.Is(err, ErrLimitExceeded):
+		return outcomeLimitExceeded
+	case errors.Is(err, ErrHoldNotActive), errors.Is(err, ErrCaptureExceedsHold),
+		errors.Is(err, ErrOperationNotReversible), errors.Is(err, ErrAlreadyReversed),
+		errors.Is(err, ErrReversalExceedsOriginal):
+		return outcomeConflict
+	case errors.Is(err, ErrWalletBusy):
+		return outcomeBusy
+	case errors.Is(err, ErrRequestAborted):
+		return outcomeAborted
+	case errors.Is(err, ErrWalletNotOwned):
+		return outcomeNotOwner
+	case errors.Is(err, ErrInvalidAmount),
+		errors.Is(err, ErrSameWallet),
+		errors.Is(err, ErrUnsupportedCurrency),
+		errors.Is(err, ErrCurrencyMismatch),
+		errors.Is(err, ErrInvalidAmountPrecision),
+		errors.Is(err, ErrInvalidHoldTTL):
+		return outcomeInvalid
+	}
+	return outcomeError
+}
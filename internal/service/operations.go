@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultOperationsLimit = 50
+	MaxOperationsLimit     = 200
+)
+
+var (
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidOperationType = errors.New("invalid operation type")
+	ErrInvalidTimeRange     = errors.New("invalid time range")
+)
+
+type Operation = repository.Operation
+
+type OperationsQuery struct {
+	Types  []string
+	From   time.Time
+	To     time.Time
+	Cursor string
+	Limit  int
+}
+
+type OperationsPage struct {
+	// Currency is the currency of the wallet and of every amount on the page.
+	Currency   string
+	Operations []Operation
+	NextCursor string
+}
+
+// HistoricalBalance is the balance of a wallet as of At.
+type HistoricalBalance struct {
+	WalletID uuid.UUID
+	Currency string
+	At       time.Time
+	Balance  decimal.Decimal
+}
+
+var operationTypes = map[string]struct{}{
+	repository.OperationDeposit:     {},
+	repository.OperationWithdraw:    {},
+	repository.OperationTransferIn:  {},
+	repository.OperationTransferOut: {},
+	repository.OperationReversal:    {},
+}
+
+func (s *walletService) ListOperations(ctx context.Context, walletID uuid.UUID, query OperationsQuery) (_ *OperationsPage, err error) {
+	ctx, span := tracer.Start(ctx, "service.ListOperations", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	for _, t := range query.Types {
+		if _, ok := operationTypes[t]; !ok {
+			return nil, ErrInvalidOperationType
+		}
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultOperationsLimit
+	}
+	if limit > MaxOperationsLimit {
+		limit = MaxOperationsLimit
+	}
+
+	filter := repository.OperationFilter{
+		Types: query.Types,
+		From:  query.From.UTC(),
+		To:    query.To.UTC(),
+		// One extra row tells whether there is a next page.
+		Limit: limit + 1,
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		filter.After = cursor
+	}
+
+	cur, err := s.walletCurrency(ctx, walletID)
+	if err != nil {
+		if !errors.Is(err, ErrWalletNotFound) {
+			s.log.Error("failed to list operations", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		}
+		return nil, err
+	}
+
+	ops, err := s.repo.ListOperations(ctx, walletID, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		s.log.Error("failed to list operations", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	page := &OperationsPage{Currency: cur.Code, Operations: ops}
+	if len(ops) > limit {
+		page.Operations = ops[:limit]
+		last := page.Operations[limit-1]
+		page.NextCursor = encodeCursor(repository.OperationCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// BalanceAt reconstructs the balance of a wallet as of at from the
+// balance_after of its operations. Holds do not change the balance, so it is
+// not reduced by holds active at that time.
+func (s *walletService) BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (_ *HistoricalBalance, err error) {
+	ctx, span := tracer.Start(ctx, "service.BalanceAt", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	wallet, err := s.repo.GetByID(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		s.log.Error("failed to get balance", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	balance, err := s.repo.BalanceAt(ctx, walletID, at.UTC())
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		s.log.Error("failed to get historical balance", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to get historical balance: %w", err)
+	}
+
+	return &HistoricalBalance{
+		WalletID: walletID,
+		Currency: wallet.Currency,
+		At:       at,
+		Balance:  balance,
+	}, nil
+}
+
+func encodeCursor(c repository.OperationCursor) string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*repository.OperationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	opID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.OperationCursor{
+		CreatedAt: time.UnixMicro(micros).UTC(),
+		ID:        opID,
+	}, nil
+}
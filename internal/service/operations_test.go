@@ -0,0 +1,142 @@
+package service
+
+import (
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestListOperations_NextCursor() {
+	walletID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	ops := []repository.Operation{
+		{ID: uuid.New(), WalletID: walletID, Type: "DEPOSIT", Amount: decimal.NewFromInt(3), CreatedAt: now},
+		{ID: uuid.New(), WalletID: walletID, Type: "DEPOSIT", Amount: decimal.NewFromInt(2), CreatedAt: now.Add(-time.Second)},
+		{ID: uuid.New(), WalletID: walletID, Type: "DEPOSIT", Amount: decimal.NewFromInt(1), CreatedAt: now.Add(-2 * time.Second)},
+	}
+
+	s.expectWallet(walletID, "USD")
+	s.walletRepo.EXPECT().
+		ListOperations(gomock.Any(), walletID, repository.OperationFilter{Limit: 3}).
+		Return(ops, nil)
+
+	page, err := s.walletService.ListOperations(s.ctx, walletID, OperationsQuery{Limit: 2})
+
+	s.NoError(err)
+	s.Equal("USD", page.Currency)
+	s.Len(page.Operations, 2)
+	s.NotEmpty(page.NextCursor)
+
+	cursor, err := decodeCursor(page.NextCursor)
+	s.NoError(err)
+	s.Equal(ops[1].ID, cursor.ID)
+	s.True(ops[1].CreatedAt.Equal(cursor.CreatedAt))
+}
+
+func (s *WalletServiceSuite) TestListOperations_LastPage() {
+	walletID := uuid.New()
+	cursor := repository.OperationCursor{CreatedAt: time.Now().UTC().Truncate(time.Microsecond), ID: uuid.New()}
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ListOperations(gomock.Any(), walletID, gomock.Any()).
+		DoAndReturn(func(_ any, _ uuid.UUID, filter repository.OperationFilter) ([]repository.Operation, error) {
+			s.Equal(DefaultOperationsLimit+1, filter.Limit)
+			s.Equal([]string{"WITHDRAW"}, filter.Types)
+			s.Require().NotNil(filter.After)
+			s.Equal(cursor.ID, filter.After.ID)
+			s.True(cursor.CreatedAt.Equal(filter.After.CreatedAt))
+			return []repository.Operation{{ID: uuid.New(), WalletID: walletID, Type: "WITHDRAW"}}, nil
+		})
+
+	page, err := s.walletService.ListOperations(s.ctx, walletID, OperationsQuery{
+		Types:  []string{"WITHDRAW"},
+		Cursor: encodeCursor(cursor),
+	})
+
+	s.NoError(err)
+	s.Len(page.Operations, 1)
+	s.Empty(page.NextCursor)
+}
+
+func (s *WalletServiceSuite) TestListOperations_LimitCapped() {
+	walletID := uuid.New()
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ListOperations(gomock.Any(), walletID, repository.OperationFilter{Limit: MaxOperationsLimit + 1}).
+		Return(nil, nil)
+
+	_, err := s.walletService.ListOperations(s.ctx, walletID, OperationsQuery{Limit: 10000})
+
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestListOperations_InvalidType() {
+	_, err := s.walletService.ListOperations(s.ctx, uuid.New(), OperationsQuery{Types: []string{"STEAL"}})
+
+	s.ErrorIs(err, ErrInvalidOperationType)
+}
+
+func (s *WalletServiceSuite) TestListOperations_InvalidCursor() {
+	_, err := s.walletService.ListOperations(s.ctx, uuid.New(), OperationsQuery{Cursor: "not-a-cursor"})
+
+	s.ErrorIs(err, ErrInvalidCursor)
+}
+
+func (s *WalletServiceSuite) TestListOperations_InvalidTimeRange() {
+	now := time.Now()
+
+	_, err := s.walletService.ListOperations(s.ctx, uuid.New(), OperationsQuery{From: now, To: now.Add(-time.Hour)})
+
+	s.ErrorIs(err, ErrInvalidTimeRange)
+}
+
+func (s *WalletServiceSuite) TestListOperations_WalletNotFound() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), walletID).
+		Return(nil, repository.ErrWalletNotFound)
+
+	_, err := s.walletService.ListOperations(s.ctx, walletID, OperationsQuery{})
+
+	s.ErrorIs(err, ErrWalletNotFound)
+}
+
+func (s *WalletServiceSuite) TestBalanceAt_Success() {
+	walletID := uuid.New()
+	at := time.Date(2025, 1, 31, 23, 59, 59, 0, time.FixedZone("MSK", 3*60*60))
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		BalanceAt(gomock.Any(), walletID, at.UTC()).
+		Return(decimal.RequireFromString("150.25"), nil)
+
+	balance, err := s.walletService.BalanceAt(s.ctx, walletID, at)
+
+	s.NoError(err)
+	s.Equal(&HistoricalBalance{
+		WalletID: walletID,
+		Currency: "RUB",
+		At:       at,
+		Balance:  decimal.RequireFromString("150.25"),
+	}, balance)
+}
+
+func (s *WalletServiceSuite) TestBalanceAt_WalletNotFound() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), walletID).
+		Return(nil, repository.ErrWalletNotFound)
+
+	_, err := s.walletService.BalanceAt(s.ctx, walletID, time.Now())
+
+	s.ErrorIs(err, ErrWalletNotFound)
+}
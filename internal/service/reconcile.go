@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// reconcilePageSize is the number of wallet IDs ReconcileAll lists at a time.
+const reconcilePageSize = 500
+
+type Reconciliation = repository.Reconciliation
+
+// ReconcileScan configures ReconcileAll. The callbacks are called from one
+// goroutine at a time.
+type ReconcileScan struct {
+	// Concurrency is the number of wallets reconciled at once; at least one.
+	Concurrency int
+	// Progress is called with the number of wallets checked so far after
+	// every wallet.
+	Progress func(checked int)
+	// Drift is called for every wallet that does not reconcile.
+	Drift func(rc *Reconciliation)
+}
+
+type ReconcileSummary struct {
+	Checked int
+	Drifted int
+}
+
+// ReconcileWallet recomputes the balance of a wallet from its operations and
+// compares it with the cached balance and the balance after its latest
+// operation. Drift is logged as an error: it means money was created or
+// destroyed outside of an operation.
+func (s *walletService) ReconcileWallet(ctx context.Context, walletID uuid.UUID) (_ *Reconciliation, err error) {
+	ctx, span := tracer.Start(ctx, "service.ReconcileWallet", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	rc, err := s.repo.Reconcile(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		s.log.Error("failed to reconcile wallet", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to reconcile wallet: %w", err)
+	}
+
+	s.logDrift(rc)
+	return rc, nil
+}
+
+// ReconcileAll reconciles every wallet and stops at the first failure. The
+// summary counts the wallets checked until then.
+func (s *walletService) ReconcileAll(ctx context.Context, scan ReconcileScan) (_ *ReconcileSummary, err error) {
+	ctx, span := tracer.Start(ctx, "service.ReconcileAll")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	ids := make(chan uuid.UUID)
+	go func() {
+		defer close(ids)
+		after := uuid.Nil
+		for {
+			page, err := s.repo.ListWalletIDs(ctx, after, reconcilePageSize)
+			if err != nil {
+				cancel(fmt.Errorf("failed to list wallets: %w", err))
+				return
+			}
+			for _, id := range page {
+				select {
+				case ids <- id:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(page) < reconcilePageSize {
+				return
+			}
+			after = page[len(page)-1]
+		}
+	}()
+
+	results := make(chan *Reconciliation)
+	var wg sync.WaitGroup
+	for range max(scan.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				rc, err := s.repo.Reconcile(ctx, id)
+				if errors.Is(err, repository.ErrWalletNotFound) {
+					continue
+				}
+				if err != nil {
+					cancel(fmt.Errorf("failed to reconcile wallet %s: %w", id, err))
+					return
+				}
+				select {
+				case results <- rc:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := &ReconcileSummary{}
+	for rc := range results {
+		summary.Checked++
+		if !rc.Consistent() {
+			summary.Drifted++
+			s.logDrift(rc)
+			if scan.Drift != nil {
+				scan.Drift(rc)
+			}
+		}
+		if scan.Progress != nil {
+			scan.Progress(summary.Checked)
+		}
+	}
+
+	if err = context.Cause(ctx); err != nil {
+		s.log.Error("reconciliation stopped", slog.String("error", err.Error()), slog.Int("checked", summary.Checked))
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (s *walletService) logDrift(rc *Reconciliation) {
+	if rc.Consistent() {
+		return
+	}
+	s.log.Error("wallet balance drift",
+		slog.String("wallet_id", rc.WalletID.String()),
+		slog.String("balance", rc.Balance.String()),
+		slog.String("computed", rc.Computed.String()),
+		slog.String("last_balance_after", rc.LastBalanceAfter.String()),
+		slog.String("drift", rc.Drift().String()),
+	)
+}
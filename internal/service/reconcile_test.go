@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func reconciliation(walletID uuid.UUID, balance, computed, last int64) *repository.Reconciliation {
+	return &repository.Reconciliation{
+		WalletID:         walletID,
+		Balance:          decimal.NewFromInt(balance),
+		Computed:         decimal.NewFromInt(computed),
+		LastBalanceAfter: decimal.NewFromInt(last),
+	}
+}
+
+func (s *WalletServiceSuite) TestReconcileWallet() {
+	walletID := uuid.New()
+	rc := reconciliation(walletID, 101, 100, 100)
+	s.walletRepo.EXPECT().Reconcile(gomock.Any(), walletID).Return(rc, nil)
+
+	result, err := s.walletService.ReconcileWallet(s.ctx, walletID)
+
+	s.NoError(err)
+	s.Same(rc, result)
+	s.False(result.Consistent())
+	s.True(decimal.NewFromInt(1).Equal(result.Drift()))
+}
+
+func (s *WalletServiceSuite) TestReconcileWallet_WalletNotFound() {
+	s.walletRepo.EXPECT().Reconcile(gomock.Any(), gomock.Any()).Return(nil, repository.ErrWalletNotFound)
+
+	_, err := s.walletService.ReconcileWallet(s.ctx, uuid.New())
+
+	s.ErrorIs(err, ErrWalletNotFound)
+}
+
+func (s *WalletServiceSuite) TestReconcileAll_Pages() {
+	ids := make([]uuid.UUID, reconcilePageSize+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	drifted := ids[7]
+
+	gomock.InOrder(
+		s.walletRepo.EXPECT().ListWalletIDs(gomock.Any(), uuid.Nil, reconcilePageSize).Return(ids[:reconcilePageSize], nil),
+		s.walletRepo.EXPECT().ListWalletIDs(gomock.Any(), ids[reconcilePageSize-1], reconcilePageSize).Return(ids[reconcilePageSize:], nil),
+	)
+	s.walletRepo.EXPECT().
+		Reconcile(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, walletID uuid.UUID) (*repository.Reconciliation, error) {
+			if walletID == drifted {
+				return reconciliation(walletID, 10, 10, 9), nil
+			}
+			return reconciliation(walletID, 10, 10, 10), nil
+		}).
+		Times(len(ids))
+
+	var (
+		progress int
+		drifts   []uuid.UUID
+	)
+	summary, err := s.walletService.ReconcileAll(s.ctx, ReconcileScan{
+		Concurrency: 4,
+		Progress:    func(checked int) { progress = checked },
+		Drift:       func(rc *Reconciliation) { drifts = append(drifts, rc.WalletID) },
+	})
+
+	s.NoError(err)
+	s.Equal(&ReconcileSummary{Checked: len(ids), Drifted: 1}, summary)
+	s.Equal(len(ids), progress)
+	s.Equal([]uuid.UUID{drifted}, drifts)
+}
+
+func (s *WalletServiceSuite) TestReconcileAll_SkipsRemovedWallets() {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	s.walletRepo.EXPECT().ListWalletIDs(gomock.Any(), uuid.Nil, reconcilePageSize).Return(ids, nil)
+	s.walletRepo.EXPECT().Reconcile(gomock.Any(), ids[0]).Return(nil, repository.ErrWalletNotFound)
+	s.walletRepo.EXPECT().Reconcile(gomock.Any(), ids[1]).Return(reconciliation(ids[1], 0, 0, 0), nil)
+
+	summary, err := s.walletService.ReconcileAll(s.ctx, ReconcileScan{})
+
+	s.NoError(err)
+	s.Equal(&ReconcileSummary{Checked: 1}, summary)
+}
+
+func (s *WalletServiceSuite) TestReconcileAll_StopsOnError() {
+	repoErr := errors.New("connection refused")
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	s.walletRepo.EXPECT().ListWalletIDs(gomock.Any(), uuid.Nil, reconcilePageSize).Return(ids, nil)
+	s.walletRepo.EXPECT().Reconcile(gomock.Any(), ids[0]).Return(nil, repoErr)
+
+	summary, err := s.walletService.ReconcileAll(s.ctx, ReconcileScan{Concurrency: 1})
+
+	s.ErrorIs(err, repoErr)
+	s.Zero(summary.Checked)
+}
+
+func (s *WalletServiceSuite) TestReconcileAll_ListError() {
+	repoErr := errors.New("connection refused")
+	s.walletRepo.EXPECT().ListWalletIDs(gomock.Any(), uuid.Nil, reconcilePageSize).Return(nil, repoErr)
+
+	_, err := s.walletService.ReconcileAll(s.ctx, ReconcileScan{Concurrency: 2})
+
+	s.ErrorIs(err, repoErr)
+}
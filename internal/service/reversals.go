@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrOperationNotFound       = repository.ErrOperationNotFound
+	ErrOperationNotReversible  = repository.ErrOperationNotReversible
+	ErrAlreadyReversed         = repository.ErrAlreadyReversed
+	ErrReversalExceedsOriginal = repository.ErrReversalExceedsOriginal
+)
+
+// Reverse reverses amount of an earlier operation of the wallet. Several
+// partial reversals may add up to the original amount; reversing a transfer
+// leg reverses the other leg too.
+func (s *walletService) Reverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal, currencyCode string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Reverse", trace.WithAttributes(
+		tracing.WalletID(walletID),
+		tracing.OperationType(repository.OperationReversal),
+	))
+	defer func() {
+		recordOperation("reversal", err)
+		tracing.End(span, err)
+	}()
+
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return ErrInvalidAmount
+	}
+	if _, err = s.checkAmount(ctx, walletID, amount, currencyCode); err != nil {
+		return err
+	}
+
+	// The other wallet of a reversed transfer is only locked by the
+	// repository's advisory lock.
+	ctx, unlock, err := s.lock(ctx, walletID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err = s.repo.ReverseOperation(ctx, walletID, operationID, amount); err != nil {
+		switch {
+		case isStatusError(err):
+			return err
+		case errors.Is(err, repository.ErrOperationNotFound):
+			return ErrOperationNotFound
+		case errors.Is(err, repository.ErrOperationNotReversible):
+			return ErrOperationNotReversible
+		case errors.Is(err, repository.ErrAlreadyReversed):
+			return ErrAlreadyReversed
+		case errors.Is(err, repository.ErrReversalExceedsOriginal):
+			return ErrReversalExceedsOriginal
+		case errors.Is(err, repository.ErrWalletNotFound):
+			return ErrWalletNotFound
+		case errors.Is(err, repository.ErrInsufficientFunds):
+			return ErrInsufficientFunds
+		}
+		s.log.Error("failed to reverse operation",
+			slog.String("error", err.Error()),
+			slog.String("wallet_id", walletID.String()),
+			slog.String("operation_id", operationID.String()),
+		)
+		return fmt.Errorf("failed to reverse operation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestReverse_Success() {
+	walletID := uuid.New()
+	operationID := uuid.New()
+	amount := decimal.NewFromFloat(250.5)
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ReverseOperation(gomock.Any(), walletID, operationID, amount).
+		Return(&repository.Operation{Type: repository.OperationReversal}, nil)
+
+	err := s.walletService.Reverse(s.ctx, walletID, operationID, amount, "RUB")
+
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestReverse_InvalidAmount() {
+	err := s.walletService.Reverse(s.ctx, uuid.New(), uuid.New(), decimal.Zero, "")
+
+	s.ErrorIs(err, ErrInvalidAmount)
+}
+
+func (s *WalletServiceSuite) TestReverse_Errors() {
+	for _, repoErr := range []error{
+		repository.ErrOperationNotFound,
+		repository.ErrOperationNotReversible,
+		repository.ErrAlreadyReversed,
+		repository.ErrReversalExceedsOriginal,
+		repository.ErrInsufficientFunds,
+		repository.ErrWalletFrozen,
+	} {
+		walletID := uuid.New()
+		s.expectWallet(walletID, "RUB")
+		s.walletRepo.EXPECT().
+			ReverseOperation(gomock.Any(), walletID, gomock.Any(), gomock.Any()).
+			Return(nil, repoErr)
+
+		err := s.walletService.Reverse(s.ctx, walletID, uuid.New(), decimal.NewFromInt(1), "")
+
+		s.ErrorIs(err, repoErr)
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Statement formats.
+const (
+	StatementCSV   = "csv"
+	StatementJSONL = "jsonl"
+)
+
+var ErrInvalidStatementFormat = errors.New("invalid statement format")
+
+// StatementQuery selects the period and format of a statement. From is
+// inclusive and To is exclusive; a zero From starts at the first operation
+// and a zero To ends now. An empty Format means CSV.
+type StatementQuery struct {
+	From   time.Time
+	To     time.Time
+	Format string
+}
+
+// WriteStatement writes the statement of a wallet to w: the opening balance,
+// every operation of the period with its signed amount and the running
+// balance after it, and the closing balance. The wallet and query are
+// checked before anything is written, so an error returned after the first
+// write means the statement was cut short.
+func (s *walletService) WriteStatement(ctx context.Context, walletID uuid.UUID, query StatementQuery, w io.Writer) (err error) {
+	ctx, span := tracer.Start(ctx, "service.WriteStatement", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	switch query.Format {
+	case "":
+		query.Format = StatementCSV
+	case StatementCSV, StatementJSONL:
+	default:
+		return ErrInvalidStatementFormat
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if !query.From.IsZero() && !query.From.Before(query.To) {
+		return ErrInvalidTimeRange
+	}
+
+	wallet, err := s.repo.GetByID(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return ErrWalletNotFound
+		}
+		s.log.Error("failed to get wallet", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	var enc statementEncoder = newCSVStatement(w)
+	if query.Format == StatementJSONL {
+		enc = newJSONLStatement(w, wallet)
+	}
+
+	sw := &statementWriter{enc: enc, from: query.From.UTC(), to: query.To.UTC()}
+	if err = s.repo.Statement(ctx, walletID, sw.from, sw.to, sw); err == nil {
+		err = sw.close()
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return ErrWalletNotFound
+		}
+		s.log.Error("failed to write statement", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+	return nil
+}
+
+// statementEncoder writes the lines of a statement in one format.
+type statementEncoder interface {
+	opening(from, to time.Time, balance decimal.Decimal) error
+	operation(op Operation, amount, balance decimal.Decimal) error
+	closing(to time.Time, balance decimal.Decimal, operations int) error
+	flush() error
+}
+
+// statementWriter keeps the running balance of a statement as the repository
+// reads it. The signed amount of an operation is the change of the balance
+// it made, which also covers reversals of either direction.
+type statementWriter struct {
+	enc        statementEncoder
+	from, to   time.Time
+	balance    decimal.Decimal
+	operations int
+}
+
+func (sw *statementWriter) Opening(balance decimal.Decimal) error {
+	sw.balance = balance
+	return sw.enc.opening(sw.from, sw.to, balance)
+}
+
+func (sw *statementWriter) Operation(op Operation) error {
+	amount := op.BalanceAfter.Sub(sw.balance)
+	sw.balance = op.BalanceAfter
+	sw.operations++
+	return sw.enc.operation(op, amount, sw.balance)
+}
+
+func (sw *statementWriter) close() error {
+	if err := sw.enc.closing(sw.to, sw.balance, sw.operations); err != nil {
+		return err
+	}
+	return sw.enc.flush()
+}
+
+// Rows of a CSV statement that are not operations carry these in place of
+// the operation type.
+const (
+	statementOpening = "OPENING_BALANCE"
+	statementClosing = "CLOSING_BALANCE"
+)
+
+var csvStatementHeader = []string{"date", "operation_id", "operation_type", "amount", "balance", "transfer_id", "reversal_of"}
+
+type csvStatement struct {
+	w *csv.Writer
+}
+
+func newCSVStatement(w io.Writer) *csvStatement {
+	return &csvStatement{w: csv.NewWriter(w)}
+}
+
+func (c *csvStatement) opening(from, _ time.Time, balance decimal.Decimal) error {
+	if err := c.w.Write(csvStatementHeader); err != nil {
+		return err
+	}
+	return c.w.Write([]string{formatStatementTime(from), "", statementOpening, "", balance.String(), "", ""})
+}
+
+func (c *csvStatement) operation(op Operation, amount, balance decimal.Decimal) error {
+	return c.w.Write([]string{
+		formatStatementTime(op.CreatedAt),
+		op.ID.String(),
+		op.Type,
+		amount.String(),
+		balance.String(),
+		formatStatementID(op.TransferID),
+		formatStatementID(op.ReversalOf),
+	})
+}
+
+func (c *csvStatement) closing(to time.Time, balance decimal.Decimal, _ int) error {
+	return c.w.Write([]string{formatStatementTime(to), "", statementClosing, "", balance.String(), "", ""})
+}
+
+func (c *csvStatement) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlStatement writes one JSON object per line, told apart by their type.
+type jsonlStatement struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	wallet *repository.Wallet
+}
+
+type statementOpeningLine struct {
+	Type     string `json:"type"`
+	WalletID string `json:"walletId"`
+	Currency string `json:"currency"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to"`
+	Balance  string `json:"balance"`
+}
+
+type statementOperationLine struct {
+	Type          string `json:"type"`
+	ID            string `json:"id"`
+	OperationType string `json:"operationType"`
+	Amount        string `json:"amount"`
+	Balance       string `json:"balance"`
+	TransferID    string `json:"transferId,omitempty"`
+	ReversalOf    string `json:"reversalOf,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type statementClosingLine struct {
+	Type       string `json:"type"`
+	Balance    string `json:"balance"`
+	Operations int    `json:"operations"`
+}
+
+func newJSONLStatement(w io.Writer, wallet *repository.Wallet) *jsonlStatement {
+	bw := bufio.NewWriter(w)
+	return &jsonlStatement{w: bw, enc: json.NewEncoder(bw), wallet: wallet}
+}
+
+func (j *jsonlStatement) opening(from, to time.Time, balance decimal.Decimal) error {
+	return j.enc.Encode(statementOpeningLine{
+		Type:     "opening",
+		WalletID: j.wallet.ID.String(),
+		Currency: j.wallet.Currency,
+		From:     formatStatementTime(from),
+		To:       formatStatementTime(to),
+		Balance:  balance.String(),
+	})
+}
+
+func (j *jsonlStatement) operation(op Operation, amount, balance decimal.Decimal) error {
+	return j.enc.Encode(statementOperationLine{
+		Type:          "operation",
+		ID:            op.ID.String(),
+		OperationType: op.Type,
+		Amount:        amount.String(),
+		Balance:       balance.String(),
+		TransferID:    formatStatementID(op.TransferID),
+		ReversalOf:    formatStatementID(op.ReversalOf),
+		CreatedAt:     formatStatementTime(op.CreatedAt),
+	})
+}
+
+func (j *jsonlStatement) closing(_ time.Time, balance decimal.Decimal, operations int) error {
+	return j.enc.Encode(statementClosingLine{Type: "closing", Balance: balance.String(), Operations: operations})
+}
+
+func (j *jsonlStatement) flush() error {
+	return j.w.Flush()
+}
+
+// formatStatementTime formats t the way the API does, or as an empty string
+// if it is zero.
+func formatStatementTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func formatStatementID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
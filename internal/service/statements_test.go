@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+// expectStatement makes the repository read an opening balance of 100, a
+// withdrawal of 30 and the reversal of 10 of it.
+func (s *WalletServiceSuite) expectStatement(walletID uuid.UUID, from, to time.Time) (withdrawal, reversal repository.Operation) {
+	createdAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	withdrawal = repository.Operation{
+		ID:           uuid.MustParse("0194b1a0-0000-7000-8000-000000000001"),
+		WalletID:     walletID,
+		Type:         repository.OperationWithdraw,
+		Amount:       decimal.RequireFromString("30"),
+		BalanceAfter: decimal.RequireFromString("70"),
+		CreatedAt:    createdAt,
+	}
+	reversal = repository.Operation{
+		ID:           uuid.MustParse("0194b1a0-0000-7000-8000-000000000002"),
+		WalletID:     walletID,
+		Type:         repository.OperationReversal,
+		Amount:       decimal.RequireFromString("10"),
+		BalanceAfter: decimal.RequireFromString("80"),
+		ReversalOf:   &withdrawal.ID,
+		CreatedAt:    createdAt.Add(time.Hour),
+	}
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		Statement(gomock.Any(), walletID, from, to, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, _, _ time.Time, w repository.StatementWriter) error {
+			s.Require().NoError(w.Opening(decimal.RequireFromString("100")))
+			s.Require().NoError(w.Operation(withdrawal))
+			s.Require().NoError(w.Operation(reversal))
+			return nil
+		})
+	return withdrawal, reversal
+}
+
+func (s *WalletServiceSuite) TestWriteStatement_CSV() {
+	walletID := uuid.New()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	withdrawal, reversal := s.expectStatement(walletID, from, to)
+
+	var out strings.Builder
+	err := s.walletService.WriteStatement(s.ctx, walletID, StatementQuery{From: from, To: to}, &out)
+
+	s.NoError(err)
+	s.Equal(`date,operation_id,operation_type,amount,balance,transfer_id,reversal_of
+2025-01-01T00:00:00Z,,OPENING_BALANCE,,100,,
+2025-01-15T10:00:00Z,`+withdrawal.ID.String()+`,WITHDRAW,-30,70,,
+2025-01-15T11:00:00Z,`+reversal.ID.String()+`,REVERSAL,10,80,,`+withdrawal.ID.String()+`
+2025-02-01T00:00:00Z,,CLOSING_BALANCE,,80,,
+`, out.String())
+}
+
+func (s *WalletServiceSuite) TestWriteStatement_JSONL() {
+	walletID := uuid.New()
+	// Bounds are passed to the repository in UTC.
+	msk := time.FixedZone("MSK", 3*60*60)
+	from := time.Date(2025, 1, 1, 3, 0, 0, 0, msk)
+	to := time.Date(2025, 2, 1, 3, 0, 0, 0, msk)
+	withdrawal, reversal := s.expectStatement(walletID, from.UTC(), to.UTC())
+
+	var out strings.Builder
+	err := s.walletService.WriteStatement(s.ctx, walletID, StatementQuery{From: from, To: to, Format: StatementJSONL}, &out)
+
+	s.NoError(err)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	s.Require().Len(lines, 4)
+	s.JSONEq(`{"type":"opening","walletId":"`+walletID.String()+`","currency":"RUB","from":"2025-01-01T00:00:00Z","to":"2025-02-01T00:00:00Z","balance":"100"}`, lines[0])
+	s.JSONEq(`{"type":"operation","id":"`+withdrawal.ID.String()+`","operationType":"WITHDRAW","amount":"-30","balance":"70","createdAt":"2025-01-15T10:00:00Z"}`, lines[1])
+	s.JSONEq(`{"type":"operation","id":"`+reversal.ID.String()+`","operationType":"REVERSAL","amount":"10","balance":"80","reversalOf":"`+withdrawal.ID.String()+`","createdAt":"2025-01-15T11:00:00Z"}`, lines[2])
+	s.JSONEq(`{"type":"closing","balance":"80","operations":2}`, lines[3])
+}
+
+func (s *WalletServiceSuite) TestWriteStatement_InvalidQuery() {
+	now := time.Now()
+	for _, tt := range []struct {
+		query StatementQuery
+		err   error
+	}{
+		{StatementQuery{Format: "xlsx"}, ErrInvalidStatementFormat},
+		{StatementQuery{From: now, To: now}, ErrInvalidTimeRange},
+		{StatementQuery{From: now.Add(time.Hour)}, ErrInvalidTimeRange},
+	} {
+		var out strings.Builder
+		err := s.walletService.WriteStatement(s.ctx, uuid.New(), tt.query, &out)
+
+		s.ErrorIs(err, tt.err)
+		s.Empty(out.String())
+	}
+}
+
+func (s *WalletServiceSuite) TestWriteStatement_WalletNotFound() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), walletID).
+		Return(nil, repository.ErrWalletNotFound)
+
+	var out strings.Builder
+	err := s.walletService.WriteStatement(s.ctx, walletID, StatementQuery{}, &out)
+
+	s.ErrorIs(err, ErrWalletNotFound)
+	s.Empty(out.String())
+}
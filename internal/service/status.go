@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrInvalidWalletStatus     = errors.New("status must be ACTIVE, FROZEN or CLOSED")
+	ErrStatusReasonRequired    = errors.New("reason and actor are required")
+	ErrWalletFrozen            = repository.ErrWalletFrozen
+	ErrWalletClosed            = repository.ErrWalletClosed
+	ErrInvalidStatusTransition = repository.ErrInvalidStatusTransition
+	ErrWalletNotEmpty          = repository.ErrWalletNotEmpty
+)
+
+type (
+	StatusUpdate = repository.StatusUpdate
+	StatusChange = repository.StatusChange
+)
+
+// SetWalletStatus freezes, unfreezes or closes a wallet. The change waits for
+// operations already queued on the wallet and is recorded with its reason
+// and actor. DepositsBlocked is ignored unless the wallet is being frozen.
+func (s *walletService) SetWalletStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (_ *StatusChange, err error) {
+	ctx, span := tracer.Start(ctx, "service.SetWalletStatus", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
+	switch update.Status {
+	case repository.WalletActive, repository.WalletFrozen, repository.WalletClosed:
+	default:
+		return nil, ErrInvalidWalletStatus
+	}
+	update.Reason = strings.TrimSpace(update.Reason)
+	update.Actor = strings.TrimSpace(update.Actor)
+	if update.Reason == "" || update.Actor == "" {
+		return nil, ErrStatusReasonRequired
+	}
+	update.DepositsBlocked = update.DepositsBlocked && update.Status == repository.WalletFrozen
+
+	ctx, unlock, err := s.lock(ctx, walletID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	change, err := s.repo.SetStatus(ctx, walletID, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrWalletNotFound):
+			return nil, ErrWalletNotFound
+		case errors.Is(err, repository.ErrInvalidStatusTransition):
+			return nil, ErrInvalidStatusTransition
+		case errors.Is(err, repository.ErrWalletNotEmpty):
+			return nil, ErrWalletNotEmpty
+		}
+		s.log.Error("failed to set wallet status", slog.String("error", err.Error()), slog.String("wallet_id", walletID.String()))
+		return nil, fmt.Errorf("failed to set wallet status: %w", err)
+	}
+
+	s.log.Info("wallet status changed",
+		slog.String("wallet_id", walletID.String()),
+		slog.String("from_status", change.FromStatus),
+		slog.String("to_status", change.ToStatus),
+		slog.Bool("deposits_blocked", change.DepositsBlocked),
+		slog.String("reason", change.Reason),
+		slog.String("actor", change.Actor),
+	)
+	return change, nil
+}
+
+// isStatusError reports whether err was returned because the wallet status
+// does not allow the operation.
+func isStatusError(err error) bool {
+	return errors.Is(err, ErrWalletFrozen) || errors.Is(err, ErrWalletClosed)
+}
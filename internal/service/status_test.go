@@ -0,0 +1,91 @@
+package service
+
+import (
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/mock/gomock"
+)
+
+func (s *WalletServiceSuite) TestSetWalletStatus_Success() {
+	walletID := uuid.New()
+	change := &repository.StatusChange{ID: uuid.New(), WalletID: walletID, FromStatus: repository.WalletActive, ToStatus: repository.WalletFrozen}
+
+	s.walletRepo.EXPECT().
+		SetStatus(gomock.Any(), walletID, repository.StatusUpdate{
+			Status:          repository.WalletFrozen,
+			DepositsBlocked: true,
+			Reason:          "fraud",
+			Actor:           "compliance",
+		}).
+		Return(change, nil)
+
+	result, err := s.walletService.SetWalletStatus(s.ctx, walletID, StatusUpdate{
+		Status:          repository.WalletFrozen,
+		DepositsBlocked: true,
+		Reason:          " fraud ",
+		Actor:           "compliance",
+	})
+
+	s.NoError(err)
+	s.Equal(change, result)
+}
+
+func (s *WalletServiceSuite) TestSetWalletStatus_DepositsBlockedOnlyWhenFrozen() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		SetStatus(gomock.Any(), walletID, repository.StatusUpdate{
+			Status: repository.WalletActive,
+			Reason: "cleared",
+			Actor:  "compliance",
+		}).
+		Return(&repository.StatusChange{}, nil)
+
+	_, err := s.walletService.SetWalletStatus(s.ctx, walletID, StatusUpdate{
+		Status:          repository.WalletActive,
+		DepositsBlocked: true,
+		Reason:          "cleared",
+		Actor:           "compliance",
+	})
+
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestSetWalletStatus_Validation() {
+	_, err := s.walletService.SetWalletStatus(s.ctx, uuid.New(), StatusUpdate{Status: "DELETED", Reason: "r", Actor: "a"})
+	s.ErrorIs(err, ErrInvalidWalletStatus)
+
+	_, err = s.walletService.SetWalletStatus(s.ctx, uuid.New(), StatusUpdate{Status: repository.WalletFrozen, Reason: " ", Actor: "a"})
+	s.ErrorIs(err, ErrStatusReasonRequired)
+
+	_, err = s.walletService.SetWalletStatus(s.ctx, uuid.New(), StatusUpdate{Status: repository.WalletFrozen, Reason: "r"})
+	s.ErrorIs(err, ErrStatusReasonRequired)
+}
+
+func (s *WalletServiceSuite) TestSetWalletStatus_NotEmpty() {
+	walletID := uuid.New()
+
+	s.walletRepo.EXPECT().
+		SetStatus(gomock.Any(), walletID, gomock.Any()).
+		Return(nil, repository.ErrWalletNotEmpty)
+
+	_, err := s.walletService.SetWalletStatus(s.ctx, walletID, StatusUpdate{Status: repository.WalletClosed, Reason: "r", Actor: "a"})
+
+	s.ErrorIs(err, ErrWalletNotEmpty)
+}
+
+func (s *WalletServiceSuite) TestWithdraw_WalletFrozen() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(100)
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), walletID, repository.OperationWithdraw, amount).
+		Return(repository.ErrWalletFrozen)
+
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
+
+	s.ErrorIs(err, ErrWalletFrozen)
+}
@@ -4,62 +4,151 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"time"
 
 	"ITK/internal/repository"
+	"ITK/internal/tracing"
+	"ITK/pkg/currency"
 	pkgsync "ITK/pkg/sync"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("ITK/internal/service")
+
 var (
-	ErrInvalidAmount     = errors.New("amount must be positive")
-	ErrWalletNotFound    = repository.ErrWalletNotFound
-	ErrInsufficientFunds = repository.ErrInsufficientFunds
+	ErrInvalidAmount          = errors.New("amount must be positive")
+	ErrSameWallet             = errors.New("source and destination wallets must differ")
+	ErrUnsupportedCurrency    = errors.New("unsupported currency")
+	ErrCurrencyMismatch       = errors.New("currency does not match wallet currency")
+	ErrInvalidAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+	ErrWalletNotFound         = repository.ErrWalletNotFound
+	ErrInsufficientFunds      = repository.ErrInsufficientFunds
+	ErrCommitUnknown          = repository.ErrCommitUnknown
 )
 
 //go:generate go run go.uber.org/mock/mockgen@latest -destination=wallet_mock.go -source=wallet.go -package=service
 type Service interface {
-	CreateWallet(ctx context.Context) (uuid.UUID, error)
+	CreateWallet(ctx context.Context, currency string) (uuid.UUID, error)
 	GetBalance(ctx context.Context, walletID uuid.UUID) (*WalletBalance, error)
-	Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error
-	Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error
+	Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error
+	Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error
+	Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal, currency string) error
+	ListOperations(ctx context.Context, walletID uuid.UUID, query OperationsQuery) (*OperationsPage, error)
+	BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*HistoricalBalance, error)
+	WriteStatement(ctx context.Context, walletID uuid.UUID, query StatementQuery, w io.Writer) error
+	PlaceHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error)
+	GetHold(ctx context.Context, holdID uuid.UUID) (*Hold, error)
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*Hold, error)
+	ReleaseHold(ctx context.Context, holdID uuid.UUID) (*Hold, error)
+	SetWalletStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error)
+	PutLimitPolicy(ctx context.Context, policy LimitPolicy) (*LimitPolicy, error)
+	ListLimitPolicies(ctx context.Context) ([]LimitPolicy, error)
+	SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) error
+	Reverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal, currency string) error
+	TrialBalance(ctx context.Context) (*TrialBalance, error)
+	ReconcileWallet(ctx context.Context, walletID uuid.UUID) (*Reconciliation, error)
+	ReconcileAll(ctx context.Context, scan ReconcileScan) (*ReconcileSummary, error)
 }
 
+// WalletBalance holds the ledger balance and the part of it that is not
+// reserved by active holds.
 type WalletBalance struct {
-	WalletID uuid.UUID       `json:"walletId"`
-	Balance  decimal.Decimal `json:"balance"`
+	WalletID  uuid.UUID       `json:"walletId"`
+	Currency  string          `json:"currency"`
+	Status    string          `json:"status"`
+	Balance   decimal.Decimal `json:"balance"`
+	Available decimal.Decimal `json:"available"`
+}
+
+type Config struct {
+	DefaultCurrency string
+	HoldTTL         time.Duration
+	HoldMaxTTL      time.Duration
+	// LockTimeout bounds the wait for a wallet's in-process lock. Zero waits
+	// for as long as the request context allows.
+	LockTimeout time.Duration
+	// Locker serializes operations per wallet. Nil means an in-process
+	// KeyedMutex.
+	Locker pkgsync.Locker
+	// GroupCommit coalesces deposits and withdrawals queued on the same
+	// wallet into one transaction of up to MaxBatch operations.
+	GroupCommit bool
+	MaxBatch    int
 }
 
 type walletService struct {
-	repo       repository.Repository
-	log        *slog.Logger
-	walletLock *pkgsync.KeyedMutex
+	repo            repository.Repository
+	log             *slog.Logger
+	walletLock      pkgsync.Locker
+	defaultCurrency string
+	holdTTL         time.Duration
+	holdMaxTTL      time.Duration
+	lockTimeout     time.Duration
+	currencies      *currencyCache
+	// batches is nil unless group commit is enabled.
+	batches *batcher
 }
 
-func New(repo repository.Repository, log *slog.Logger) Service {
-	return &walletService{
-		repo:       repo,
-		log:        log.With(slog.String("component", "service/wallet")),
-		walletLock: pkgsync.NewKeyedMutex(),
+func New(repo repository.Repository, log *slog.Logger, cfg Config) Service {
+	locker := cfg.Locker
+	if locker == nil {
+		locker = pkgsync.NewKeyedMutex()
 	}
+
+	s := &walletService{
+		repo:            repo,
+		log:             log.With(slog.String("component", "service/wallet")),
+		walletLock:      locker,
+		defaultCurrency: cfg.DefaultCurrency,
+		holdTTL:         cfg.HoldTTL,
+		holdMaxTTL:      cfg.HoldMaxTTL,
+		lockTimeout:     cfg.LockTimeout,
+		currencies:      newCurrencyCache(currencyCacheSize),
+	}
+	if cfg.GroupCommit {
+		s.batches = newBatcher(cfg.MaxBatch)
+	}
+	return s
 }
 
-func (s *walletService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+// CreateWallet creates an empty wallet in the given ISO 4217 currency. An
+// empty currency uses the configured default.
+func (s *walletService) CreateWallet(ctx context.Context, currencyCode string) (_ uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "service.CreateWallet")
+	defer func() { tracing.End(span, err) }()
+
+	if currencyCode == "" {
+		currencyCode = s.defaultCurrency
+	}
+	cur, ok := currency.Lookup(currencyCode)
+	if !ok {
+		return uuid.Nil, ErrUnsupportedCurrency
+	}
+
 	walletID := uuid.New()
+	span.SetAttributes(tracing.WalletID(walletID))
 
-	err := s.repo.Create(ctx, walletID)
+	err = s.repo.Create(ctx, walletID, cur.Code)
 	if err != nil {
 		s.log.Error("failed to create wallet", slog.String("error", err.Error()))
 		return uuid.Nil, fmt.Errorf("failed to create wallet: %w", err)
 	}
+	s.currencies.add(walletID, cur)
 
-	s.log.Debug("wallet created", slog.String("wallet_id", walletID.String()))
+	s.log.Debug("wallet created", slog.String("wallet_id", walletID.String()), slog.String("currency", cur.Code))
 	return walletID, nil
 }
 
-func (s *walletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*WalletBalance, error) {
+func (s *walletService) GetBalance(ctx context.Context, walletID uuid.UUID) (_ *WalletBalance, err error) {
+	ctx, span := tracer.Start(ctx, "service.GetBalance", trace.WithAttributes(tracing.WalletID(walletID)))
+	defer func() { tracing.End(span, err) }()
+
 	wallet, err := s.repo.GetByID(ctx, walletID)
 	if err != nil {
 		if errors.Is(err, repository.ErrWalletNotFound) {
@@ -70,22 +159,36 @@ func (s *walletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*Wa
 	}
 
 	return &WalletBalance{
-		WalletID: wallet.ID,
-		Balance:  wallet.Balance,
+		WalletID:  wallet.ID,
+		Currency:  wallet.Currency,
+		Status:    wallet.Status,
+		Balance:   wallet.Balance,
+		Available: wallet.Balance.Sub(wallet.Held),
 	}, nil
 }
 
-func (s *walletService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (s *walletService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currencyCode string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Deposit", trace.WithAttributes(
+		tracing.WalletID(walletID),
+		tracing.OperationType(repository.OperationDeposit),
+	))
+	defer func() {
+		recordOperation("deposit", err)
+		tracing.End(span, err)
+	}()
+
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return ErrInvalidAmount
 	}
+	if _, err = s.checkAmount(ctx, walletID, amount, currencyCode); err != nil {
+		return err
+	}
 
-	key := walletID.String()
-	s.walletLock.Lock(key)
-	defer s.walletLock.Unlock(key)
-
-	err := s.repo.ApplyOperation(ctx, walletID, "DEPOSIT", amount)
+	err = s.applyOperation(ctx, walletID, repository.BatchOperation{Type: repository.OperationDeposit, Amount: amount})
 	if err != nil {
+		if isLockError(err) || isStatusError(err) || errors.Is(err, ErrLimitExceeded) {
+			return err
+		}
 		if errors.Is(err, repository.ErrWalletNotFound) {
 			return ErrWalletNotFound
 		}
@@ -96,17 +199,28 @@ func (s *walletService) Deposit(ctx context.Context, walletID uuid.UUID, amount
 	return nil
 }
 
-func (s *walletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (s *walletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currencyCode string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Withdraw", trace.WithAttributes(
+		tracing.WalletID(walletID),
+		tracing.OperationType(repository.OperationWithdraw),
+	))
+	defer func() {
+		recordOperation("withdraw", err)
+		tracing.End(span, err)
+	}()
+
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return ErrInvalidAmount
 	}
+	if _, err = s.checkAmount(ctx, walletID, amount, currencyCode); err != nil {
+		return err
+	}
 
-	key := walletID.String()
-	s.walletLock.Lock(key)
-	defer s.walletLock.Unlock(key)
-
-	err := s.repo.ApplyOperation(ctx, walletID, "WITHDRAW", amount)
+	err = s.applyOperation(ctx, walletID, repository.BatchOperation{Type: repository.OperationWithdraw, Amount: amount})
 	if err != nil {
+		if isLockError(err) || isStatusError(err) || errors.Is(err, ErrLimitExceeded) {
+			return err
+		}
 		if errors.Is(err, repository.ErrWalletNotFound) {
 			return ErrWalletNotFound
 		}
@@ -120,3 +234,73 @@ func (s *walletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount
 	return nil
 }
 
+// applyOperation runs a deposit or withdrawal under the wallet lock, or
+// queues it for group commit when that is enabled.
+func (s *walletService) applyOperation(ctx context.Context, walletID uuid.UUID, op repository.BatchOperation) error {
+	if s.batches != nil {
+		return s.applyBatched(ctx, walletID, op)
+	}
+
+	ctx, unlock, err := s.lock(ctx, walletID.String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.repo.ApplyOperation(ctx, walletID, op.Type, op.Amount)
+}
+
+func (s *walletService) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal, currencyCode string) (err error) {
+	ctx, span := tracer.Start(ctx, "service.Transfer", trace.WithAttributes(
+		tracing.FromWalletID(fromID),
+		tracing.ToWalletID(toID),
+	))
+	defer func() {
+		recordOperation("transfer", err)
+		tracing.End(span, err)
+	}()
+
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return ErrInvalidAmount
+	}
+	if fromID == toID {
+		return ErrSameWallet
+	}
+	fromCurrency, err := s.checkAmount(ctx, fromID, amount, currencyCode)
+	if err != nil {
+		return err
+	}
+	// Both wallets must hold the same currency.
+	if _, err = s.checkAmount(ctx, toID, amount, fromCurrency.Code); err != nil {
+		return err
+	}
+
+	// Same ordering as the advisory locks taken by the repository.
+	ordered := repository.OrderWalletIDs(fromID, toID)
+	ctx, unlock, err := s.lock(ctx, ordered[0].String(), ordered[1].String())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = s.repo.Transfer(ctx, fromID, toID, amount)
+	if err != nil {
+		if isStatusError(err) || errors.Is(err, ErrLimitExceeded) {
+			return err
+		}
+		if errors.Is(err, repository.ErrWalletNotFound) {
+			return ErrWalletNotFound
+		}
+		if errors.Is(err, repository.ErrInsufficientFunds) {
+			return ErrInsufficientFunds
+		}
+		s.log.Error("failed to transfer",
+			slog.String("error", err.Error()),
+			slog.String("from_wallet_id", fromID.String()),
+			slog.String("to_wallet_id", toID.String()),
+		)
+		return fmt.Errorf("failed to transfer: %w", err)
+	}
+
+	return nil
+}
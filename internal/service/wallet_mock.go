@@ -11,7 +11,9 @@ package service
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	decimal "github.com/shopspring/decimal"
@@ -42,33 +44,63 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// BalanceAt mocks base method.
+func (m *MockService) BalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (*HistoricalBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BalanceAt", ctx, walletID, at)
+	ret0, _ := ret[0].(*HistoricalBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceAt indicates an expected call of BalanceAt.
+func (mr *MockServiceMockRecorder) BalanceAt(ctx, walletID, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceAt", reflect.TypeOf((*MockService)(nil).BalanceAt), ctx, walletID, at)
+}
+
+// CaptureHold mocks base method.
+func (m *MockService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount decimal.Decimal) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, holdID, amount)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockServiceMockRecorder) CaptureHold(ctx, holdID, amount any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockService)(nil).CaptureHold), ctx, holdID, amount)
+}
+
 // CreateWallet mocks base method.
-func (m *MockService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+func (m *MockService) CreateWallet(ctx context.Context, currency string) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateWallet", ctx)
+	ret := m.ctrl.Call(m, "CreateWallet", ctx, currency)
 	ret0, _ := ret[0].(uuid.UUID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateWallet indicates an expected call of CreateWallet.
-func (mr *MockServiceMockRecorder) CreateWallet(ctx any) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateWallet(ctx, currency any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockService)(nil).CreateWallet), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWallet", reflect.TypeOf((*MockService)(nil).CreateWallet), ctx, currency)
 }
 
 // Deposit mocks base method.
-func (m *MockService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (m *MockService) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deposit", ctx, walletID, amount)
+	ret := m.ctrl.Call(m, "Deposit", ctx, walletID, amount, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Deposit indicates an expected call of Deposit.
-func (mr *MockServiceMockRecorder) Deposit(ctx, walletID, amount any) *gomock.Call {
+func (mr *MockServiceMockRecorder) Deposit(ctx, walletID, amount, currency any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockService)(nil).Deposit), ctx, walletID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockService)(nil).Deposit), ctx, walletID, amount, currency)
 }
 
 // GetBalance mocks base method.
@@ -86,16 +118,222 @@ func (mr *MockServiceMockRecorder) GetBalance(ctx, walletID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockService)(nil).GetBalance), ctx, walletID)
 }
 
+// GetHold mocks base method.
+func (m *MockService) GetHold(ctx context.Context, holdID uuid.UUID) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockServiceMockRecorder) GetHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockService)(nil).GetHold), ctx, holdID)
+}
+
+// ListLimitPolicies mocks base method.
+func (m *MockService) ListLimitPolicies(ctx context.Context) ([]LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLimitPolicies", ctx)
+	ret0, _ := ret[0].([]LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLimitPolicies indicates an expected call of ListLimitPolicies.
+func (mr *MockServiceMockRecorder) ListLimitPolicies(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLimitPolicies", reflect.TypeOf((*MockService)(nil).ListLimitPolicies), ctx)
+}
+
+// ListOperations mocks base method.
+func (m *MockService) ListOperations(ctx context.Context, walletID uuid.UUID, query OperationsQuery) (*OperationsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOperations", ctx, walletID, query)
+	ret0, _ := ret[0].(*OperationsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOperations indicates an expected call of ListOperations.
+func (mr *MockServiceMockRecorder) ListOperations(ctx, walletID, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOperations", reflect.TypeOf((*MockService)(nil).ListOperations), ctx, walletID, query)
+}
+
+// PlaceHold mocks base method.
+func (m *MockService) PlaceHold(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, ttl time.Duration) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceHold", ctx, walletID, amount, ttl)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceHold indicates an expected call of PlaceHold.
+func (mr *MockServiceMockRecorder) PlaceHold(ctx, walletID, amount, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceHold", reflect.TypeOf((*MockService)(nil).PlaceHold), ctx, walletID, amount, ttl)
+}
+
+// PutLimitPolicy mocks base method.
+func (m *MockService) PutLimitPolicy(ctx context.Context, policy LimitPolicy) (*LimitPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutLimitPolicy", ctx, policy)
+	ret0, _ := ret[0].(*LimitPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutLimitPolicy indicates an expected call of PutLimitPolicy.
+func (mr *MockServiceMockRecorder) PutLimitPolicy(ctx, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutLimitPolicy", reflect.TypeOf((*MockService)(nil).PutLimitPolicy), ctx, policy)
+}
+
+// ReconcileAll mocks base method.
+func (m *MockService) ReconcileAll(ctx context.Context, scan ReconcileScan) (*ReconcileSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileAll", ctx, scan)
+	ret0, _ := ret[0].(*ReconcileSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileAll indicates an expected call of ReconcileAll.
+func (mr *MockServiceMockRecorder) ReconcileAll(ctx, scan any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileAll", reflect.TypeOf((*MockService)(nil).ReconcileAll), ctx, scan)
+}
+
+// ReconcileWallet mocks base method.
+func (m *MockService) ReconcileWallet(ctx context.Context, walletID uuid.UUID) (*Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileWallet", ctx, walletID)
+	ret0, _ := ret[0].(*Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileWallet indicates an expected call of ReconcileWallet.
+func (mr *MockServiceMockRecorder) ReconcileWallet(ctx, walletID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileWallet", reflect.TypeOf((*MockService)(nil).ReconcileWallet), ctx, walletID)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockService) ReleaseHold(ctx context.Context, holdID uuid.UUID) (*Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID)
+	ret0, _ := ret[0].(*Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockServiceMockRecorder) ReleaseHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockService)(nil).ReleaseHold), ctx, holdID)
+}
+
+// Reverse mocks base method.
+func (m *MockService) Reverse(ctx context.Context, walletID, operationID uuid.UUID, amount decimal.Decimal, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", ctx, walletID, operationID, amount, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockServiceMockRecorder) Reverse(ctx, walletID, operationID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockService)(nil).Reverse), ctx, walletID, operationID, amount, currency)
+}
+
+// SetWalletLimits mocks base method.
+func (m *MockService) SetWalletLimits(ctx context.Context, walletID uuid.UUID, limits WalletLimits) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWalletLimits", ctx, walletID, limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWalletLimits indicates an expected call of SetWalletLimits.
+func (mr *MockServiceMockRecorder) SetWalletLimits(ctx, walletID, limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWalletLimits", reflect.TypeOf((*MockService)(nil).SetWalletLimits), ctx, walletID, limits)
+}
+
+// SetWalletStatus mocks base method.
+func (m *MockService) SetWalletStatus(ctx context.Context, walletID uuid.UUID, update StatusUpdate) (*StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWalletStatus", ctx, walletID, update)
+	ret0, _ := ret[0].(*StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetWalletStatus indicates an expected call of SetWalletStatus.
+func (mr *MockServiceMockRecorder) SetWalletStatus(ctx, walletID, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWalletStatus", reflect.TypeOf((*MockService)(nil).SetWalletStatus), ctx, walletID, update)
+}
+
+// Transfer mocks base method.
+func (m *MockService) Transfer(ctx context.Context, fromID, toID uuid.UUID, amount decimal.Decimal, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, fromID, toID, amount, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockServiceMockRecorder) Transfer(ctx, fromID, toID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockService)(nil).Transfer), ctx, fromID, toID, amount, currency)
+}
+
+// TrialBalance mocks base method.
+func (m *MockService) TrialBalance(ctx context.Context) (*TrialBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrialBalance", ctx)
+	ret0, _ := ret[0].(*TrialBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrialBalance indicates an expected call of TrialBalance.
+func (mr *MockServiceMockRecorder) TrialBalance(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrialBalance", reflect.TypeOf((*MockService)(nil).TrialBalance), ctx)
+}
+
 // Withdraw mocks base method.
-func (m *MockService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal) error {
+func (m *MockService) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, currency string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Withdraw", ctx, walletID, amount)
+	ret := m.ctrl.Call(m, "Withdraw", ctx, walletID, amount, currency)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Withdraw indicates an expected call of Withdraw.
-func (mr *MockServiceMockRecorder) Withdraw(ctx, walletID, amount any) *gomock.Call {
+func (mr *MockServiceMockRecorder) Withdraw(ctx, walletID, amount, currency any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockService)(nil).Withdraw), ctx, walletID, amount, currency)
+}
+
+// WriteStatement mocks base method.
+func (m *MockService) WriteStatement(ctx context.Context, walletID uuid.UUID, query StatementQuery, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteStatement", ctx, walletID, query, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteStatement indicates an expected call of WriteStatement.
+func (mr *MockServiceMockRecorder) WriteStatement(ctx, walletID, query, w any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockService)(nil).Withdraw), ctx, walletID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteStatement", reflect.TypeOf((*MockService)(nil).WriteStatement), ctx, walletID, query, w)
 }
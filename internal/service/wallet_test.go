@@ -5,9 +5,13 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"ITK/internal/repository"
+	"ITK/pkg/currency"
+	pkgsync "ITK/pkg/sync"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -37,21 +41,35 @@ func (s *WalletServiceSuite) SetupTest() {
 	s.ctx = context.Background()
 
 	s.walletService = &walletService{
-		repo: s.walletRepo,
-		log:  s.logger,
+		repo:            s.walletRepo,
+		log:             s.logger,
+		walletLock:      pkgsync.NewKeyedMutex(),
+		defaultCurrency: "RUB",
+		holdTTL:         time.Hour,
+		holdMaxTTL:      24 * time.Hour,
+		currencies:      newCurrencyCache(currencyCacheSize),
 	}
 }
 
+// expectWallet lets the service resolve the wallet currency, which it loads
+// for the first operation on the wallet.
+func (s *WalletServiceSuite) expectWallet(walletID uuid.UUID, currency string) {
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), walletID).
+		Return(&repository.Wallet{ID: walletID, Currency: currency}, nil).
+		AnyTimes()
+}
+
 func (s *WalletServiceSuite) TearDownTest() {
 	s.ctrl.Finish()
 }
 
 func (s *WalletServiceSuite) TestCreateWallet_Success() {
 	s.walletRepo.EXPECT().
-		Create(s.ctx, gomock.Any()).
+		Create(gomock.Any(), gomock.Any(), "RUB").
 		Return(nil)
 
-	walletID, err := s.walletService.CreateWallet(s.ctx)
+	walletID, err := s.walletService.CreateWallet(s.ctx, "")
 
 	s.NoError(err)
 	s.NotEqual(uuid.Nil, walletID)
@@ -61,10 +79,10 @@ func (s *WalletServiceSuite) TestCreateWallet_RepositoryError() {
 	repoError := errors.New("database error")
 
 	s.walletRepo.EXPECT().
-		Create(s.ctx, gomock.Any()).
+		Create(gomock.Any(), gomock.Any(), "RUB").
 		Return(repoError)
 
-	walletID, err := s.walletService.CreateWallet(s.ctx)
+	walletID, err := s.walletService.CreateWallet(s.ctx, "")
 
 	s.Error(err)
 	s.Equal(uuid.Nil, walletID)
@@ -78,10 +96,11 @@ func (s *WalletServiceSuite) TestGetBalance_Success() {
 	wallet := &repository.Wallet{
 		ID:      walletID,
 		Balance: balance,
+		Held:    decimal.NewFromFloat(100.25),
 	}
 
 	s.walletRepo.EXPECT().
-		GetByID(s.ctx, walletID).
+		GetByID(gomock.Any(), walletID).
 		Return(wallet, nil)
 
 	result, err := s.walletService.GetBalance(s.ctx, walletID)
@@ -90,13 +109,14 @@ func (s *WalletServiceSuite) TestGetBalance_Success() {
 	s.NotNil(result)
 	s.Equal(walletID, result.WalletID)
 	s.True(result.Balance.Equal(balance))
+	s.True(result.Available.Equal(decimal.NewFromFloat(400.25)))
 }
 
 func (s *WalletServiceSuite) TestGetBalance_WalletNotFound() {
 	walletID := uuid.New()
 
 	s.walletRepo.EXPECT().
-		GetByID(s.ctx, walletID).
+		GetByID(gomock.Any(), walletID).
 		Return(nil, repository.ErrWalletNotFound)
 
 	result, err := s.walletService.GetBalance(s.ctx, walletID)
@@ -110,11 +130,12 @@ func (s *WalletServiceSuite) TestDeposit_Success() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(1000)
 
+	s.expectWallet(walletID, "RUB")
 	s.walletRepo.EXPECT().
-		ApplyOperation(s.ctx, walletID, "DEPOSIT", amount).
+		ApplyOperation(gomock.Any(), walletID, "DEPOSIT", amount).
 		Return(nil)
 
-	err := s.walletService.Deposit(s.ctx, walletID, amount)
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
 
 	s.NoError(err)
 }
@@ -123,7 +144,7 @@ func (s *WalletServiceSuite) TestDeposit_InvalidAmount_Zero() {
 	walletID := uuid.New()
 	amount := decimal.Zero
 
-	err := s.walletService.Deposit(s.ctx, walletID, amount)
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrInvalidAmount)
@@ -133,7 +154,7 @@ func (s *WalletServiceSuite) TestDeposit_InvalidAmount_Negative() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(-100)
 
-	err := s.walletService.Deposit(s.ctx, walletID, amount)
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrInvalidAmount)
@@ -143,25 +164,113 @@ func (s *WalletServiceSuite) TestDeposit_WalletNotFound() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(1000)
 
+	s.expectWallet(walletID, "RUB")
 	s.walletRepo.EXPECT().
-		ApplyOperation(s.ctx, walletID, "DEPOSIT", amount).
+		ApplyOperation(gomock.Any(), walletID, "DEPOSIT", amount).
 		Return(repository.ErrWalletNotFound)
 
-	err := s.walletService.Deposit(s.ctx, walletID, amount)
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrWalletNotFound)
 }
 
+func (s *WalletServiceSuite) TestDeposit_WalletBusy() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+	s.walletService.lockTimeout = 10 * time.Millisecond
+
+	s.expectWallet(walletID, "RUB")
+	s.Require().NoError(s.walletService.walletLock.LockContext(s.ctx, walletID.String()))
+	defer s.walletService.walletLock.Unlock(walletID.String())
+
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
+
+	s.ErrorIs(err, ErrWalletBusy)
+}
+
+func (s *WalletServiceSuite) TestWithdraw_RequestAborted() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	s.expectWallet(walletID, "RUB")
+	s.Require().NoError(s.walletService.walletLock.LockContext(s.ctx, walletID.String()))
+	defer s.walletService.walletLock.Unlock(walletID.String())
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Millisecond)
+	defer cancel()
+
+	// The operation must not reach the repository once the caller gave up.
+	err := s.walletService.Withdraw(ctx, walletID, amount, "")
+
+	s.ErrorIs(err, ErrRequestAborted)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *WalletServiceSuite) TestDeposit_WalletOwnedByAnotherInstance() {
+	amount := decimal.NewFromFloat(1000)
+	ring := pkgsync.NewRing([]string{"a", "b"}, 0)
+	s.walletService.walletLock = pkgsync.NewRingLocker(ring, "a", pkgsync.NewKeyedMutex())
+
+	walletID := uuid.New()
+	for ring.Owner(walletID.String()) != "b" {
+		walletID = uuid.New()
+	}
+
+	s.expectWallet(walletID, "RUB")
+
+	err := s.walletService.Deposit(s.ctx, walletID, amount, "")
+
+	s.ErrorIs(err, ErrWalletNotOwned)
+	var notOwner *NotOwnerError
+	s.Require().ErrorAs(err, &notOwner)
+	s.Equal("b", notOwner.Owner)
+}
+
+// losingLocker is a KeyedMutex whose locks are lost when lose is called.
+type losingLocker struct {
+	*pkgsync.KeyedMutex
+	held context.Context
+	lose context.CancelCauseFunc
+}
+
+func (l *losingLocker) HeldContext(string) context.Context {
+	return l.held
+}
+
+func (s *WalletServiceSuite) TestWithdraw_LockLostCancelsOperation() {
+	walletID := uuid.New()
+	amount := decimal.NewFromFloat(500)
+	held, lose := context.WithCancelCause(context.Background())
+	s.walletService.walletLock = &losingLocker{KeyedMutex: pkgsync.NewKeyedMutex(), held: held, lose: lose}
+
+	s.expectWallet(walletID, "RUB")
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), walletID, "WITHDRAW", amount).
+		DoAndReturn(func(ctx context.Context, _ uuid.UUID, _ string, _ decimal.Decimal) error {
+			s.NoError(ctx.Err())
+			lose(pkgsync.ErrLockLost)
+			<-ctx.Done()
+			s.ErrorIs(context.Cause(ctx), pkgsync.ErrLockLost)
+			return ctx.Err()
+		})
+
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
+
+	s.ErrorIs(err, context.Canceled)
+	s.Zero(s.walletService.walletLock.Len())
+}
+
 func (s *WalletServiceSuite) TestWithdraw_Success() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(500)
 
+	s.expectWallet(walletID, "RUB")
 	s.walletRepo.EXPECT().
-		ApplyOperation(s.ctx, walletID, "WITHDRAW", amount).
+		ApplyOperation(gomock.Any(), walletID, "WITHDRAW", amount).
 		Return(nil)
 
-	err := s.walletService.Withdraw(s.ctx, walletID, amount)
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
 
 	s.NoError(err)
 }
@@ -170,7 +279,7 @@ func (s *WalletServiceSuite) TestWithdraw_InvalidAmount_Zero() {
 	walletID := uuid.New()
 	amount := decimal.Zero
 
-	err := s.walletService.Withdraw(s.ctx, walletID, amount)
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrInvalidAmount)
@@ -180,7 +289,7 @@ func (s *WalletServiceSuite) TestWithdraw_InvalidAmount_Negative() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(-100)
 
-	err := s.walletService.Withdraw(s.ctx, walletID, amount)
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrInvalidAmount)
@@ -190,11 +299,12 @@ func (s *WalletServiceSuite) TestWithdraw_InsufficientFunds() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(1000)
 
+	s.expectWallet(walletID, "RUB")
 	s.walletRepo.EXPECT().
-		ApplyOperation(s.ctx, walletID, "WITHDRAW", amount).
+		ApplyOperation(gomock.Any(), walletID, "WITHDRAW", amount).
 		Return(repository.ErrInsufficientFunds)
 
-	err := s.walletService.Withdraw(s.ctx, walletID, amount)
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrInsufficientFunds)
@@ -204,12 +314,235 @@ func (s *WalletServiceSuite) TestWithdraw_WalletNotFound() {
 	walletID := uuid.New()
 	amount := decimal.NewFromFloat(500)
 
+	s.expectWallet(walletID, "RUB")
 	s.walletRepo.EXPECT().
-		ApplyOperation(s.ctx, walletID, "WITHDRAW", amount).
+		ApplyOperation(gomock.Any(), walletID, "WITHDRAW", amount).
 		Return(repository.ErrWalletNotFound)
 
-	err := s.walletService.Withdraw(s.ctx, walletID, amount)
+	err := s.walletService.Withdraw(s.ctx, walletID, amount, "")
 
 	s.Error(err)
 	s.ErrorIs(err, ErrWalletNotFound)
 }
+
+func (s *WalletServiceSuite) TestTransfer_Success() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(250)
+
+	s.expectWallet(fromID, "RUB")
+	s.expectWallet(toID, "RUB")
+	s.walletRepo.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, amount).
+		Return(nil)
+
+	err := s.walletService.Transfer(s.ctx, fromID, toID, amount, "")
+
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestTransfer_WalletBusyReleasesLocks() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(250)
+	s.walletService.lockTimeout = 10 * time.Millisecond
+
+	s.expectWallet(fromID, "RUB")
+	s.expectWallet(toID, "RUB")
+	s.Require().NoError(s.walletService.walletLock.LockContext(s.ctx, toID.String()))
+	defer s.walletService.walletLock.Unlock(toID.String())
+
+	err := s.walletService.Transfer(s.ctx, fromID, toID, amount, "")
+
+	s.ErrorIs(err, ErrWalletBusy)
+	// Only the lock held by the test is left.
+	s.Equal(1, s.walletService.walletLock.Len())
+}
+
+func (s *WalletServiceSuite) TestTransfer_InvalidAmount() {
+	err := s.walletService.Transfer(s.ctx, uuid.New(), uuid.New(), decimal.Zero, "")
+
+	s.Error(err)
+	s.ErrorIs(err, ErrInvalidAmount)
+}
+
+func (s *WalletServiceSuite) TestTransfer_SameWallet() {
+	walletID := uuid.New()
+
+	err := s.walletService.Transfer(s.ctx, walletID, walletID, decimal.NewFromFloat(100), "")
+
+	s.Error(err)
+	s.ErrorIs(err, ErrSameWallet)
+}
+
+func (s *WalletServiceSuite) TestTransfer_InsufficientFunds() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(1000)
+
+	s.expectWallet(fromID, "RUB")
+	s.expectWallet(toID, "RUB")
+	s.walletRepo.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, amount).
+		Return(repository.ErrInsufficientFunds)
+
+	err := s.walletService.Transfer(s.ctx, fromID, toID, amount, "")
+
+	s.Error(err)
+	s.ErrorIs(err, ErrInsufficientFunds)
+}
+
+func (s *WalletServiceSuite) TestTransfer_WalletNotFound() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	amount := decimal.NewFromFloat(100)
+
+	s.expectWallet(fromID, "RUB")
+	s.expectWallet(toID, "RUB")
+	s.walletRepo.EXPECT().
+		Transfer(gomock.Any(), fromID, toID, amount).
+		Return(repository.ErrWalletNotFound)
+
+	err := s.walletService.Transfer(s.ctx, fromID, toID, amount, "")
+
+	s.Error(err)
+	s.ErrorIs(err, ErrWalletNotFound)
+}
+
+func (s *WalletServiceSuite) TestTransfer_OppositeDirectionsDoNotDeadlock() {
+	a := uuid.New()
+	b := uuid.New()
+	amount := decimal.NewFromFloat(1)
+
+	s.expectWallet(a, "RUB")
+	s.expectWallet(b, "RUB")
+	s.walletRepo.EXPECT().
+		Transfer(gomock.Any(), gomock.Any(), gomock.Any(), amount).
+		Return(nil).
+		Times(200)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.NoError(s.walletService.Transfer(s.ctx, a, b, amount, ""))
+		}()
+		go func() {
+			defer wg.Done()
+			s.NoError(s.walletService.Transfer(s.ctx, b, a, amount, ""))
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *WalletServiceSuite) TestCreateWallet_WithCurrency() {
+	s.walletRepo.EXPECT().
+		Create(gomock.Any(), gomock.Any(), "JPY").
+		Return(nil)
+
+	walletID, err := s.walletService.CreateWallet(s.ctx, "jpy")
+
+	s.NoError(err)
+	s.NotEqual(uuid.Nil, walletID)
+}
+
+func (s *WalletServiceSuite) TestCreateWallet_UnsupportedCurrency() {
+	_, err := s.walletService.CreateWallet(s.ctx, "XYZ")
+
+	s.ErrorIs(err, ErrUnsupportedCurrency)
+}
+
+func (s *WalletServiceSuite) TestDeposit_CurrencyMismatch() {
+	walletID := uuid.New()
+	s.expectWallet(walletID, "USD")
+
+	err := s.walletService.Deposit(s.ctx, walletID, decimal.NewFromInt(10), "EUR")
+
+	s.ErrorIs(err, ErrCurrencyMismatch)
+}
+
+func (s *WalletServiceSuite) TestDeposit_PrecisionPerCurrency() {
+	jpy := uuid.New()
+	bhd := uuid.New()
+	s.expectWallet(jpy, "JPY")
+	s.expectWallet(bhd, "BHD")
+
+	err := s.walletService.Deposit(s.ctx, jpy, decimal.RequireFromString("10.5"), "")
+	s.ErrorIs(err, ErrInvalidAmountPrecision)
+
+	err = s.walletService.Deposit(s.ctx, bhd, decimal.RequireFromString("1.2345"), "")
+	s.ErrorIs(err, ErrInvalidAmountPrecision)
+
+	amount := decimal.RequireFromString("1.234")
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), bhd, "DEPOSIT", amount).
+		Return(nil)
+
+	err = s.walletService.Deposit(s.ctx, bhd, amount, "BHD")
+	s.NoError(err)
+}
+
+func (s *WalletServiceSuite) TestDeposit_LoadsCurrencyOnce() {
+	walletID := uuid.New()
+	amount := decimal.NewFromInt(10)
+
+	s.walletRepo.EXPECT().
+		GetByID(gomock.Any(), walletID).
+		Return(&repository.Wallet{ID: walletID, Currency: "RUB"}, nil).
+		Times(1)
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), walletID, "DEPOSIT", amount).
+		Return(nil).
+		Times(3)
+
+	for range 3 {
+		s.NoError(s.walletService.Deposit(s.ctx, walletID, amount, ""))
+	}
+}
+
+func (s *WalletServiceSuite) TestCreateWallet_CachesCurrency() {
+	amount := decimal.NewFromInt(10)
+
+	s.walletRepo.EXPECT().
+		Create(gomock.Any(), gomock.Any(), "USD").
+		Return(nil)
+	walletID, err := s.walletService.CreateWallet(s.ctx, "USD")
+	s.Require().NoError(err)
+
+	s.walletRepo.EXPECT().
+		ApplyOperation(gomock.Any(), walletID, "DEPOSIT", amount).
+		Return(nil)
+
+	s.NoError(s.walletService.Deposit(s.ctx, walletID, amount, "USD"))
+}
+
+func (s *WalletServiceSuite) TestCurrencyCache_EvictsLeastRecentlyUsed() {
+	cache := newCurrencyCache(2)
+	rub := currency.Currency{Code: "RUB", MinorUnits: 2}
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	cache.add(a, rub)
+	cache.add(b, rub)
+	_, ok := cache.get(a)
+	s.True(ok)
+	cache.add(c, rub)
+
+	_, ok = cache.get(b)
+	s.False(ok)
+	_, ok = cache.get(a)
+	s.True(ok)
+	_, ok = cache.get(c)
+	s.True(ok)
+}
+
+func (s *WalletServiceSuite) TestTransfer_CurrencyMismatch() {
+	fromID := uuid.New()
+	toID := uuid.New()
+	s.expectWallet(fromID, "USD")
+	s.expectWallet(toID, "EUR")
+
+	err := s.walletService.Transfer(s.ctx, fromID, toID, decimal.NewFromInt(10), "")
+
+	s.ErrorIs(err, ErrCurrencyMismatch)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+
+	"ITK/internal/repository"
+	"ITK/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 200
+
+	minWebhookSecretLength = 16
+)
+
+var (
+	ErrInvalidWebhookURL           = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookSecret        = errors.New("webhook secret is too short")
+	ErrUnknownEventType            = errors.New("unknown event type")
+	ErrInvalidDeliveryStatus       = errors.New("invalid delivery status")
+	ErrWebhookSubscriptionNotFound = repository.ErrWebhookSubscriptionNotFound
+	ErrWebhookDeliveryNotFound     = repository.ErrWebhookDeliveryNotFound
+)
+
+// EventTypes lists the events a webhook can subscribe to.
+var EventTypes = []string{repository.EventOperationCreated}
+
+type (
+	WebhookSubscription = repository.WebhookSubscription
+	WebhookDelivery     = repository.WebhookDelivery
+)
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=webhooks_mock.go -source=webhooks.go -package=service
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, url, secret string, eventTypes []string) (*WebhookSubscription, error)
+	// ListSubscriptions returns the subscriptions without their secrets.
+	ListSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) ([]WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, id uuid.UUID) (*WebhookDelivery, error)
+	ReplayDeadDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+}
+
+type webhookService struct {
+	repo repository.WebhookRepository
+	log  *slog.Logger
+}
+
+func NewWebhooks(repo repository.WebhookRepository, log *slog.Logger) WebhookService {
+	return &webhookService{
+		repo: repo,
+		log:  log.With(slog.String("component", "service/webhooks")),
+	}
+}
+
+// CreateSubscription registers a webhook endpoint. An empty secret is
+// replaced by a random one; the returned subscription is the only place the
+// caller can read it back.
+func (s *webhookService) CreateSubscription(ctx context.Context, rawURL, secret string, eventTypes []string) (_ *WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "service.CreateSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(EventTypes, t) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, t)
+		}
+	}
+
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	} else if len(secret) < minWebhookSecretLength {
+		return nil, ErrInvalidWebhookSecret
+	}
+
+	sub, err := s.repo.CreateSubscription(ctx, u.String(), secret, eventTypes)
+	if err != nil {
+		s.log.Error("failed to create webhook subscription", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	s.log.Info("webhook subscription created", slog.String("subscription_id", sub.ID.String()), slog.String("url", sub.URL))
+	return sub, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) (_ []WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "service.ListSubscriptions")
+	defer func() { tracing.End(span, err) }()
+
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		s.log.Error("failed to list webhook subscriptions", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "service.DeleteSubscription", trace.WithAttributes(tracing.SubscriptionID(id)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+			return ErrWebhookSubscriptionNotFound
+		}
+		s.log.Error("failed to delete webhook subscription", slog.String("error", err.Error()), slog.String("subscription_id", id.String()))
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	s.log.Info("webhook subscription deleted", slog.String("subscription_id", id.String()))
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, limit int) (_ []WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "service.ListDeliveries", trace.WithAttributes(tracing.SubscriptionID(subscriptionID)))
+	defer func() { tracing.End(span, err) }()
+
+	switch status {
+	case "", repository.DeliveryPending, repository.DeliveryDelivered, repository.DeliveryDead:
+	default:
+		return nil, ErrInvalidDeliveryStatus
+	}
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+	limit = min(limit, MaxDeliveriesLimit)
+
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, status, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+			return nil, ErrWebhookSubscriptionNotFound
+		}
+		s.log.Error("failed to list webhook deliveries", slog.String("error", err.Error()), slog.String("subscription_id", subscriptionID.String()))
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, id uuid.UUID) (_ *WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "service.ReplayDelivery", trace.WithAttributes(tracing.DeliveryID(id)))
+	defer func() { tracing.End(span, err) }()
+
+	delivery, err := s.repo.ReplayDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		s.log.Error("failed to replay webhook delivery", slog.String("error", err.Error()), slog.String("delivery_id", id.String()))
+		return nil, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) ReplayDeadDeliveries(ctx context.Context, subscriptionID uuid.UUID) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "service.ReplayDeadDeliveries", trace.WithAttributes(tracing.SubscriptionID(subscriptionID)))
+	defer func() { tracing.End(span, err) }()
+
+	replayed, err := s.repo.ReplayDeadDeliveries(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+			return 0, ErrWebhookSubscriptionNotFound
+		}
+		s.log.Error("failed to replay webhook deliveries", slog.String("error", err.Error()), slog.String("subscription_id", subscriptionID.String()))
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+
+	s.log.Info("dead webhook deliveries replayed", slog.String("subscription_id", subscriptionID.String()), slog.Int64("count", replayed))
+	return replayed, nil
+}
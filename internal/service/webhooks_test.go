@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"ITK/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type WebhookServiceSuite struct {
+	suite.Suite
+
+	ctrl    *gomock.Controller
+	repo    *repository.MockWebhookRepository
+	service WebhookService
+	ctx     context.Context
+}
+
+func TestWebhookService(t *testing.T) {
+	suite.Run(t, &WebhookServiceSuite{})
+}
+
+func (s *WebhookServiceSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.repo = repository.NewMockWebhookRepository(s.ctrl)
+	s.service = NewWebhooks(s.repo, slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	s.ctx = context.Background()
+}
+
+func (s *WebhookServiceSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func (s *WebhookServiceSuite) TestCreateSubscription_GeneratesSecret() {
+	var secret string
+	s.repo.EXPECT().
+		CreateSubscription(gomock.Any(), "https://example.com/hook", gomock.Any(), []string{repository.EventOperationCreated}).
+		DoAndReturn(func(_ context.Context, url, sec string, types []string) (*repository.WebhookSubscription, error) {
+			secret = sec
+			return &repository.WebhookSubscription{ID: uuid.New(), URL: url, Secret: sec, EventTypes: types}, nil
+		})
+
+	sub, err := s.service.CreateSubscription(s.ctx, "https://example.com/hook", "", []string{repository.EventOperationCreated})
+
+	s.NoError(err)
+	s.Len(secret, 64)
+	s.Equal(secret, sub.Secret)
+}
+
+func (s *WebhookServiceSuite) TestCreateSubscription_InvalidURL() {
+	for _, url := range []string{"", "example.com/hook", "ftp://example.com/hook", "https://"} {
+		_, err := s.service.CreateSubscription(s.ctx, url, "", nil)
+		s.ErrorIs(err, ErrInvalidWebhookURL, url)
+	}
+}
+
+func (s *WebhookServiceSuite) TestCreateSubscription_UnknownEventType() {
+	_, err := s.service.CreateSubscription(s.ctx, "https://example.com/hook", "", []string{"wallet.deleted"})
+
+	s.ErrorIs(err, ErrUnknownEventType)
+}
+
+func (s *WebhookServiceSuite) TestCreateSubscription_ShortSecret() {
+	_, err := s.service.CreateSubscription(s.ctx, "https://example.com/hook", "secret", nil)
+
+	s.ErrorIs(err, ErrInvalidWebhookSecret)
+}
+
+func (s *WebhookServiceSuite) TestDeleteSubscription_NotFound() {
+	id := uuid.New()
+	s.repo.EXPECT().
+		DeleteSubscription(gomock.Any(), id).
+		Return(repository.ErrWebhookSubscriptionNotFound)
+
+	err := s.service.DeleteSubscription(s.ctx, id)
+
+	s.ErrorIs(err, ErrWebhookSubscriptionNotFound)
+}
+
+func (s *WebhookServiceSuite) TestListDeliveries_ClampsLimit() {
+	id := uuid.New()
+	s.repo.EXPECT().
+		ListDeliveries(gomock.Any(), id, repository.DeliveryDead, MaxDeliveriesLimit).
+		Return([]repository.WebhookDelivery{}, nil)
+	s.repo.EXPECT().
+		ListDeliveries(gomock.Any(), id, "", DefaultDeliveriesLimit).
+		Return([]repository.WebhookDelivery{}, nil)
+
+	_, err := s.service.ListDeliveries(s.ctx, id, repository.DeliveryDead, 1000)
+	s.NoError(err)
+
+	_, err = s.service.ListDeliveries(s.ctx, id, "", 0)
+	s.NoError(err)
+}
+
+func (s *WebhookServiceSuite) TestListDeliveries_InvalidStatus() {
+	_, err := s.service.ListDeliveries(s.ctx, uuid.New(), "FAILED", 10)
+
+	s.ErrorIs(err, ErrInvalidDeliveryStatus)
+}
+
+func (s *WebhookServiceSuite) TestReplayDelivery_NotFound() {
+	id := uuid.New()
+	s.repo.EXPECT().
+		ReplayDelivery(gomock.Any(), id).
+		Return(nil, repository.ErrWebhookDeliveryNotFound)
+
+	_, err := s.service.ReplayDelivery(s.ctx, id)
+
+	s.ErrorIs(err, ErrWebhookDeliveryNotFound)
+}
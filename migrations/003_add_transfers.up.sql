@@ -0,0 +1,7 @@
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_operation_type_check;
+ALTER TABLE operations ADD CONSTRAINT operations_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER_IN', 'TRANSFER_OUT'));
+
+ALTER TABLE operations ADD COLUMN transfer_id UUID;
+
+CREATE INDEX idx_operations_transfer_id ON operations(transfer_id) WHERE transfer_id IS NOT NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_operations_wallet_created_id;
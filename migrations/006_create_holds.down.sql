@@ -0,0 +1,6 @@
+ALTER TABLE operations DROP COLUMN IF EXISTS hold_id;
+
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_held_within_balance;
+ALTER TABLE wallets DROP COLUMN IF EXISTS held;
@@ -0,0 +1,18 @@
+ALTER TABLE wallets ADD COLUMN held NUMERIC(20, 2) NOT NULL DEFAULT 0 CHECK (held >= 0);
+ALTER TABLE wallets ADD CONSTRAINT wallets_held_within_balance CHECK (held <= balance);
+
+CREATE TABLE holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    amount NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    captured_amount NUMERIC(20, 2) NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_holds_wallet_id ON holds(wallet_id);
+CREATE INDEX idx_holds_active_expires_at ON holds(expires_at) WHERE status = 'ACTIVE';
+
+ALTER TABLE operations ADD COLUMN hold_id UUID REFERENCES holds(id);
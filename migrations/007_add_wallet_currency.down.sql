@@ -0,0 +1,22 @@
+-- The previous schema only holds roubles with two decimal places; anything
+-- else would be rounded or relabelled.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM wallets WHERE currency <> 'RUB') THEN
+        RAISE EXCEPTION 'wallets hold currencies other than RUB, which the previous schema cannot tell apart';
+    END IF;
+    IF EXISTS (SELECT 1 FROM wallets WHERE balance <> ROUND(balance, 2) OR held <> ROUND(held, 2))
+        OR EXISTS (SELECT 1 FROM operations WHERE amount <> ROUND(amount, 2) OR balance_after <> ROUND(balance_after, 2))
+        OR EXISTS (SELECT 1 FROM holds WHERE amount <> ROUND(amount, 2) OR captured_amount <> ROUND(captured_amount, 2)) THEN
+        RAISE EXCEPTION 'amounts have three decimal places, which NUMERIC(20, 2) would round';
+    END IF;
+END $$;
+
+ALTER TABLE holds ALTER COLUMN captured_amount TYPE NUMERIC(20, 2);
+ALTER TABLE holds ALTER COLUMN amount TYPE NUMERIC(20, 2);
+ALTER TABLE operations ALTER COLUMN balance_after TYPE NUMERIC(20, 2);
+ALTER TABLE operations ALTER COLUMN amount TYPE NUMERIC(20, 2);
+ALTER TABLE wallets ALTER COLUMN held TYPE NUMERIC(20, 2);
+ALTER TABLE wallets ALTER COLUMN balance TYPE NUMERIC(20, 2);
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,10 @@
+ALTER TABLE wallets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+
+-- Wide enough for currencies with three minor units (BHD, KWD, ...).
+-- Per-currency precision is enforced by the service.
+ALTER TABLE wallets ALTER COLUMN balance TYPE NUMERIC(21, 3);
+ALTER TABLE wallets ALTER COLUMN held TYPE NUMERIC(21, 3);
+ALTER TABLE operations ALTER COLUMN amount TYPE NUMERIC(21, 3);
+ALTER TABLE operations ALTER COLUMN balance_after TYPE NUMERIC(21, 3);
+ALTER TABLE holds ALTER COLUMN amount TYPE NUMERIC(21, 3);
+ALTER TABLE holds ALTER COLUMN captured_amount TYPE NUMERIC(21, 3);
@@ -0,0 +1,16 @@
+ALTER TABLE wallets ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'FROZEN', 'CLOSED'));
+ALTER TABLE wallets ADD COLUMN deposits_blocked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE wallets ADD CONSTRAINT wallets_closed_empty CHECK (status <> 'CLOSED' OR balance = 0);
+
+CREATE TABLE wallet_status_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    deposits_blocked BOOLEAN NOT NULL,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    actor TEXT NOT NULL CHECK (actor <> ''),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_status_changes_wallet_id ON wallet_status_changes(wallet_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE wallets DROP COLUMN IF EXISTS limit_policy;
+ALTER TABLE wallets DROP COLUMN IF EXISTS tier;
+
+DROP TABLE IF EXISTS limit_policies;
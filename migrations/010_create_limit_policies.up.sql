@@ -0,0 +1,22 @@
+-- Limits are amounts in the currency of the policy, with the same precision
+-- as wallet balances.
+CREATE TABLE limit_policies (
+    name VARCHAR(64) PRIMARY KEY,
+    tier VARCHAR(64),
+    currency CHAR(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    max_withdrawal NUMERIC(21, 3) CHECK (max_withdrawal > 0),
+    daily_withdrawal NUMERIC(21, 3) CHECK (daily_withdrawal > 0),
+    monthly_withdrawal NUMERIC(21, 3) CHECK (monthly_withdrawal > 0),
+    max_balance NUMERIC(21, 3) CHECK (max_balance > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- A tier has one policy per currency.
+    UNIQUE (tier, currency),
+    UNIQUE (name, currency)
+);
+
+ALTER TABLE wallets ADD COLUMN tier VARCHAR(64);
+ALTER TABLE wallets ADD COLUMN limit_policy VARCHAR(64);
+-- A wallet can only be assigned a policy of its own currency.
+ALTER TABLE wallets ADD CONSTRAINT wallets_limit_policy_currency_fkey
+    FOREIGN KEY (limit_policy, currency) REFERENCES limit_policies(name, currency);
@@ -0,0 +1,17 @@
+-- Reversals have moved money; dropping them would leave balances that the
+-- remaining history does not explain.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM operations WHERE operation_type = 'REVERSAL') THEN
+        RAISE EXCEPTION 'operations contain reversals, which the previous schema cannot hold';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_operations_reversal_of;
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_reversal_of_check;
+ALTER TABLE operations DROP COLUMN IF EXISTS reversal_of;
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_operation_type_check;
+ALTER TABLE operations ADD CONSTRAINT operations_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER_IN', 'TRANSFER_OUT'));
@@ -0,0 +1,9 @@
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_operation_type_check;
+ALTER TABLE operations ADD CONSTRAINT operations_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER_IN', 'TRANSFER_OUT', 'REVERSAL'));
+
+ALTER TABLE operations ADD COLUMN reversal_of UUID REFERENCES operations(id);
+ALTER TABLE operations ADD CONSTRAINT operations_reversal_of_check
+    CHECK ((operation_type = 'REVERSAL') = (reversal_of IS NOT NULL));
+
+CREATE INDEX idx_operations_reversal_of ON operations(reversal_of) WHERE reversal_of IS NOT NULL;
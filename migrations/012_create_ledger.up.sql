@@ -0,0 +1,77 @@
+-- Every wallet is a WALLET account with the wallet's ID. SYSTEM accounts are
+-- the other side of money entering and leaving the system, one per code and
+-- currency.
+CREATE TABLE ledger_accounts (
+    id UUID PRIMARY KEY,
+    account_type VARCHAR(10) NOT NULL CHECK (account_type IN ('WALLET', 'SYSTEM')),
+    code VARCHAR(32),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT ledger_accounts_code_check CHECK ((account_type = 'SYSTEM') = (code IS NOT NULL)),
+    UNIQUE (code, currency)
+);
+
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY,
+    entry_type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A posting adds amount to the balance of its account. The postings of an
+-- entry sum to zero in every currency.
+CREATE TABLE postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    operation_id UUID REFERENCES operations(id),
+    amount NUMERIC(21, 3) NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_postings_entry_id ON postings(entry_id);
+CREATE INDEX idx_postings_account_id ON postings(account_id);
+
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM postings p
+        JOIN ledger_accounts a ON a.id = p.account_id
+        WHERE p.entry_id = NEW.entry_id
+        GROUP BY a.currency
+        HAVING SUM(p.amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Checked at commit, once every posting of the entry is written.
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT OR UPDATE ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Existing balances are opened against OPENING_BALANCE, one entry per
+-- wallet with the wallet's ID.
+INSERT INTO ledger_accounts (id, account_type, currency)
+SELECT id, 'WALLET', currency FROM wallets;
+
+INSERT INTO ledger_accounts (id, account_type, code, currency)
+SELECT gen_random_uuid(), 'SYSTEM', code, currency
+FROM (SELECT DISTINCT currency FROM wallets) c
+CROSS JOIN (VALUES ('CASH_IN'), ('CASH_OUT'), ('OPENING_BALANCE')) AS s(code);
+
+INSERT INTO journal_entries (id, entry_type)
+SELECT id, 'OPENING_BALANCE' FROM wallets WHERE balance <> 0;
+
+INSERT INTO postings (entry_id, account_id, amount)
+SELECT id, id, balance FROM wallets WHERE balance <> 0;
+
+INSERT INTO postings (entry_id, account_id, amount)
+SELECT w.id, a.id, -w.balance
+FROM wallets w
+JOIN ledger_accounts a ON a.code = 'OPENING_BALANCE' AND a.currency = w.currency
+WHERE w.balance <> 0;
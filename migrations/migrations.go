@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations, so the binaries carry the
+// schema they were built against.
+package migrations
+
+import "embed"
+
+// FS holds the NNN_name.up.sql and NNN_name.down.sql files of this directory.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,58 @@
+package currency
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxMinorUnits is the largest number of decimal places a supported currency
+// uses. Money columns in the database are sized for it.
+const MaxMinorUnits = 3
+
+type Currency struct {
+	Code       string
+	MinorUnits int32
+}
+
+// ISO 4217 currencies accepted by the service, with their minor units.
+var currencies = map[string]Currency{
+	"AED": {Code: "AED", MinorUnits: 2},
+	"AMD": {Code: "AMD", MinorUnits: 2},
+	"AZN": {Code: "AZN", MinorUnits: 2},
+	"BHD": {Code: "BHD", MinorUnits: 3},
+	"BYN": {Code: "BYN", MinorUnits: 2},
+	"CHF": {Code: "CHF", MinorUnits: 2},
+	"CNY": {Code: "CNY", MinorUnits: 2},
+	"EUR": {Code: "EUR", MinorUnits: 2},
+	"GBP": {Code: "GBP", MinorUnits: 2},
+	"GEL": {Code: "GEL", MinorUnits: 2},
+	"INR": {Code: "INR", MinorUnits: 2},
+	"JOD": {Code: "JOD", MinorUnits: 3},
+	"JPY": {Code: "JPY", MinorUnits: 0},
+	"KGS": {Code: "KGS", MinorUnits: 2},
+	"KRW": {Code: "KRW", MinorUnits: 0},
+	"KWD": {Code: "KWD", MinorUnits: 3},
+	"KZT": {Code: "KZT", MinorUnits: 2},
+	"OMR": {Code: "OMR", MinorUnits: 3},
+	"RUB": {Code: "RUB", MinorUnits: 2},
+	"TJS": {Code: "TJS", MinorUnits: 2},
+	"TND": {Code: "TND", MinorUnits: 3},
+	"TRY": {Code: "TRY", MinorUnits: 2},
+	"UAH": {Code: "UAH", MinorUnits: 2},
+	"USD": {Code: "USD", MinorUnits: 2},
+	"UZS": {Code: "UZS", MinorUnits: 2},
+	"VND": {Code: "VND", MinorUnits: 0},
+}
+
+// Lookup returns the currency for an ISO 4217 code, case-insensitively.
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// Fits reports whether amount has no more decimal places than the currency
+// allows.
+func (c Currency) Fits(amount decimal.Decimal) bool {
+	return amount.Equal(amount.Truncate(c.MinorUnits))
+}
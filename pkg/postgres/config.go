@@ -16,6 +16,8 @@ type DBConfig struct {
 	MaxIdleConns    int
 	MaxOpenConns    int
 	ConnMaxLifetime int
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool
 }
 
 func (c *DBConfig) DSN() string {
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MigrationLockKey is the bigint advisory lock serialising Migrate across
+// replicas. Its top byte is the lock class 'M', so it cannot collide with the
+// per-wallet keys of class 'W'.
+const MigrationLockKey int64 = 0x4D << 56
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// the binary does not know about, i.e. it was migrated by a newer release.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// NewMigrate returns a migrate instance applying the migrations in fsys to the
+// database at databaseURL.
+func NewMigrate(fsys fs.FS, databaseURL string) (*migrate.Migrate, error) {
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init migrate: %w", err)
+	}
+	return m, nil
+}
+
+// LatestMigration returns the highest migration version in fsys.
+func LatestMigration(fsys fs.FS) (uint, error) {
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// Migrate applies the pending migrations in fsys. Replicas starting together
+// queue on MigrationLockKey, so the schema version is checked and upgraded by
+// one of them at a time. It refuses to touch a dirty schema or one newer than
+// fsys.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, cfg DBConfig, fsys fs.FS, logger Logger) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock connection: %w", err)
+	}
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", MigrationLockKey); err != nil {
+		closeConn(conn)
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		var unlocked bool
+		err := conn.QueryRow(context.Background(), "SELECT pg_advisory_unlock($1)", MigrationLockKey).Scan(&unlocked)
+		if err != nil || !unlocked {
+			closeConn(conn)
+			return
+		}
+		conn.Release()
+	}()
+
+	m, err := NewMigrate(fsys, cfg.DSN())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	from, err := checkSchema(m, fsys)
+	if err != nil {
+		return err
+	}
+
+	if err = m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			logger.Info("database schema is up to date", "version", from)
+			return nil
+		}
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	to, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	logger.Info("migrations applied", "from_version", from, "to_version", to)
+	return nil
+}
+
+// CheckSchema reports whether migrations from fsys are pending without
+// changing anything. It fails like Migrate on a dirty schema or one newer than
+// fsys.
+func CheckSchema(cfg DBConfig, fsys fs.FS) (pending bool, err error) {
+	m, err := NewMigrate(fsys, cfg.DSN())
+	if err != nil {
+		return false, err
+	}
+	defer m.Close()
+
+	version, err := checkSchema(m, fsys)
+	if err != nil {
+		return false, err
+	}
+	latest, err := LatestMigration(fsys)
+	if err != nil {
+		return false, err
+	}
+	return version < int(latest), nil
+}
+
+// checkSchema returns the applied schema version, -1 when none is.
+func checkSchema(m *migrate.Migrate, fsys fs.FS) (int, error) {
+	latest, err := LatestMigration(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("database schema is dirty at version %d, fix it and run the migrator's force command", version)
+	}
+	if version > latest {
+		return 0, fmt.Errorf("%w: version %d, latest known %d", ErrSchemaTooNew, version, latest)
+	}
+	return int(version), nil
+}
+
+// closeConn ends the session behind conn, which drops its advisory lock,
+// instead of returning it to the pool.
+func closeConn(conn *pgxpool.Conn) {
+	_ = conn.Hijack().Close(context.Background())
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := LatestMigration(fstest.MapFS{
+		"000_init.up.sql":    {Data: []byte("SELECT 1;")},
+		"000_init.down.sql":  {Data: []byte("SELECT 1;")},
+		"002_wallets.up.sql": {Data: []byte("SELECT 1;")},
+		"010_index.up.sql":   {Data: []byte("SELECT 1;")},
+		"010_index.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(10), latest)
+}
+
+func TestLatestMigrationEmpty(t *testing.T) {
+	_, err := LatestMigration(fstest.MapFS{})
+	assert.Error(t, err)
+}
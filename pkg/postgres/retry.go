@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	CodeSerializationFailure = "40001"
+	CodeDeadlockDetected     = "40P01"
+)
+
+// DefaultRetryableCodes are the SQLSTATEs after which rerunning the whole
+// transaction is expected to succeed.
+var DefaultRetryableCodes = []string{CodeSerializationFailure, CodeDeadlockDetected}
+
+// Retryable returns a classifier for retry.Policy that accepts server errors
+// with one of the given SQLSTATEs (DefaultRetryableCodes when empty) and
+// connection failures: resets, unexpected EOFs, closed connections and
+// anything pgx reports as safe to retry.
+//
+// The classifier cannot tell whether a COMMIT that lost its connection was
+// applied; callers must keep such errors away from it.
+func Retryable(codes []string) func(error) bool {
+	if len(codes) == 0 {
+		codes = DefaultRetryableCodes
+	}
+
+	return func(err error) bool {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return slices.Contains(codes, pgErr.Code)
+		}
+		return IsConnectionError(err)
+	}
+}
+
+// IsConnectionError reports whether err means the connection to the server
+// was lost or never established, as opposed to the server rejecting a query.
+func IsConnectionError(err error) bool {
+	// pgx closes the connection when a query is cancelled; that is the
+	// caller giving up, not the server going away.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	retryable := Retryable(nil)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: CodeSerializationFailure}, true},
+		{"deadlock", fmt.Errorf("failed to update balance: %w", &pgconn.PgError{Code: CodeDeadlockDetected}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryable(tt.err))
+		})
+	}
+}
+
+func TestRetryableCustomCodes(t *testing.T) {
+	retryable := Retryable([]string{"55P03"})
+
+	assert.True(t, retryable(&pgconn.PgError{Code: "55P03"}))
+	assert.False(t, retryable(&pgconn.PgError{Code: CodeSerializationFailure}))
+}
@@ -0,0 +1,49 @@
+package retry
+
+import "sync"
+
+// Budget limits retries across calls, in the spirit of gRPC retry
+// throttling. Every retry costs one token and every success earns Ratio
+// tokens back; retries stop while fewer than half of the tokens are left, so
+// a failing dependency sees roughly one attempt per call instead of
+// MaxAttempts. A nil *Budget never refuses a retry.
+type Budget struct {
+	mu     sync.Mutex
+	max    float64
+	ratio  float64
+	tokens float64
+}
+
+func NewBudget(maxTokens, ratio float64) *Budget {
+	return &Budget{
+		max:    maxTokens,
+		ratio:  ratio,
+		tokens: maxTokens,
+	}
+}
+
+func (b *Budget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens <= b.max/2 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *Budget) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, b.max)
+}
@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	JitterNone         = "none"
+	JitterFull         = "full"
+	JitterDecorrelated = "decorrelated"
+)
+
+var ErrExhausted = errors.New("too many retries")
+
+// ExhaustedError is returned when the attempts or the retry budget ran out.
+// It matches ErrExhausted and unwraps to the last error.
+type ExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("too many retries (%d attempts): %v", e.Attempts, e.Err)
+}
+
+func (e *ExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExhaustedError) Is(target error) bool {
+	return target == ErrExhausted
+}
+
+// Policy describes how a failed call is retried.
+type Policy struct {
+	// MaxAttempts includes the first call. Values below 1 mean one call.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay caps a single backoff. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is JitterNone, JitterFull or JitterDecorrelated. Empty means
+	// JitterFull.
+	Jitter string
+	// Budget, when set, is shared by all calls and stops retries while most
+	// recent calls are failing.
+	Budget *Budget
+	// Retryable reports whether an error is worth another attempt. Nil
+	// retries every error.
+	Retryable func(error) bool
+	// OnRetry is called before sleeping ahead of the next attempt.
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or the policy
+// runs out. Attempts are numbered from 1. Waiting between attempts ends early
+// when ctx is done, in which case the context error is returned together with
+// the last failure.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context, attempt int) error) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+	prev := p.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, attempt)
+		if err == nil {
+			p.Budget.success()
+			return nil
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if attempt >= maxAttempts || !p.Budget.withdraw() {
+			return &ExhaustedError{Attempts: attempt, Err: err}
+		}
+
+		delay := p.backoff(attempt, prev)
+		prev = delay
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry aborted after %d attempts: %w", attempt, errors.Join(ctx.Err(), err))
+		}
+	}
+}
+
+// backoff returns the delay after the given attempt. prev is the previous
+// delay, which decorrelated jitter grows from.
+func (p Policy) backoff(attempt int, prev time.Duration) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	var d time.Duration
+	switch p.Jitter {
+	case JitterDecorrelated:
+		// min(cap, random_between(base, prev * 3))
+		upper := max(prev*3, p.BaseDelay)
+		d = p.BaseDelay + rand.N(upper-p.BaseDelay+1)
+	default:
+		d = p.BaseDelay << min(attempt-1, 32)
+		if d <= 0 {
+			// Shifted past the int64 range.
+			d = time.Duration(1<<63 - 1)
+		}
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+
+	if p.Jitter == JitterFull || p.Jitter == "" {
+		d = rand.N(d + 1)
+	}
+	return d
+}
@@ -0,0 +1,191 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var errTransient = errors.New("transient")
+
+type RetrySuite struct {
+	suite.Suite
+
+	ctx context.Context
+}
+
+func TestRetry(t *testing.T) {
+	suite.Run(t, &RetrySuite{})
+}
+
+func (s *RetrySuite) SetupTest() {
+	s.ctx = context.Background()
+}
+
+func (s *RetrySuite) TestSucceedsAfterRetries() {
+	var retried []int
+	policy := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Microsecond,
+		OnRetry: func(attempt int, _ time.Duration, err error) {
+			s.ErrorIs(err, errTransient)
+			retried = append(retried, attempt)
+		},
+	}
+
+	err := policy.Do(s.ctx, func(_ context.Context, attempt int) error {
+		if attempt < 3 {
+			return errTransient
+		}
+		return nil
+	})
+
+	s.NoError(err)
+	s.Equal([]int{1, 2}, retried)
+}
+
+func (s *RetrySuite) TestExhaustedCarriesAttemptsAndLastError() {
+	policy := Policy{MaxAttempts: 3, BaseDelay: time.Microsecond}
+
+	calls := 0
+	err := policy.Do(s.ctx, func(context.Context, int) error {
+		calls++
+		return errTransient
+	})
+
+	s.Equal(3, calls)
+	s.ErrorIs(err, ErrExhausted)
+	s.ErrorIs(err, errTransient)
+	var exhausted *ExhaustedError
+	s.Require().ErrorAs(err, &exhausted)
+	s.Equal(3, exhausted.Attempts)
+}
+
+func (s *RetrySuite) TestNonRetryableErrorIsReturnedAsIs() {
+	policy := Policy{
+		MaxAttempts: 5,
+		Retryable:   func(err error) bool { return errors.Is(err, errTransient) },
+	}
+	permanent := errors.New("permanent")
+
+	calls := 0
+	err := policy.Do(s.ctx, func(context.Context, int) error {
+		calls++
+		return permanent
+	})
+
+	s.Equal(1, calls)
+	s.Equal(permanent, err)
+}
+
+func (s *RetrySuite) TestContextCancelStopsBackoff() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	policy := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Hour,
+		Jitter:      JitterNone,
+		OnRetry:     func(int, time.Duration, error) { cancel() },
+	}
+
+	start := time.Now()
+	err := policy.Do(ctx, func(context.Context, int) error {
+		return errTransient
+	})
+
+	s.Less(time.Since(start), time.Second)
+	s.ErrorIs(err, context.Canceled)
+	s.ErrorIs(err, errTransient)
+	s.NotErrorIs(err, ErrExhausted)
+}
+
+func (s *RetrySuite) TestBackoffWithoutJitterDoublesUpToCap() {
+	policy := Policy{
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay:  50 * time.Millisecond,
+		Jitter:    JitterNone,
+	}
+
+	var got []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		got = append(got, policy.backoff(attempt, 0))
+	}
+
+	s.Equal([]time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}, got)
+}
+
+func (s *RetrySuite) TestBackoffDoesNotOverflow() {
+	policy := Policy{BaseDelay: time.Second, Jitter: JitterNone}
+
+	s.Positive(policy.backoff(100, 0))
+}
+
+func (s *RetrySuite) TestFullJitterStaysBelowExponent() {
+	policy := Policy{
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay:  50 * time.Millisecond,
+		Jitter:    JitterFull,
+	}
+
+	for i := 0; i < 1000; i++ {
+		s.LessOrEqual(policy.backoff(2, 0), 20*time.Millisecond)
+		s.LessOrEqual(policy.backoff(10, 0), 50*time.Millisecond)
+		s.GreaterOrEqual(policy.backoff(10, 0), time.Duration(0))
+	}
+}
+
+func (s *RetrySuite) TestDecorrelatedJitterBounds() {
+	policy := Policy{
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay:  50 * time.Millisecond,
+		Jitter:    JitterDecorrelated,
+	}
+
+	prev := policy.BaseDelay
+	for i := 0; i < 1000; i++ {
+		d := policy.backoff(i+1, prev)
+		s.GreaterOrEqual(d, policy.BaseDelay)
+		s.LessOrEqual(d, min(prev*3, policy.MaxDelay))
+		prev = d
+	}
+}
+
+func (s *RetrySuite) TestBudgetStopsRetries() {
+	budget := NewBudget(4, 1)
+	policy := Policy{MaxAttempts: 10, Budget: budget}
+
+	calls := 0
+	err := policy.Do(s.ctx, func(context.Context, int) error {
+		calls++
+		return errTransient
+	})
+
+	// Two tokens can be spent before the budget drops to half.
+	s.Equal(3, calls)
+	s.ErrorIs(err, ErrExhausted)
+
+	calls = 0
+	err = policy.Do(s.ctx, func(context.Context, int) error {
+		calls++
+		return errTransient
+	})
+	s.Equal(1, calls)
+	s.ErrorIs(err, ErrExhausted)
+
+	// A success earns a token back, enough for one more retry.
+	s.NoError(policy.Do(s.ctx, func(context.Context, int) error { return nil }))
+	calls = 0
+	_ = policy.Do(s.ctx, func(context.Context, int) error {
+		calls++
+		return errTransient
+	})
+	s.Equal(2, calls)
+}
@@ -1,39 +1,141 @@
 package sync
 
 import (
+	"context"
+	"hash/maphash"
 	"sync"
 )
 
+// DefaultShards is the shard count used by NewKeyedMutex.
+const DefaultShards = 32
+
+// KeyedMutex is a set of mutexes addressed by string keys. An entry lives
+// only while some goroutine holds or waits for its key, so memory is bounded
+// by the number of keys in use rather than the number of keys ever seen.
+// Keys are spread over shards to keep the bookkeeping lock uncontended.
 type KeyedMutex struct {
-	mu     sync.Mutex
-	mutexes map[string]*sync.Mutex
+	seed   maphash.Seed
+	shards []shard
+}
+
+type shard struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is a one-slot semaphore rather than a sync.Mutex so that waiting
+// for it can be abandoned.
+type entry struct {
+	sem chan struct{}
+	// refs counts the holder and the waiters of the key. It is guarded by
+	// the shard mutex.
+	refs int
 }
 
 func NewKeyedMutex() *KeyedMutex {
-	return &KeyedMutex{
-		mutexes: make(map[string]*sync.Mutex),
+	return NewShardedKeyedMutex(DefaultShards)
+}
+
+// NewShardedKeyedMutex returns a KeyedMutex with the given number of shards.
+// A non-positive count means a single shard.
+func NewShardedKeyedMutex(shards int) *KeyedMutex {
+	if shards < 1 {
+		shards = 1
+	}
+
+	km := &KeyedMutex{
+		seed:   maphash.MakeSeed(),
+		shards: make([]shard, shards),
+	}
+	for i := range km.shards {
+		km.shards[i].entries = make(map[string]*entry)
 	}
+	return km
 }
 
 func (km *KeyedMutex) Lock(key string) {
-	km.mu.Lock()
-	mutex, exists := km.mutexes[key]
-	if !exists {
-		mutex = &sync.Mutex{}
-		km.mutexes[key] = mutex
+	km.acquire(key).sem <- struct{}{}
+}
+
+// LockContext is like Lock but stops waiting and returns ctx.Err() once ctx
+// is done. The key is not held when an error is returned.
+func (km *KeyedMutex) LockContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	km.mu.Unlock()
 
-	mutex.Lock()
+	e := km.acquire(key)
+
+	select {
+	case e.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		km.release(key, e)
+		return ctx.Err()
+	}
 }
 
+// Unlock releases key and drops its entry once nobody else holds or waits
+// for it. Unlocking a key that is not locked panics, as with sync.Mutex.
 func (km *KeyedMutex) Unlock(key string) {
-	km.mu.Lock()
-	mutex, exists := km.mutexes[key]
-	km.mu.Unlock()
+	s := km.shard(key)
+
+	s.mu.Lock()
+	e, exists := s.entries[key]
+	s.mu.Unlock()
+	if !exists || len(e.sem) == 0 {
+		panic("sync: unlock of unlocked key " + key)
+	}
+
+	<-e.sem
+	km.release(key, e)
+}
+
+// Len returns the number of keys currently held or waited for.
+func (km *KeyedMutex) Len() int {
+	n := 0
+	for i := range km.shards {
+		s := &km.shards[i]
+		s.mu.Lock()
+		n += len(s.entries)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// acquire registers the caller as a holder or waiter of key.
+func (km *KeyedMutex) acquire(key string) *entry {
+	s := km.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if exists {
-		mutex.Unlock()
+	e, exists := s.entries[key]
+	if !exists {
+		e = &entry{sem: make(chan struct{}, 1)}
+		s.entries[key] = e
+	}
+	e.refs++
+	return e
+}
+
+// release drops the caller's reference to e and forgets the key once
+// nobody holds or waits for it.
+func (km *KeyedMutex) release(key string, e *entry) {
+	s := km.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.refs--
+	if e.refs == 0 {
+		delete(s.entries, key)
 	}
 }
 
+func (km *KeyedMutex) shard(key string) *shard {
+	if len(km.shards) == 1 {
+		return &km.shards[0]
+	}
+	return &km.shards[maphash.String(km.seed, key)%uint64(len(km.shards))]
+}
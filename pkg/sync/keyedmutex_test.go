@@ -0,0 +1,248 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type KeyedMutexSuite struct {
+	suite.Suite
+
+	km *KeyedMutex
+}
+
+func TestKeyedMutex(t *testing.T) {
+	suite.Run(t, &KeyedMutexSuite{})
+}
+
+func (s *KeyedMutexSuite) SetupTest() {
+	s.km = NewKeyedMutex()
+}
+
+func (s *KeyedMutexSuite) TestMutualExclusion() {
+	const (
+		keys       = 4
+		goroutines = 64
+		iterations = 200
+	)
+	// Plain ints: the race detector flags any unsynchronised access.
+	counters := make([]int, keys)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			key := g % keys
+			for i := 0; i < iterations; i++ {
+				s.km.Lock(strconv.Itoa(key))
+				counters[key]++
+				s.km.Unlock(strconv.Itoa(key))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, c := range counters {
+		s.Equal(goroutines/keys*iterations, c)
+	}
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestDifferentKeysDoNotBlock() {
+	s.km.Lock("a")
+	defer s.km.Unlock("a")
+
+	done := make(chan struct{})
+	go func() {
+		s.km.Lock("b")
+		s.km.Unlock("b")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.Fail("lock on another key blocked")
+	}
+}
+
+func (s *KeyedMutexSuite) TestSameKeyBlocks() {
+	s.km.Lock("a")
+
+	var acquired atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		s.km.Lock("a")
+		acquired.Store(true)
+		s.km.Unlock("a")
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	s.False(acquired.Load())
+	// The waiter keeps the entry alive.
+	s.Equal(1, s.km.Len())
+
+	s.km.Unlock("a")
+	<-done
+	s.True(acquired.Load())
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestEntriesRemovedAfterUnlock() {
+	for i := 0; i < 10_000; i++ {
+		key := strconv.Itoa(i)
+		s.km.Lock(key)
+		s.km.Unlock(key)
+	}
+
+	s.Zero(s.km.Len())
+	for i := range s.km.shards {
+		s.Empty(s.km.shards[i].entries)
+	}
+}
+
+func (s *KeyedMutexSuite) TestLenCountsHeldKeys() {
+	s.km.Lock("a")
+	s.km.Lock("b")
+	s.Equal(2, s.km.Len())
+
+	s.km.Unlock("a")
+	s.Equal(1, s.km.Len())
+
+	s.km.Unlock("b")
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestUnlockOfUnlockedKeyPanics() {
+	s.Panics(func() { s.km.Unlock("a") })
+}
+
+func (s *KeyedMutexSuite) TestLockContext() {
+	s.Require().NoError(s.km.LockContext(context.Background(), "a"))
+	s.Equal(1, s.km.Len())
+	s.km.Unlock("a")
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestLockContext_GivesUpOnTimeout() {
+	s.km.Lock("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := s.km.LockContext(ctx, "a")
+	s.ErrorIs(err, context.DeadlineExceeded)
+	// Only the holder is left.
+	s.Equal(1, s.km.Len())
+
+	s.km.Unlock("a")
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestLockContext_Cancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.ErrorIs(s.km.LockContext(ctx, "a"), context.Canceled)
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestLockContext_AbandonedWaitDoesNotBreakQueue() {
+	s.km.Lock("a")
+
+	const waiters = 16
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	var abandoned, acquired atomic.Int32
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lockCtx := context.Background()
+			if i%2 == 0 {
+				lockCtx = ctx
+			}
+			if err := s.km.LockContext(lockCtx, "a"); err != nil {
+				abandoned.Add(1)
+				return
+			}
+			acquired.Add(1)
+			s.km.Unlock("a")
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	s.km.Unlock("a")
+	wg.Wait()
+
+	s.Equal(int32(waiters/2), abandoned.Load())
+	s.Equal(int32(waiters/2), acquired.Load())
+	s.Zero(s.km.Len())
+}
+
+func (s *KeyedMutexSuite) TestSingleShard() {
+	km := NewShardedKeyedMutex(0)
+	s.Len(km.shards, 1)
+
+	km.Lock("a")
+	km.Lock("b")
+	s.Equal(2, km.Len())
+	km.Unlock("b")
+	km.Unlock("a")
+	s.Zero(km.Len())
+}
+
+func benchmarkKeyedMutex(b *testing.B, km *KeyedMutex, keys int) {
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+	}
+
+	var next atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		key := names[next.Add(1)%uint64(keys)]
+		for pb.Next() {
+			km.Lock(key)
+			km.Unlock(key)
+		}
+	})
+}
+
+func BenchmarkKeyedMutex_SameKey(b *testing.B) {
+	benchmarkKeyedMutex(b, NewKeyedMutex(), 1)
+}
+
+func BenchmarkKeyedMutex_DistinctKeys(b *testing.B) {
+	benchmarkKeyedMutex(b, NewKeyedMutex(), 1024)
+}
+
+func BenchmarkKeyedMutex_DistinctKeysSingleShard(b *testing.B) {
+	benchmarkKeyedMutex(b, NewShardedKeyedMutex(1), 1024)
+}
+
+func BenchmarkKeyedMutex_ManyKeys(b *testing.B) {
+	km := NewKeyedMutex()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := strconv.Itoa(i)
+			km.Lock(key)
+			km.Unlock(key)
+			i++
+		}
+	})
+	if km.Len() != 0 {
+		b.Fatalf("expected no live keys, got %d", km.Len())
+	}
+}
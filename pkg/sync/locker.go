@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Locker serializes work per key. Implementations differ in how far the
+// guarantee reaches: KeyedMutex covers one process, PGLocker every process
+// sharing the database, RingLocker the keys a process owns.
+type Locker interface {
+	// LockContext blocks until key is held or ctx is done. The key is not
+	// held when an error is returned.
+	LockContext(ctx context.Context, key string) error
+	Unlock(key string)
+	// Len returns the number of keys held or waited for in this process.
+	Len() int
+}
+
+var (
+	_ Locker = (*KeyedMutex)(nil)
+	_ Locker = (*PGLocker)(nil)
+	_ Locker = (*RingLocker)(nil)
+
+	_ LossNotifier = (*PGLocker)(nil)
+)
+
+var ErrNotOwner = errors.New("key is owned by another node")
+
+// NotOwnerError is returned by RingLocker for keys another node owns.
+type NotOwnerError struct {
+	Key   string
+	Owner string
+}
+
+func (e *NotOwnerError) Error() string {
+	return fmt.Sprintf("key %s is owned by %s", e.Key, e.Owner)
+}
+
+func (e *NotOwnerError) Is(target error) bool {
+	return target == ErrNotOwner
+}
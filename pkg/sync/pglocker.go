@@ -0,0 +1,278 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultLockClass is the top byte of the bigint advisory lock keys taken by
+// PGLocker, 'L'. It must differ from the classes of the transaction-level
+// wallet locks ('W') and the migration lock ('M'): a session lock on a wallet
+// key would make the repository wait on its own caller.
+const DefaultLockClass byte = 0x4C
+
+// DefaultPGLockSessions is the number of sessions PGLocker spreads its keys
+// over when not configured otherwise.
+const DefaultPGLockSessions = 8
+
+// ErrLockLost is the cause of the contexts returned by PGLocker.HeldContext
+// once the session holding the lock has ended.
+var ErrLockLost = errors.New("lock lost")
+
+const (
+	minLockPoll = time.Millisecond
+	maxLockPoll = 20 * time.Millisecond
+
+	// sessionCheckInterval is how often a session holding locks is checked,
+	// so that losing it is noticed while its holders still run.
+	sessionCheckInterval = time.Second
+	sessionCheckTimeout  = 5 * time.Second
+)
+
+// LossNotifier is implemented by lockers whose locks can be lost while held.
+type LossNotifier interface {
+	// HeldContext returns a context that is cancelled with ErrLockLost once
+	// the lock of key, which must be held, is lost. It is not cancelled by
+	// Unlock.
+	HeldContext(key string) context.Context
+}
+
+// PGLocker extends a KeyedMutex across processes with Postgres session
+// advisory locks. Callers in one process queue on the in-memory mutex first,
+// then poll pg_try_advisory_lock until the key is free in every process.
+//
+// Keys are spread by their lock key over a fixed number of sessions, each
+// pinning at most one connection while it holds a lock, so PGLocker never
+// needs more connections than it has sessions however many keys are held.
+// Round trips of different sessions run in parallel. Keys with the same lock
+// key share a session, where advisory locks are reentrant, so they never
+// wait on each other.
+//
+// A session that fails is closed, which drops the locks of its keys only.
+// Their holders learn about it from HeldContext; PGLocker serializes work,
+// it does not replace the row and advisory locks the guarded transactions
+// take themselves.
+type PGLocker struct {
+	pool     *pgxpool.Pool
+	class    byte
+	local    *KeyedMutex
+	sessions []pgSessionSlot
+}
+
+// pgSessionSlot is one of the sessions of a PGLocker and the keys it holds.
+type pgSessionSlot struct {
+	mu      sync.Mutex
+	current *pgSession
+	// held maps every held key of the slot to the session holding its lock.
+	held map[string]*pgSession
+}
+
+// pgSession is a connection pinned while it holds locks. ctx is cancelled
+// when the session ends.
+type pgSession struct {
+	conn   *pgxpool.Conn
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// NewPGLocker returns a PGLocker with the given number of sessions; the pool
+// should allow as many connections. A non-positive count means one session.
+func NewPGLocker(pool *pgxpool.Pool, class byte, sessions int) *PGLocker {
+	if sessions < 1 {
+		sessions = 1
+	}
+
+	l := &PGLocker{
+		pool:     pool,
+		class:    class,
+		local:    NewKeyedMutex(),
+		sessions: make([]pgSessionSlot, sessions),
+	}
+	for i := range l.sessions {
+		l.sessions[i].held = make(map[string]*pgSession)
+	}
+	return l
+}
+
+func (l *PGLocker) LockContext(ctx context.Context, key string) error {
+	if err := l.local.LockContext(ctx, key); err != nil {
+		return err
+	}
+
+	slot := l.slot(key)
+	poll := minLockPoll
+	for {
+		locked, err := l.tryLock(ctx, slot, key)
+		if err != nil {
+			l.local.Unlock(key)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			l.local.Unlock(key)
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+		poll = min(2*poll, maxLockPoll)
+	}
+}
+
+// tryLock takes the advisory lock of key in the session of slot without
+// waiting for it.
+func (l *PGLocker) tryLock(ctx context.Context, slot *pgSessionSlot, key string) (bool, error) {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.current == nil {
+		conn, err := l.pool.Acquire(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to acquire lock connection: %w", err)
+		}
+		slot.start(conn)
+	}
+	session := slot.current
+
+	// The query does not block, so it is not cut short by ctx: a cancelled
+	// query would cost the session and every lock in it.
+	var locked bool
+	err := session.conn.QueryRow(context.WithoutCancel(ctx), "SELECT pg_try_advisory_lock($1)", LockKey(l.class, key)).Scan(&locked)
+	if err != nil {
+		slot.drop()
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !locked {
+		slot.releaseIdle()
+		return false, nil
+	}
+
+	slot.held[key] = session
+	return true, nil
+}
+
+func (l *PGLocker) Unlock(key string) {
+	slot := l.slot(key)
+
+	slot.mu.Lock()
+	session, exists := slot.held[key]
+	if !exists {
+		slot.mu.Unlock()
+		panic("sync: unlock of unlocked key " + key)
+	}
+	delete(slot.held, key)
+
+	// A lock taken in a dropped session is already gone.
+	if session == slot.current {
+		// Unlock must not be cut short by the caller's context.
+		var unlocked bool
+		err := session.conn.QueryRow(context.Background(), "SELECT pg_advisory_unlock($1)", LockKey(l.class, key)).Scan(&unlocked)
+		if err != nil || !unlocked {
+			slot.drop()
+		}
+	}
+	slot.releaseIdle()
+	slot.mu.Unlock()
+
+	l.local.Unlock(key)
+}
+
+func (l *PGLocker) HeldContext(key string) context.Context {
+	slot := l.slot(key)
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	session, exists := slot.held[key]
+	if !exists {
+		panic("sync: held context of unlocked key " + key)
+	}
+	return session.ctx
+}
+
+func (l *PGLocker) Len() int {
+	return l.local.Len()
+}
+
+func (l *PGLocker) slot(key string) *pgSessionSlot {
+	return &l.sessions[uint64(LockKey(l.class, key))%uint64(len(l.sessions))]
+}
+
+// start makes conn the current session of the slot and checks it while it
+// lives. It must be called with slot.mu held.
+func (slot *pgSessionSlot) start(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	session := &pgSession{conn: conn, ctx: ctx, cancel: cancel}
+	slot.current = session
+	go slot.check(session)
+}
+
+// check pings session until it ends and drops it once a ping fails.
+func (slot *pgSessionSlot) check(session *pgSession) {
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-session.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		slot.mu.Lock()
+		if slot.current == session {
+			ctx, cancel := context.WithTimeout(context.Background(), sessionCheckTimeout)
+			if err := session.conn.Ping(ctx); err != nil {
+				slot.drop()
+			}
+			cancel()
+		}
+		slot.mu.Unlock()
+	}
+}
+
+// drop closes the current session, which drops all of its advisory locks,
+// and tells their holders. It must be called with slot.mu held.
+func (slot *pgSessionSlot) drop() {
+	if slot.current != nil {
+		closeConn(slot.current.conn)
+		slot.current.cancel(ErrLockLost)
+		slot.current = nil
+	}
+}
+
+// releaseIdle returns the current session to the pool when it holds no
+// lock. It must be called with slot.mu held.
+func (slot *pgSessionSlot) releaseIdle() {
+	if slot.current != nil && len(slot.held) == 0 {
+		slot.current.conn.Release()
+		slot.current.cancel(nil)
+		slot.current = nil
+	}
+}
+
+// closeConn ends the session behind conn, which drops all of its advisory
+// locks, instead of returning it to the pool.
+func closeConn(conn *pgxpool.Conn) {
+	_ = conn.Hijack().Close(context.Background())
+}
+
+// LockKey returns the bigint advisory lock key of key in the given class:
+// the class followed by 56 bits of the FNV-1a hash of key, the same layout
+// as the repository's wallet lock keys.
+func LockKey(class byte, key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(uint64(class)<<56 | h.Sum64()&(1<<56-1))
+}
@@ -0,0 +1,17 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockKey(t *testing.T) {
+	key := LockKey(DefaultLockClass, "wallet-1")
+
+	assert.Equal(t, int64(DefaultLockClass), key>>56)
+	assert.Equal(t, key, LockKey(DefaultLockClass, "wallet-1"))
+	assert.NotEqual(t, key, LockKey(DefaultLockClass, "wallet-2"))
+	// The class keeps the keys apart from the repository's wallet locks.
+	assert.NotEqual(t, int64(0x57), LockKey(DefaultLockClass, "wallet-1")>>56)
+}
@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RingSuite struct {
+	suite.Suite
+
+	nodes []string
+}
+
+func TestRing(t *testing.T) {
+	suite.Run(t, &RingSuite{})
+}
+
+func (s *RingSuite) SetupTest() {
+	s.nodes = []string{"wallet-0:8080", "wallet-1:8080", "wallet-2:8080", "wallet-3:8080"}
+}
+
+func (s *RingSuite) TestOwnerIsStableAcrossInstances() {
+	a := NewRing(s.nodes, 0)
+	// Another process may list the nodes in a different order.
+	b := NewRing([]string{s.nodes[3], s.nodes[1], s.nodes[0], s.nodes[2]}, 0)
+
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		s.Equal(a.Owner(key), b.Owner(key))
+	}
+}
+
+func (s *RingSuite) TestKeysAreSpreadOverNodes() {
+	ring := NewRing(s.nodes, 0)
+
+	const keys = 10_000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		counts[ring.Owner(strconv.Itoa(i))]++
+	}
+
+	s.Len(counts, len(s.nodes))
+	for node, n := range counts {
+		s.InDelta(keys/len(s.nodes), n, float64(keys/len(s.nodes)/2), node)
+	}
+}
+
+func (s *RingSuite) TestAddingNodeOnlyMovesKeysToIt() {
+	before := NewRing(s.nodes, 0)
+	after := NewRing(append(s.nodes, "wallet-4:8080"), 0)
+
+	for i := 0; i < 10_000; i++ {
+		key := strconv.Itoa(i)
+		if owner := after.Owner(key); owner != before.Owner(key) {
+			s.Equal("wallet-4:8080", owner)
+		}
+	}
+}
+
+func (s *RingSuite) TestEmptyRing() {
+	s.Empty(NewRing(nil, 0).Owner("a"))
+}
+
+func (s *RingSuite) TestRingLocker() {
+	ring := NewRing(s.nodes, 0)
+	locker := NewRingLocker(ring, s.nodes[0], NewKeyedMutex())
+
+	var owned, foreign string
+	for i := 0; owned == "" || foreign == ""; i++ {
+		key := strconv.Itoa(i)
+		if ring.Owner(key) == s.nodes[0] {
+			owned = key
+		} else {
+			foreign = key
+		}
+	}
+
+	s.Require().NoError(locker.LockContext(context.Background(), owned))
+	s.Equal(1, locker.Len())
+	locker.Unlock(owned)
+	s.Zero(locker.Len())
+
+	err := locker.LockContext(context.Background(), foreign)
+	s.ErrorIs(err, ErrNotOwner)
+	var notOwner *NotOwnerError
+	s.Require().ErrorAs(err, &notOwner)
+	s.Equal(ring.Owner(foreign), notOwner.Owner)
+	s.Zero(locker.Len())
+}
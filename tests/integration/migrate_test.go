@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"ITK/migrations"
+	"ITK/pkg/logger/handlers/slogdiscard"
+	"ITK/pkg/postgres"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentAutoMigrate starts several replicas' migrations at once
+// against an up to date database; all of them must succeed.
+func TestConcurrentAutoMigrate(t *testing.T) {
+	if !isIntegrationTestsRun() {
+		t.Skip()
+		return
+	}
+
+	cfg := postgres.DBConfig{
+		Host:     "localhost",
+		Port:     "5433",
+		User:     "postgres",
+		Password: "postgres",
+		Database: "wallet",
+	}
+	pool, err := pgxpool.New(context.Background(), cfg.DSN())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = postgres.Migrate(context.Background(), pool, cfg, migrations.FS, slogdiscard.NewDiscardLogger())
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+
+	pending, err := postgres.CheckSchema(cfg, migrations.FS)
+	require.NoError(t, err)
+	assert.False(t, pending)
+}
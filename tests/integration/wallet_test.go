@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"ITK/internal/repository"
+
 	"github.com/google/uuid"
 	jsoniter "github.com/json-iterator/go"
 	_ "github.com/lib/pq"
@@ -64,6 +66,12 @@ func (s *WalletSuite) clearDatabase() {
 	s.NoError(err)
 	_, err = s.DB.Exec(`TRUNCATE TABLE wallets CASCADE`)
 	s.NoError(err)
+	_, err = s.DB.Exec(`TRUNCATE TABLE journal_entries, ledger_accounts CASCADE`)
+	s.NoError(err)
+	_, err = s.DB.Exec(`TRUNCATE TABLE idempotency_keys`)
+	s.NoError(err)
+	_, err = s.DB.Exec(`TRUNCATE TABLE webhook_subscriptions CASCADE`)
+	s.NoError(err)
 }
 
 func (s *WalletSuite) TestCreateWallet() {
@@ -198,6 +206,410 @@ func (s *WalletSuite) TestInsufficientFunds() {
 	s.Equal(409, resp.StatusCode)
 }
 
+func (s *WalletSuite) TestTransfer() {
+	s.clearDatabase()
+
+	fromID := s.createWallet()
+	toID := s.createWallet()
+
+	depositBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 1000
+	}`, fromID)
+
+	_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(depositBody), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	transferBody := fmt.Sprintf(`{
+		"fromWalletId": "%s",
+		"toWalletId": "%s",
+		"amount": 300
+	}`, fromID, toID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/transfers", []byte(transferBody), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	var fromBalance, toBalance float64
+	err = s.DB.QueryRow(`SELECT balance FROM wallets WHERE id = $1`, fromID).Scan(&fromBalance)
+	s.NoError(err)
+	err = s.DB.QueryRow(`SELECT balance FROM wallets WHERE id = $1`, toID).Scan(&toBalance)
+	s.NoError(err)
+	s.Equal(700.0, fromBalance)
+	s.Equal(300.0, toBalance)
+
+	var legs, transfers int
+	err = s.DB.QueryRow(
+		`SELECT COUNT(*), COUNT(DISTINCT transfer_id) FROM operations WHERE transfer_id IS NOT NULL`,
+	).Scan(&legs, &transfers)
+	s.NoError(err)
+	s.Equal(2, legs)
+	s.Equal(1, transfers)
+}
+
+func (s *WalletSuite) TestTransferInsufficientFunds() {
+	s.clearDatabase()
+
+	fromID := s.createWallet()
+	toID := s.createWallet()
+
+	transferBody := fmt.Sprintf(`{
+		"fromWalletId": "%s",
+		"toWalletId": "%s",
+		"amount": 100
+	}`, fromID, toID)
+
+	_, resp, err := postAPIResponse(mainHost, "/api/v1/transfers", []byte(transferBody), nil)
+	s.NoError(err)
+	s.Equal(409, resp.StatusCode)
+
+	var count int
+	err = s.DB.QueryRow(`SELECT COUNT(*) FROM operations`).Scan(&count)
+	s.NoError(err)
+	s.Equal(0, count)
+}
+
+func (s *WalletSuite) TestIdempotentDeposit() {
+	s.clearDatabase()
+
+	walletID := s.createWallet()
+	headers := map[string]string{"Idempotency-Key": uuid.New().String()}
+
+	depositBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 100
+	}`, walletID)
+
+	for i := 0; i < 3; i++ {
+		_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(depositBody), headers)
+		s.NoError(err)
+		s.Equal(200, resp.StatusCode)
+	}
+
+	var balance float64
+	err := s.DB.QueryRow(`SELECT balance FROM wallets WHERE id = $1`, walletID).Scan(&balance)
+	s.NoError(err)
+	s.Equal(100.0, balance)
+
+	otherBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 200
+	}`, walletID)
+
+	_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(otherBody), headers)
+	s.NoError(err)
+	s.Equal(422, resp.StatusCode)
+}
+
+func (s *WalletSuite) TestListOperations() {
+	s.clearDatabase()
+
+	walletID := s.createWallet()
+
+	for i := 1; i <= 5; i++ {
+		body := fmt.Sprintf(`{
+			"walletId": "%s",
+			"operationType": "DEPOSIT",
+			"amount": %d
+		}`, walletID, i)
+
+		_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(body), nil)
+		s.NoError(err)
+		s.Equal(200, resp.StatusCode)
+	}
+
+	type page struct {
+		Operations []struct {
+			ID     string  `json:"id"`
+			Amount float64 `json:"amount"`
+		} `json:"operations"`
+		NextCursor string `json:"nextCursor"`
+	}
+
+	var amounts []float64
+	seen := make(map[string]bool)
+	path := fmt.Sprintf("/api/v1/wallets/%s/operations?limit=2", walletID)
+
+	for {
+		respBody, resp, err := getAPIResponse(mainHost, path, nil)
+		s.NoError(err)
+		s.Equal(200, resp.StatusCode)
+
+		var p page
+		s.NoError(jsoniter.Unmarshal(respBody, &p))
+
+		for _, op := range p.Operations {
+			s.False(seen[op.ID])
+			seen[op.ID] = true
+			amounts = append(amounts, op.Amount)
+		}
+
+		if p.NextCursor == "" {
+			break
+		}
+		path = fmt.Sprintf("/api/v1/wallets/%s/operations?limit=2&cursor=%s", walletID, p.NextCursor)
+	}
+
+	s.Equal([]float64{5, 4, 3, 2, 1}, amounts)
+}
+
+func (s *WalletSuite) TestHoldCaptureAndRelease() {
+	s.clearDatabase()
+
+	walletID := s.createWallet()
+
+	depositBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 1000
+	}`, walletID)
+
+	_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(depositBody), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	type hold struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+
+	placeHold := func(amount int) hold {
+		respBody, resp, err := postAPIResponse(mainHost,
+			fmt.Sprintf("/api/v1/wallets/%s/holds", walletID),
+			[]byte(fmt.Sprintf(`{"amount": %d}`, amount)), nil)
+		s.NoError(err)
+		s.Equal(201, resp.StatusCode)
+
+		var h hold
+		s.NoError(jsoniter.Unmarshal(respBody, &h))
+		return h
+	}
+
+	captured := placeHold(600)
+
+	withdrawBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "WITHDRAW",
+		"amount": 500
+	}`, walletID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/wallet", []byte(withdrawBody), nil)
+	s.NoError(err)
+	s.Equal(409, resp.StatusCode)
+
+	respBody, resp, err := getAPIResponse(mainHost, fmt.Sprintf("/api/v1/wallets/%s", walletID), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	var balance struct {
+		Balance   float64 `json:"balance"`
+		Available float64 `json:"available"`
+	}
+	s.NoError(jsoniter.Unmarshal(respBody, &balance))
+	s.Equal(1000.0, balance.Balance)
+	s.Equal(400.0, balance.Available)
+
+	_, resp, err = postAPIResponse(mainHost,
+		fmt.Sprintf("/api/v1/holds/%s/capture", captured.ID), []byte(`{"amount": 450}`), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	released := placeHold(100)
+	_, resp, err = postAPIResponse(mainHost, fmt.Sprintf("/api/v1/holds/%s/release", released.ID), nil, nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	_, resp, err = postAPIResponse(mainHost, fmt.Sprintf("/api/v1/holds/%s/release", released.ID), nil, nil)
+	s.NoError(err)
+	s.Equal(409, resp.StatusCode)
+
+	var dbBalance, held float64
+	err = s.DB.QueryRow(`SELECT balance, held FROM wallets WHERE id = $1`, walletID).Scan(&dbBalance, &held)
+	s.NoError(err)
+	s.Equal(550.0, dbBalance)
+	s.Equal(0.0, held)
+}
+
+func (s *WalletSuite) TestWalletCurrency() {
+	s.clearDatabase()
+
+	respBody, resp, err := postAPIResponse(mainHost, "/api/v1/wallet/create", []byte(`{"currency": "USD"}`), nil)
+	s.NoError(err)
+	s.Equal(201, resp.StatusCode)
+
+	var created struct {
+		WalletID string `json:"walletId"`
+	}
+	s.NoError(jsoniter.Unmarshal(respBody, &created))
+
+	var currency string
+	err = s.DB.QueryRow(`SELECT currency FROM wallets WHERE id = $1`, created.WalletID).Scan(&currency)
+	s.NoError(err)
+	s.Equal("USD", currency)
+
+	requestBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 100,
+		"currency": "EUR"
+	}`, created.WalletID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/wallet", []byte(requestBody), nil)
+	s.NoError(err)
+	s.Equal(400, resp.StatusCode)
+
+	requestBody = fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 10.005
+	}`, created.WalletID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/wallet", []byte(requestBody), nil)
+	s.NoError(err)
+	s.Equal(400, resp.StatusCode)
+
+	rubID := s.createWallet()
+	transferBody := fmt.Sprintf(`{
+		"fromWalletId": "%s",
+		"toWalletId": "%s",
+		"amount": 1
+	}`, rubID, created.WalletID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/transfers", []byte(transferBody), nil)
+	s.NoError(err)
+	s.Equal(400, resp.StatusCode)
+}
+
+func (s *WalletSuite) TestExactAmountsV2() {
+	s.clearDatabase()
+
+	walletID := s.createWallet()
+
+	for _, amount := range []string{"0.10", "0.20"} {
+		requestBody := fmt.Sprintf(`{
+			"walletId": "%s",
+			"operationType": "DEPOSIT",
+			"amount": "%s"
+		}`, walletID, amount)
+
+		_, resp, err := postAPIResponse(mainHost, "/api/v2/wallet", []byte(requestBody), nil)
+		s.NoError(err)
+		s.Equal(200, resp.StatusCode)
+	}
+
+	requestBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": "0.001"
+	}`, walletID)
+
+	_, resp, err := postAPIResponse(mainHost, "/api/v2/wallet", []byte(requestBody), nil)
+	s.NoError(err)
+	s.Equal(400, resp.StatusCode)
+
+	respBody, resp, err := getAPIResponse(mainHost, fmt.Sprintf("/api/v2/wallets/%s", walletID), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	var balance struct {
+		Balance string `json:"balance"`
+	}
+	s.NoError(jsoniter.Unmarshal(respBody, &balance))
+	s.Equal("0.3", balance.Balance)
+}
+
+func (s *WalletSuite) TestOperationEventsAndWebhooks() {
+	s.clearDatabase()
+
+	// Nothing listens on this port, so deliveries stay pending or die.
+	respBody, resp, err := postAPIResponse(mainHost, "/admin/webhooks", []byte(`{"url": "http://127.0.0.1:9/hook"}`), nil)
+	s.NoError(err)
+	s.Equal(201, resp.StatusCode)
+
+	var webhook struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	s.NoError(jsoniter.Unmarshal(respBody, &webhook))
+	s.NotEmpty(webhook.Secret)
+
+	respBody, resp, err = getAPIResponse(mainHost, "/admin/webhooks", nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+	s.Contains(string(respBody), webhook.ID)
+	s.NotContains(string(respBody), webhook.Secret)
+	s.NotContains(string(respBody), `"secret"`)
+
+	walletID := s.createWallet()
+	requestBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 100
+	}`, walletID)
+
+	_, resp, err = postAPIResponse(mainHost, "/api/v1/wallet", []byte(requestBody), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+
+	var (
+		eventType string
+		payload   []byte
+	)
+	err = s.DB.QueryRow(`SELECT event_type, payload FROM outbox_events WHERE wallet_id = $1`, walletID).Scan(&eventType, &payload)
+	s.NoError(err)
+	s.Equal("operation.created", eventType)
+	s.Contains(string(payload), `"operationType": "DEPOSIT"`)
+
+	s.Eventually(func() bool {
+		var count int
+		err := s.DB.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries WHERE subscription_id = $1`, webhook.ID).Scan(&count)
+		return err == nil && count == 1
+	}, 10*time.Second, 100*time.Millisecond)
+
+	respBody, resp, err = getAPIResponse(mainHost, fmt.Sprintf("/admin/webhooks/%s/deliveries", webhook.ID), nil)
+	s.NoError(err)
+	s.Equal(200, resp.StatusCode)
+	s.Contains(string(respBody), `"attempts"`)
+}
+
+// TestWalletLocksAreIndependent holds the advisory lock of one wallet and
+// deposits to another wallet whose ID has the same XOR of halves, which used
+// to map to the same lock.
+func (s *WalletSuite) TestWalletLocksAreIndependent() {
+	s.clearDatabase()
+
+	lockedID := uuid.MustParse(s.createWallet())
+	var otherID uuid.UUID
+	copy(otherID[:8], lockedID[8:])
+	copy(otherID[8:], lockedID[:8])
+	_, err := s.DB.Exec(`INSERT INTO wallets (id) VALUES ($1)`, otherID)
+	s.Require().NoError(err)
+	_, err = s.DB.Exec(`INSERT INTO ledger_accounts (id, account_type, currency) VALUES ($1, 'WALLET', 'RUB')`, otherID)
+	s.Require().NoError(err)
+
+	tx, err := s.DB.Begin()
+	s.Require().NoError(err)
+	defer func() { s.NoError(tx.Rollback()) }()
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, repository.WalletLockKey(lockedID))
+	s.Require().NoError(err)
+
+	requestBody := fmt.Sprintf(`{
+		"walletId": "%s",
+		"operationType": "DEPOSIT",
+		"amount": 100
+	}`, otherID)
+
+	start := time.Now()
+	_, resp, err := postAPIResponse(mainHost, "/api/v1/wallet", []byte(requestBody), nil)
+	s.Require().NoError(err)
+	s.Equal(200, resp.StatusCode)
+	s.Less(time.Since(start), 5*time.Second)
+}
+
 func (s *WalletSuite) TestWalletNotFound() {
 	s.clearDatabase()
 